@@ -4,13 +4,39 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
+	"vetclinic-rest-api/services"
 	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// doctorAvailable answers 409 and returns false when the doctor already has an appointment overlapping the slot
+func doctorAvailable(c *gin.Context, q services.Querier, doctorId uuid.UUID, typeId *uuid.UUID, start time.Time, excludeAppointmentId *uuid.UUID) bool {
+    if doctorId == uuid.Nil {
+        return true
+    }
+    duration, err := services.AppointmentDuration(q, typeId)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type not found"})
+        return false
+    }
+    free, err := services.DoctorIsFree(q, doctorId, start, duration, excludeAppointmentId)
+    if err != nil {
+        log.Println("Error checking doctor availability:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check doctor availability"})
+        return false
+    }
+    if !free {
+        c.JSON(http.StatusConflict, gin.H{"error": "Doctor already has an appointment at that time"})
+        return false
+    }
+    return true
+}
+
 func CreateAppointment(c *gin.Context, db *sql.DB) {
     var newAppointment structs.Appointment
     if err := c.ShouldBindJSON(&newAppointment); err != nil {
@@ -24,6 +50,10 @@ func CreateAppointment(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "PetId and AppointmentDatetime are required"})
         return
     }
+    if msg := checkAppointmentType(db, newAppointment.AppointmentTypeId, newAppointment.DoctorId); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
 
     // Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
@@ -46,12 +76,20 @@ func CreateAppointment(c *gin.Context, db *sql.DB) {
     newAppointment.ModifiedBy = createdBy
 
     query := `INSERT INTO "Appointments"
-        (id, pet_id, doctor_id, status, appointment_datetime, notes,
+        (id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes,
         active_status, created_at, created_by, modified_at, modified_by)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`
 
-    _, err := db.Exec(query,
-        newAppointment.Id, newAppointment.PetId, newAppointment.DoctorId, newAppointment.Status,
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new Appointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+    defer tx.Rollback()
+
+    _, err = tx.Exec(query,
+        newAppointment.Id, newAppointment.PetId, newAppointment.DoctorId, newAppointment.AppointmentTypeId, newAppointment.Status,
         newAppointment.AppointmentDatetime, newAppointment.Notes, newAppointment.ActiveStatus,
         newAppointment.CreatedAt, newAppointment.CreatedBy, newAppointment.ModifiedAt, newAppointment.ModifiedBy,
     )
@@ -61,6 +99,17 @@ func CreateAppointment(c *gin.Context, db *sql.DB) {
         return
     }
 
+    // Reserve the rooms/equipment the appointment type needs
+    if !allocateAppointmentResources(c, tx, newAppointment.Id, newAppointment.AppointmentTypeId, newAppointment.AppointmentDatetime, createdBy) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new Appointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+
     c.JSON(http.StatusCreated, newAppointment)
 }
 
@@ -68,11 +117,11 @@ func FetchAppointment(c *gin.Context, db *sql.DB) {
     appointmentId := c.Param("id")
     var appt structs.Appointment
 
-    query := `SELECT id, pet_id, doctor_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
+    query := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
             FROM "Appointments"
             WHERE id=$1 AND active_status=1`
     err := db.QueryRow(query, appointmentId).Scan(
-        &appt.Id, &appt.PetId, &appt.DoctorId, &appt.Status,
+        &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
         &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
         &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
     )
@@ -81,6 +130,7 @@ func FetchAppointment(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
         return
     }
+    fetchAppointmentCancellation(db, &appt)
 
     c.JSON(http.StatusOK, appt)
 }
@@ -90,14 +140,14 @@ func UpdateAppointment(c *gin.Context, db *sql.DB) {
 
     // 1. Fetch existing appointment
     var existing structs.Appointment
-    fetchQuery := `SELECT id, pet_id, doctor_id, status, appointment_datetime,
+    fetchQuery := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime,
                           notes, active_status, created_at, created_by,
                           modified_at, modified_by
                    FROM "Appointments"
                    WHERE id=$1 AND active_status=1`
 
     err := db.QueryRow(fetchQuery, appointmentId).Scan(
-        &existing.Id, &existing.PetId, &existing.DoctorId, &existing.Status,
+        &existing.Id, &existing.PetId, &existing.DoctorId, &existing.AppointmentTypeId, &existing.Status,
         &existing.AppointmentDatetime, &existing.Notes, &existing.ActiveStatus,
         &existing.CreatedAt, &existing.CreatedBy,
         &existing.ModifiedAt, &existing.ModifiedBy,
@@ -116,18 +166,27 @@ func UpdateAppointment(c *gin.Context, db *sql.DB) {
     }
 
     // 3. Merge fields
+    previousDatetime := existing.AppointmentDatetime
+    previousTypeId := existing.AppointmentTypeId
     if req.PetId != uuid.Nil {
         existing.PetId = req.PetId
     }
     if req.DoctorId != uuid.Nil {
         existing.DoctorId = req.DoctorId
     }
+    if req.AppointmentTypeId != nil {
+        existing.AppointmentTypeId = req.AppointmentTypeId
+    }
     if !req.AppointmentDatetime.IsZero() {
         existing.AppointmentDatetime = req.AppointmentDatetime
     }
     if req.Notes != "" {
         existing.Notes = req.Notes
     }
+    if msg := checkAppointmentType(db, existing.AppointmentTypeId, existing.DoctorId); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
 
     // 4. Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
@@ -137,14 +196,22 @@ func UpdateAppointment(c *gin.Context, db *sql.DB) {
     }
     modifiedBy := userIdVal.(string)
 
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateAppointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment"})
+        return
+    }
+    defer tx.Rollback()
+
     // 5. Update query
     updateQuery := `UPDATE "Appointments"
-                    SET pet_id=$1, doctor_id=$2, appointment_datetime=$3,
-                        notes=$4, modified_at=$5, modified_by=$6
-                    WHERE id=$7 AND active_status=1`
+                    SET pet_id=$1, doctor_id=$2, appointment_type_id=$3, appointment_datetime=$4,
+                        notes=$5, modified_at=$6, modified_by=$7
+                    WHERE id=$8 AND active_status=1`
 
-    _, err = db.Exec(updateQuery,
-        existing.PetId, existing.DoctorId, existing.AppointmentDatetime,
+    _, err = tx.Exec(updateQuery,
+        existing.PetId, existing.DoctorId, existing.AppointmentTypeId, existing.AppointmentDatetime,
         existing.Notes, time.Now(), modifiedBy, appointmentId,
     )
     if err != nil {
@@ -153,6 +220,24 @@ func UpdateAppointment(c *gin.Context, db *sql.DB) {
         return
     }
 
+    // 6. Moving the appointment or changing its type re-reserves rooms/equipment
+    if !existing.AppointmentDatetime.Equal(previousDatetime) || !sameUUID(existing.AppointmentTypeId, previousTypeId) {
+        if err := services.ReleaseResources(tx, appointmentId, modifiedBy); err != nil {
+            log.Println("Error releasing Appointment resources:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment"})
+            return
+        }
+        if !allocateAppointmentResources(c, tx, existing.Id, existing.AppointmentTypeId, existing.AppointmentDatetime, modifiedBy) {
+            return
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing Appointment update:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment"})
+        return
+    }
+
     c.JSON(http.StatusOK, gin.H{"message": "Appointment updated successfully"})
 }
 
@@ -160,6 +245,7 @@ func UpdateAppointmentStatus(c *gin.Context, db *sql.DB) {
     appointmentId := c.Param("id")
     var req struct {
         Status string `json:"status"`
+        cancellationRequest
     }
 
     if err := c.ShouldBindJSON(&req); err != nil {
@@ -168,7 +254,7 @@ func UpdateAppointmentStatus(c *gin.Context, db *sql.DB) {
         return
     }
 
-    validStatuses := []string{"Pending", "Cancelled", "Completed"}
+    validStatuses := []string{"Pending", "InProgress", "Cancelled", "Completed", "NoShow"}
     isValid := false
     for _, s := range validStatuses {
         if req.Status == s {
@@ -181,6 +267,32 @@ func UpdateAppointmentStatus(c *gin.Context, db *sql.DB) {
         return
     }
 
+    var appointmentDatetime time.Time
+    var currentStatus string
+    var doctorId uuid.NullUUID
+    var typeId *uuid.UUID
+    err := db.QueryRow(`SELECT appointment_datetime, status, doctor_id, appointment_type_id
+                        FROM "Appointments" WHERE id=$1 AND active_status=1`,
+        appointmentId).Scan(&appointmentDatetime, &currentStatus, &doctorId, &typeId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+
+    // Surgery, anesthesia and other consent types can't start (or be completed) without the owner's signature
+    if req.Status == "InProgress" || req.Status == "Completed" {
+        missing, err := appointmentConsentMissing(db, appointmentId)
+        if err != nil {
+            log.Println("Error checking Appointment consent:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+            return
+        }
+        if missing {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A signed consent is required before this appointment can start"})
+            return
+        }
+    }
+
     // Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
     if !exists {
@@ -193,16 +305,86 @@ func UpdateAppointmentStatus(c *gin.Context, db *sql.DB) {
         return
     }
 
-    query := `UPDATE "Appointments"
-            SET status=$1, modified_at=$2, modified_by=$3
-            WHERE id=$4 AND active_status=1`
+    // Cancellation needs a structured reason and who cancelled
+    if req.Status == "Cancelled" {
+        if msg := checkCancellationReason(db, req.cancellationRequest); msg != "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+            return
+        }
+        cancelled, err := cancelAppointment(db, appointmentId, appointmentDatetime, req.cancellationRequest, modifiedBy, "")
+        if err != nil {
+            log.Println("Error cancelling Appointment:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+            return
+        }
+        if !cancelled {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "Appointment status updated successfully"})
+        return
+    }
 
-    _, err := db.Exec(query, req.Status, time.Now(), modifiedBy, appointmentId)
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateAppointmentStatus:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Back from Cancelled/NoShow to an active status: the slot may have been given away meanwhile,
+    // so the doctor has to be free again and the rooms/equipment are reserved again
+    restored := (currentStatus == "Cancelled" || currentStatus == "NoShow") && (req.Status == "Pending" || req.Status == "InProgress")
+    if restored {
+        id := uuid.MustParse(appointmentId)
+        if doctorId.Valid {
+            // Serialize bookings of the same doctor, as new bookings do
+            if _, err := tx.Exec(`SELECT id FROM "Users" WHERE id=$1 FOR UPDATE`, doctorId.UUID); err != nil {
+                log.Println("Error locking doctor for UpdateAppointmentStatus:", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+                return
+            }
+            if !doctorAvailable(c, tx, doctorId.UUID, typeId, appointmentDatetime, &id) {
+                return
+            }
+        }
+        // a no-show still holds its resources, they are booked again from scratch
+        if err := services.ReleaseResources(tx, appointmentId, modifiedBy); err != nil {
+            log.Println("Error releasing Appointment resources:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+            return
+        }
+        if !allocateAppointmentResources(c, tx, id, typeId, appointmentDatetime, modifiedBy) {
+            return
+        }
+    }
+
+    // Any other status clears a previous cancellation
+    query := `UPDATE "Appointments"
+            SET status=$1, modified_at=$2, modified_by=$3,
+                cancellation_reason_id=NULL, cancellation_note=NULL, cancelled_by_party=NULL,
+                cancelled_at=NULL, late_cancellation=false,
+                no_show_at = CASE WHEN $1='NoShow' THEN COALESCE(no_show_at, $5) ELSE NULL END
+            WHERE id=$4 AND active_status=1 AND status=$6`
+
+    now := time.Now()
+    result, err := tx.Exec(query, req.Status, now, modifiedBy, appointmentId, now, currentStatus)
     if err != nil {
         log.Println("Error updating Appointment status:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
         return
     }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment status was changed meanwhile, please retry"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing Appointment status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+        return
+    }
 
     c.JSON(http.StatusOK, gin.H{"message": "Appointment status updated successfully"})
 }
@@ -233,6 +415,12 @@ func UpdateAppointmentActiveStatus(c *gin.Context, db *sql.DB) {
         return
     }
 
+    // Give back rooms/equipment and offer the freed slot to the waitlist
+    if err := services.ReleaseResources(db, appointmentId, modifiedBy); err != nil {
+        log.Println("Error releasing Appointment resources:", err)
+    }
+    services.SlotFreed(appointmentId)
+
     c.JSON(http.StatusOK, gin.H{
         "id":             appointmentId,
         "deactivated_by": modifiedBy,
@@ -242,13 +430,18 @@ func UpdateAppointmentActiveStatus(c *gin.Context, db *sql.DB) {
 
 func GetAppointmentsByPetId(c *gin.Context, db *sql.DB) {
     petId := c.Param("pet_id")
+    typeId, ok := appointmentTypeFilter(c)
+    if !ok {
+        return
+    }
 
-    query := `SELECT id, pet_id, doctor_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
+    query := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
             FROM "Appointments"
             WHERE pet_id=$1 AND active_status=1
+            AND ($2::uuid IS NULL OR appointment_type_id=$2)
             ORDER BY appointment_datetime DESC`// sort from newest to oldest
 
-    rows, err := db.Query(query, petId)
+    rows, err := db.Query(query, petId, typeId)
     if err != nil {
         log.Println("Error fetching appointments by pet_id:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
@@ -260,7 +453,7 @@ func GetAppointmentsByPetId(c *gin.Context, db *sql.DB) {
     for rows.Next() {
         var appt structs.Appointment
         if err := rows.Scan(
-            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.Status,
+            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
             &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
             &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
         ); err != nil {
@@ -276,13 +469,18 @@ func GetAppointmentsByPetId(c *gin.Context, db *sql.DB) {
 
 func GetAppointmentsByDoctorId(c *gin.Context, db *sql.DB) {
     doctorId := c.Param("doctor_id")
+    typeId, ok := appointmentTypeFilter(c)
+    if !ok {
+        return
+    }
 
-    query := `SELECT id, pet_id, doctor_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
+    query := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
             FROM "Appointments"
             WHERE doctor_id=$1 AND active_status=1
+            AND ($2::uuid IS NULL OR appointment_type_id=$2)
             ORDER BY appointment_datetime DESC`
 
-    rows, err := db.Query(query, doctorId)
+    rows, err := db.Query(query, doctorId, typeId)
     if err != nil {
         log.Println("Error fetching appointments by doctor_id:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
@@ -294,7 +492,7 @@ func GetAppointmentsByDoctorId(c *gin.Context, db *sql.DB) {
     for rows.Next() {
         var appt structs.Appointment
         if err := rows.Scan(
-            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.Status,
+            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
             &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
             &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
         ); err != nil {
@@ -310,14 +508,26 @@ func GetAppointmentsByDoctorId(c *gin.Context, db *sql.DB) {
 
 func GetAppointmentsByAppointmentDate(c *gin.Context, db *sql.DB) {
     dateStr := c.Param("date") // YYYY-MM-DD
+    typeId, ok := appointmentTypeFilter(c)
+    if !ok {
+        return
+    }
 
-    query := `SELECT id, pet_id, doctor_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
+    // The day is taken in the clinic timezone and queried as a range so the datetime index is used
+    dayStart, err := utils.ParseClinicDate(dateStr)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be YYYY-MM-DD"})
+        return
+    }
+
+    query := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
             FROM "Appointments"
-            WHERE DATE(appointment_datetime) = $1
+            WHERE appointment_datetime >= $1 AND appointment_datetime < $2
             AND active_status=1
+            AND ($3::uuid IS NULL OR appointment_type_id=$3)
             ORDER BY appointment_datetime DESC`
 
-    rows, err := db.Query(query, dateStr)
+    rows, err := db.Query(query, dayStart, dayStart.AddDate(0, 0, 1), typeId)
     if err != nil {
         log.Println("Error fetching appointments by date:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
@@ -329,7 +539,108 @@ func GetAppointmentsByAppointmentDate(c *gin.Context, db *sql.DB) {
     for rows.Next() {
         var appt structs.Appointment
         if err := rows.Scan(
-            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.Status,
+            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
+            &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
+            &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning appointment row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse appointments"})
+            return
+        }
+        appointments = append(appointments, appt)
+    }
+
+    c.JSON(http.StatusOK, appointments)
+}
+
+// parseRangeBound accepts RFC3339 or YYYY-MM-DD (midnight in the clinic timezone)
+func parseRangeBound(value string) (time.Time, bool, error) {
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, false, nil
+    }
+    t, err := utils.ParseClinicDate(value)
+    return t, true, err
+}
+
+// ListAppointments: GET /api/appointments?from=&to=&doctor_id=&status=&pet_id=&type_id=
+// from defaults to today (clinic timezone), to defaults to from + 1 day; a date-only "to" is inclusive
+func ListAppointments(c *gin.Context, db *sql.DB) {
+    loc := utils.ClinicLocation()
+    now := time.Now().In(loc)
+    from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+    if raw := c.Query("from"); raw != "" {
+        t, _, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        from = t
+    }
+    to := from.AddDate(0, 0, 1)
+    if raw := c.Query("to"); raw != "" {
+        t, dateOnly, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        if dateOnly {
+            t = t.AddDate(0, 0, 1)
+        }
+        to = t
+    }
+    if !to.After(from) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+        return
+    }
+    if to.Sub(from) > 366*24*time.Hour {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Range cannot be longer than one year"})
+        return
+    }
+
+    query := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes, active_status, created_at, created_by, modified_at, modified_by
+            FROM "Appointments"
+            WHERE appointment_datetime >= $1 AND appointment_datetime < $2
+            AND active_status=1`
+    args := []interface{}{from, to}
+
+    // Optional filters
+    for _, filter := range []struct{ param, column string }{
+        {"doctor_id", "doctor_id"},
+        {"pet_id", "pet_id"},
+        {"type_id", "appointment_type_id"},
+    } {
+        raw := c.Query(filter.param)
+        if raw == "" {
+            continue
+        }
+        id, err := uuid.Parse(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter.param})
+            return
+        }
+        args = append(args, id)
+        query += " AND " + filter.column + "=$" + strconv.Itoa(len(args))
+    }
+    if status := c.Query("status"); status != "" {
+        args = append(args, status)
+        query += " AND status=$" + strconv.Itoa(len(args))
+    }
+    query += " ORDER BY appointment_datetime ASC"
+
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        log.Println("Error fetching appointments by range:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
+        return
+    }
+    defer rows.Close()
+
+    var appointments []structs.Appointment
+    for rows.Next() {
+        var appt structs.Appointment
+        if err := rows.Scan(
+            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
             &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
             &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
         ); err != nil {
@@ -351,13 +662,13 @@ func GetFullAppointmentDetail(c *gin.Context, db *sql.DB) {
     // -----------------------------
     var appointment structs.Appointment
 
-    apptQuery := `SELECT id, pet_id, doctor_id, status, appointment_datetime, notes,
+    apptQuery := `SELECT id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes,
                 	active_status, created_at, created_by, modified_at, modified_by
                   	FROM "Appointments"
                     WHERE id=$1 AND active_status=1`
 
     err := db.QueryRow(apptQuery, appointmentId).Scan(
-        &appointment.Id, &appointment.PetId, &appointment.DoctorId, &appointment.Status,
+        &appointment.Id, &appointment.PetId, &appointment.DoctorId, &appointment.AppointmentTypeId, &appointment.Status,
         &appointment.AppointmentDatetime, &appointment.Notes, &appointment.ActiveStatus,
         &appointment.CreatedAt, &appointment.CreatedBy, &appointment.ModifiedAt, &appointment.ModifiedBy,
     )
@@ -366,22 +677,15 @@ func GetFullAppointmentDetail(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
         return
     }
+    fetchAppointmentCancellation(db, &appointment)
 
     // -----------------------------
     // 2. Fetch Medical Record (1:1)
     // -----------------------------
-    var medicalRecord structs.MedicalRecord
-
-    mrQuery := `SELECT id, appointment_id, pet_id, diagnosis, notes,
-                active_status, created_at, created_by, modified_at, modified_by
-                FROM "MedicalRecords"
-                WHERE appointment_id=$1 AND active_status=1`
-
-    err = db.QueryRow(mrQuery, appointmentId).Scan(
-        &medicalRecord.Id, &medicalRecord.AppointmentId, &medicalRecord.PetId,
-        &medicalRecord.Diagnosis, &medicalRecord.Notes, &medicalRecord.ActiveStatus,
-        &medicalRecord.CreatedAt, &medicalRecord.CreatedBy, &medicalRecord.ModifiedAt, &medicalRecord.ModifiedBy,
-    )
+    medicalRecord, err := fetchMedicalRecordByAppointment(db, appointmentId)
+    if err != nil && err != sql.ErrNoRows {
+        log.Println("Error fetching medical record:", err)
+    }
 
     hasMedicalRecord := (err == nil)
 
@@ -423,7 +727,20 @@ func GetFullAppointmentDetail(c *gin.Context, db *sql.DB) {
     }
 
     // -----------------------------
-    // 4. Build final response
+    // 4. Fetch Invoice (not Void)
+    // -----------------------------
+    var invoice interface{}
+    var inv structs.Invoice
+    err = scanInvoice(db.QueryRow(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE appointment_id=$1 AND status <> 'Void' AND active_status=1`, appointmentId), &inv)
+    if err == nil {
+        invoice = inv
+    } else if err != sql.ErrNoRows {
+        log.Println("Error fetching invoice:", err)
+    }
+
+    // -----------------------------
+    // 5. Build final response
     // -----------------------------
     response := gin.H{
         "appointment": appointment,
@@ -434,7 +751,8 @@ func GetFullAppointmentDetail(c *gin.Context, db *sql.DB) {
             return nil
         }(),
         "treatments":  treatments,
-        "total_cost":  totalCost,
+        "total_cost":  totalCost, // current treatment costs, the invoice keeps the amounts it was made with
+        "invoice":     invoice,
     }
 
     c.JSON(http.StatusOK, response)
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// checkAppointmentType validates the type of an appointment and that the assigned user has the role it requires.
+// Returns an empty string when valid, otherwise the error message for the client.
+func checkAppointmentType(db *sql.DB, typeId *uuid.UUID, doctorId uuid.UUID) string {
+    if typeId == nil {
+        return ""
+    }
+
+    var requiredRole string
+    err := db.QueryRow(`SELECT required_role FROM "AppointmentTypes" WHERE id=$1 AND active_status=1`, *typeId).Scan(&requiredRole)
+    if err != nil {
+        return "Appointment type not found"
+    }
+
+    if doctorId != uuid.Nil {
+        var role string
+        err = db.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, doctorId).Scan(&role)
+        if err != nil || role != requiredRole {
+            return "This appointment type must be assigned to an active " + requiredRole
+        }
+    }
+    return ""
+}
+
+// appointmentTypeFilter reads the optional ?type_id= filter of the appointment list endpoints
+func appointmentTypeFilter(c *gin.Context) (*uuid.UUID, bool) {
+    raw := c.Query("type_id")
+    if raw == "" {
+        return nil, true
+    }
+    typeId, err := uuid.Parse(raw)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid type_id"})
+        return nil, false
+    }
+    return &typeId, true
+}
+
+func isValidRequiredRole(role string) bool {
+    return role == "Doctor" || role == "Staff"
+}
+
+func CreateAppointmentType(c *gin.Context, db *sql.DB) {
+    var newType structs.AppointmentType
+    if err := c.ShouldBindJSON(&newType); err != nil {
+        log.Println("Error binding JSON for new AppointmentType:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    if newType.Name == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+        return
+    }
+    if newType.DefaultDurationMinutes == 0 {
+        newType.DefaultDurationMinutes = 30
+    }
+    if newType.RequiredRole == "" {
+        newType.RequiredRole = "Doctor"
+    }
+    if newType.DefaultDurationMinutes < 0 || newType.DefaultPrice < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "DefaultDurationMinutes and DefaultPrice cannot be negative"})
+        return
+    }
+    if !isValidRequiredRole(newType.RequiredRole) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "RequiredRole must be Doctor or Staff"})
+        return
+    }
+    if newType.Color != "" && !hexColorPattern.MatchString(newType.Color) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Color must be a hex color like #4A90D9"})
+        return
+    }
+    if newType.RequiresConsent == nil {
+        requiresConsent := false
+        newType.RequiresConsent = &requiresConsent
+    }
+    newType.BillingCategory = strings.TrimSpace(newType.BillingCategory)
+    if newType.BillingCategory == "" {
+        newType.BillingCategory = "Service"
+    }
+    if msg := checkBillingCategory(db, newType.BillingCategory); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    newType.Id = uuid.New()
+    newType.ActiveStatus = 1
+    newType.CreatedAt = time.Now()
+    newType.CreatedBy = createdBy
+    newType.ModifiedAt = newType.CreatedAt
+    newType.ModifiedBy = createdBy
+
+    query := `INSERT INTO "AppointmentTypes"
+        (id, name, default_duration_minutes, default_price, required_role, color, requires_consent, billing_category,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`
+
+    _, err := db.Exec(query,
+        newType.Id, newType.Name, newType.DefaultDurationMinutes, newType.DefaultPrice,
+        newType.RequiredRole, newType.Color, newType.RequiresConsent, newType.BillingCategory,
+        newType.ActiveStatus, newType.CreatedAt, newType.CreatedBy, newType.ModifiedAt, newType.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting AppointmentType:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment type"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, newType)
+}
+
+func GetAppointmentTypes(c *gin.Context, db *sql.DB) {
+    query := `SELECT id, name, default_duration_minutes, default_price, required_role, COALESCE(color, ''), requires_consent, billing_category,
+            active_status, created_at, created_by, modified_at, modified_by
+            FROM "AppointmentTypes"
+            WHERE active_status=1
+            ORDER BY name ASC`
+
+    rows, err := db.Query(query)
+    if err != nil {
+        log.Println("Error fetching appointment types:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment types"})
+        return
+    }
+    defer rows.Close()
+
+    var types []structs.AppointmentType
+    for rows.Next() {
+        var t structs.AppointmentType
+        if err := rows.Scan(
+            &t.Id, &t.Name, &t.DefaultDurationMinutes, &t.DefaultPrice, &t.RequiredRole, &t.Color, &t.RequiresConsent, &t.BillingCategory,
+            &t.ActiveStatus, &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning appointment type row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse appointment types"})
+            return
+        }
+        types = append(types, t)
+    }
+
+    c.JSON(http.StatusOK, types)
+}
+
+func UpdateAppointmentType(c *gin.Context, db *sql.DB) {
+    typeId := c.Param("id")
+
+    // 1. Fetch existing type
+    var existing structs.AppointmentType
+    fetchQuery := `SELECT id, name, default_duration_minutes, default_price, required_role, COALESCE(color, ''), requires_consent, billing_category,
+                    active_status, created_at, created_by, modified_at, modified_by
+                    FROM "AppointmentTypes"
+                    WHERE id=$1 AND active_status=1`
+
+    err := db.QueryRow(fetchQuery, typeId).Scan(
+        &existing.Id, &existing.Name, &existing.DefaultDurationMinutes, &existing.DefaultPrice,
+        &existing.RequiredRole, &existing.Color, &existing.RequiresConsent, &existing.BillingCategory,
+        &existing.ActiveStatus, &existing.CreatedAt, &existing.CreatedBy,
+        &existing.ModifiedAt, &existing.ModifiedBy,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON (a pointer for the price, so it can be set to 0)
+    var req struct {
+        structs.AppointmentType
+        DefaultPrice *int `json:"default_price"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateAppointmentType:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if req.Name != "" {
+        existing.Name = req.Name
+    }
+    if req.DefaultDurationMinutes > 0 {
+        existing.DefaultDurationMinutes = req.DefaultDurationMinutes
+    }
+    if req.DefaultPrice != nil {
+        if *req.DefaultPrice < 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "DefaultPrice cannot be negative"})
+            return
+        }
+        existing.DefaultPrice = *req.DefaultPrice
+    }
+    if req.RequiredRole != "" {
+        if !isValidRequiredRole(req.RequiredRole) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "RequiredRole must be Doctor or Staff"})
+            return
+        }
+        existing.RequiredRole = req.RequiredRole
+    }
+    if req.Color != "" {
+        if !hexColorPattern.MatchString(req.Color) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Color must be a hex color like #4A90D9"})
+            return
+        }
+        existing.Color = req.Color
+    }
+    if req.RequiresConsent != nil {
+        existing.RequiresConsent = req.RequiresConsent
+    }
+    if category := strings.TrimSpace(req.BillingCategory); category != "" {
+        if msg := checkBillingCategory(db, category); msg != "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+            return
+        }
+        existing.BillingCategory = category
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "AppointmentTypes"
+                    SET name=$1, default_duration_minutes=$2, default_price=$3, required_role=$4, color=$5,
+                        requires_consent=$6, billing_category=$7, modified_at=$8, modified_by=$9
+                    WHERE id=$10 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.Name, existing.DefaultDurationMinutes, existing.DefaultPrice, existing.RequiredRole, existing.Color,
+        existing.RequiresConsent, existing.BillingCategory, time.Now(), modifiedBy, typeId,
+    )
+    if err != nil {
+        log.Println("Error updating AppointmentType:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appointment type"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Appointment type updated successfully"})
+}
+
+func UpdateAppointmentTypeActiveStatus(c *gin.Context, db *sql.DB) {
+    typeId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "AppointmentTypes"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, typeId)
+    if err != nil {
+        log.Println("Error soft deleting AppointmentType:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate appointment type"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             typeId,
+        "deactivated_by": modifiedBy,
+        "message":        "Appointment type deactivated successfully",
+    })
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// canManageDoctorCalendar: doctors manage their own feed tokens, Admin manages everyone's
+func canManageDoctorCalendar(c *gin.Context, doctorId string) bool {
+    role, _ := c.Get("role")
+    userId, _ := c.Get("user_id")
+    return role == "Admin" || userId == doctorId
+}
+
+func CreateCalendarToken(c *gin.Context, db *sql.DB) {
+    doctorId := c.Param("doctor_id")
+
+    if !canManageDoctorCalendar(c, doctorId) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own calendar feed"})
+        return
+    }
+
+    // Doctor must exist and be active
+    var role string
+    err := db.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, doctorId).Scan(&role)
+    if err != nil || role != "Doctor" {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Doctor not found"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tokenString, err := utils.GenerateToken(32)
+    if err != nil {
+        log.Println("Error generating calendar token:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+        return
+    }
+
+    token := structs.CalendarToken{
+        Id:           uuid.New(),
+        DoctorId:     uuid.MustParse(doctorId),
+        Token:        tokenString,
+        ActiveStatus: 1,
+        CreatedAt:    time.Now(),
+        CreatedBy:    createdBy,
+    }
+    token.ModifiedAt = token.CreatedAt
+    token.ModifiedBy = createdBy
+
+    // Only the hash is stored, the token itself is returned this once
+    query := `INSERT INTO "CalendarTokens"
+        (id, doctor_id, token_hash, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`
+
+    _, err = db.Exec(query,
+        token.Id, token.DoctorId, utils.HashToken(token.Token), token.ActiveStatus,
+        token.CreatedAt, token.CreatedBy, token.ModifiedAt, token.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting CalendarToken:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar token"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "token":    token,
+        "feed_url": "/api/calendar/feed/" + token.Token + ".ics",
+    })
+}
+
+func GetCalendarTokensByDoctorId(c *gin.Context, db *sql.DB) {
+    doctorId := c.Param("doctor_id")
+
+    if !canManageDoctorCalendar(c, doctorId) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own calendar feed"})
+        return
+    }
+
+    query := `SELECT id, doctor_id, revoked_at, revoked_by, active_status, created_at, created_by, modified_at, modified_by
+            FROM "CalendarTokens"
+            WHERE doctor_id=$1 AND active_status=1
+            ORDER BY created_at DESC`
+
+    rows, err := db.Query(query, doctorId)
+    if err != nil {
+        log.Println("Error fetching calendar tokens:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar tokens"})
+        return
+    }
+    defer rows.Close()
+
+    var tokens []structs.CalendarToken
+    for rows.Next() {
+        var t structs.CalendarToken
+        if err := rows.Scan(
+            &t.Id, &t.DoctorId, &t.RevokedAt, &t.RevokedBy, &t.ActiveStatus,
+            &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning calendar token row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse calendar tokens"})
+            return
+        }
+        tokens = append(tokens, t)
+    }
+
+    c.JSON(http.StatusOK, tokens)
+}
+
+func RevokeCalendarToken(c *gin.Context, db *sql.DB) {
+    tokenId := c.Param("id")
+
+    var doctorId string
+    err := db.QueryRow(`SELECT doctor_id FROM "CalendarTokens" WHERE id=$1 AND revoked_at IS NULL AND active_status=1`,
+        tokenId).Scan(&doctorId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Calendar token not found"})
+        return
+    }
+    if !canManageDoctorCalendar(c, doctorId) {
+        c.JSON(http.StatusForbidden, gin.H{"error": "You can only manage your own calendar feed"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    revokedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    now := time.Now()
+    query := `UPDATE "CalendarTokens"
+            SET revoked_at=$1, revoked_by=$2, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err = db.Exec(query, now, revokedBy, tokenId)
+    if err != nil {
+        log.Println("Error revoking CalendarToken:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar token"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":         tokenId,
+        "revoked_by": revokedBy,
+        "message":    "Calendar token revoked successfully",
+    })
+}
+
+// GetDoctorCalendarFeed serves the .ics subscription feed; the token in the URL is the only credential
+func GetDoctorCalendarFeed(c *gin.Context, db *sql.DB) {
+    tokenString := strings.TrimSuffix(c.Param("token"), ".ics")
+
+    var doctorId, doctorName string
+    err := db.QueryRow(`SELECT u.id, u.name
+                        FROM "CalendarTokens" t
+                        JOIN "Users" u ON u.id = t.doctor_id
+                        WHERE t.token_hash=$1 AND t.revoked_at IS NULL AND t.active_status=1 AND u.active_status=1`,
+        utils.HashToken(tokenString)).Scan(&doctorId, &doctorName)
+    if err != nil {
+        c.AbortWithStatus(http.StatusNotFound)
+        return
+    }
+
+    // Cancelled and deactivated appointments stay in the feed so subscribed calendars remove them
+    query := `SELECT a.id, a.status, a.appointment_datetime, COALESCE(a.notes, ''), a.active_status,
+                a.calendar_sequence, a.modified_at, p.name, COALESCE(p.species, ''),
+                COALESCE(t.name, 'Appointment'), COALESCE(t.default_duration_minutes, 30)
+            FROM "Appointments" a
+            JOIN "Pets" p ON p.id = a.pet_id
+            LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+            WHERE a.doctor_id=$1 AND a.appointment_datetime >= $2
+            ORDER BY a.appointment_datetime ASC`
+
+    rows, err := db.Query(query, doctorId, time.Now().AddDate(0, 0, -90))
+    if err != nil {
+        log.Println("Error fetching appointments for calendar feed:", err)
+        c.AbortWithStatus(http.StatusInternalServerError)
+        return
+    }
+    defer rows.Close()
+
+    var events []utils.CalendarEvent
+    for rows.Next() {
+        var (
+            appointmentId, status, notes, petName, species, typeName string
+            start, modifiedAt                                        time.Time
+            activeStatus, sequence, durationMinutes                  int
+        )
+        if err := rows.Scan(
+            &appointmentId, &status, &start, &notes, &activeStatus,
+            &sequence, &modifiedAt, &petName, &species, &typeName, &durationMinutes,
+        ); err != nil {
+            log.Println("Error scanning calendar feed row:", err)
+            c.AbortWithStatus(http.StatusInternalServerError)
+            return
+        }
+
+        eventStatus := "CONFIRMED"
+        if status == "Cancelled" || activeStatus == 0 {
+            eventStatus = "CANCELLED"
+        }
+
+        events = append(events, utils.CalendarEvent{
+            UID:          appointmentId + "@vetclinic-rest-api",
+            Start:        start,
+            End:          start.Add(time.Duration(durationMinutes) * time.Minute),
+            Summary:      fmt.Sprintf("%s (%s) - %s", petName, species, typeName),
+            Description:  notes,
+            Status:       eventStatus,
+            Sequence:     sequence,
+            LastModified: modifiedAt,
+        })
+    }
+
+    c.Header("Content-Disposition", `inline; filename="appointments.ics"`)
+    c.Data(http.StatusOK, "text/calendar; charset=utf-8",
+        []byte(utils.BuildICalendar("VetClinic - "+doctorName, events)))
+}
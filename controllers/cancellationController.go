@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// cancellationRequest is the body required to cancel an appointment
+type cancellationRequest struct {
+    CancellationReasonId *uuid.UUID `json:"cancellation_reason_id"`
+    CancellationNote     string     `json:"cancellation_note"`
+    CancelledByParty     string     `json:"cancelled_by_party"` // Owner, Clinic (owners cancelling online are always Owner)
+}
+
+// checkCancellationReason validates the reason against the cancelling party.
+// Returns an empty string when valid, otherwise the error message for the client.
+func checkCancellationReason(db *sql.DB, req cancellationRequest) string {
+    if req.CancellationReasonId == nil {
+        return "CancellationReasonId is required"
+    }
+    if req.CancelledByParty != "Owner" && req.CancelledByParty != "Clinic" {
+        return "CancelledByParty must be Owner or Clinic"
+    }
+
+    var party string
+    var requiresNote bool
+    err := db.QueryRow(`SELECT party, requires_note FROM "CancellationReasons" WHERE id=$1 AND active_status=1`,
+        *req.CancellationReasonId).Scan(&party, &requiresNote)
+    if err != nil {
+        return "Cancellation reason not found"
+    }
+    if party != "Any" && party != req.CancelledByParty {
+        return "This cancellation reason can only be used by the " + party
+    }
+    if requiresNote && req.CancellationNote == "" {
+        return "CancellationNote is required for this reason"
+    }
+    return ""
+}
+
+// cancelAppointment records the cancellation with its reason, party and late flag, then frees the slot
+func cancelAppointment(db *sql.DB, appointmentId string, appointmentDatetime time.Time, req cancellationRequest, modifiedBy string, requiredStatus string) (bool, error) {
+    now := time.Now()
+    late := services.LoadBookingRules().IsLateCancellation(appointmentDatetime)
+    // requiredStatus guards against the status changing since it was checked, "" cancels from any status
+    result, err := db.Exec(`UPDATE "Appointments"
+                    SET status='Cancelled', cancellation_reason_id=$1, cancellation_note=$2, cancelled_by_party=$3,
+                        cancelled_at=$4, late_cancellation=$5, no_show_at=NULL, modified_at=$4, modified_by=$6
+                    WHERE id=$7 AND active_status=1 AND ($8='' OR status=$8)`,
+        *req.CancellationReasonId, req.CancellationNote, req.CancelledByParty, now, late, modifiedBy, appointmentId, requiredStatus)
+    if err != nil {
+        return false, err
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        return false, nil
+    }
+
+    // Give back rooms/equipment and offer the freed slot to the waitlist
+    if err := services.ReleaseResources(db, appointmentId, modifiedBy); err != nil {
+        log.Println("Error releasing Appointment resources:", err)
+    }
+    services.SlotFreed(appointmentId)
+    return true, nil
+}
+
+// fetchAppointmentCancellation fills the cancellation details of a cancelled appointment
+func fetchAppointmentCancellation(db *sql.DB, appointment *structs.Appointment) {
+    if appointment.Status != "Cancelled" {
+        return
+    }
+    var cancellation structs.AppointmentCancellation
+    err := db.QueryRow(`SELECT a.cancellation_reason_id, COALESCE(r.name, ''), COALESCE(a.cancellation_note, ''),
+                            COALESCE(a.cancelled_by_party, ''), a.cancelled_at, a.late_cancellation
+                        FROM "Appointments" a
+                        LEFT JOIN "CancellationReasons" r ON r.id = a.cancellation_reason_id
+                        WHERE a.id=$1`, appointment.Id).Scan(
+        &cancellation.ReasonId, &cancellation.Reason, &cancellation.Note,
+        &cancellation.CancelledByParty, &cancellation.CancelledAt, &cancellation.LateCancellation,
+    )
+    if err != nil {
+        log.Println("Error fetching Appointment cancellation:", err)
+        return
+    }
+    appointment.Cancellation = &cancellation
+}
+
+// ownerAttendance counts no-shows and late cancellations over all pets of an owner
+// (or only of the pet itself when it isn't linked to an owner)
+func ownerAttendance(db *sql.DB, ownerId *uuid.UUID, petId uuid.UUID) (int, int, error) {
+    var noShows, lateCancellations int
+    err := db.QueryRow(`SELECT COUNT(*) FILTER (WHERE a.status='NoShow'),
+                            COUNT(*) FILTER (WHERE a.status='Cancelled' AND a.late_cancellation)
+                        FROM "Appointments" a
+                        JOIN "Pets" p ON p.id = a.pet_id
+                        WHERE a.active_status=1 AND (p.owner_id=$1 OR p.id=$2)`, ownerId, petId).Scan(
+        &noShows, &lateCancellations,
+    )
+    return noShows, lateCancellations, err
+}
+
+func CreateCancellationReason(c *gin.Context, db *sql.DB) {
+    var newReason structs.CancellationReason
+    if err := c.ShouldBindJSON(&newReason); err != nil {
+        log.Println("Error binding JSON for new CancellationReason:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    if newReason.Name == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+        return
+    }
+    if newReason.Party == "" {
+        newReason.Party = "Any"
+    }
+    if newReason.Party != "Owner" && newReason.Party != "Clinic" && newReason.Party != "Any" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Party must be Owner, Clinic or Any"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    newReason.Id = uuid.New()
+    newReason.ActiveStatus = 1
+    newReason.CreatedAt = time.Now()
+    newReason.CreatedBy = createdBy
+    newReason.ModifiedAt = newReason.CreatedAt
+    newReason.ModifiedBy = createdBy
+
+    query := `INSERT INTO "CancellationReasons"
+        (id, name, party, requires_note, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`
+
+    _, err := db.Exec(query,
+        newReason.Id, newReason.Name, newReason.Party, newReason.RequiresNote,
+        newReason.ActiveStatus, newReason.CreatedAt, newReason.CreatedBy, newReason.ModifiedAt, newReason.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting CancellationReason:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cancellation reason"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, newReason)
+}
+
+// GetCancellationReasons lists active reasons, optionally only those usable by ?party=Owner|Clinic
+func GetCancellationReasons(c *gin.Context, db *sql.DB) {
+    party := c.Query("party")
+    // owners only ever see their own reasons
+    if role, _ := c.Get("role"); role == "Owner" {
+        party = "Owner"
+    }
+
+    query := `SELECT id, name, party, requires_note, active_status, created_at, created_by, modified_at, modified_by
+            FROM "CancellationReasons"
+            WHERE active_status=1 AND ($1='' OR party=$1 OR party='Any')
+            ORDER BY party ASC, name ASC`
+
+    rows, err := db.Query(query, party)
+    if err != nil {
+        log.Println("Error fetching cancellation reasons:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cancellation reasons"})
+        return
+    }
+    defer rows.Close()
+
+    var reasons []structs.CancellationReason
+    for rows.Next() {
+        var r structs.CancellationReason
+        if err := rows.Scan(
+            &r.Id, &r.Name, &r.Party, &r.RequiresNote,
+            &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning cancellation reason row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse cancellation reasons"})
+            return
+        }
+        reasons = append(reasons, r)
+    }
+
+    c.JSON(http.StatusOK, reasons)
+}
+
+func UpdateCancellationReasonActiveStatus(c *gin.Context, db *sql.DB) {
+    reasonId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "CancellationReasons"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, reasonId)
+    if err != nil {
+        log.Println("Error soft deleting CancellationReason:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate cancellation reason"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             reasonId,
+        "deactivated_by": modifiedBy,
+        "message":        "Cancellation reason deactivated successfully",
+    })
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const clinicProfileColumns = `id, name, COALESCE(address, ''), COALESCE(phone, ''), COALESCE(email, ''), COALESCE(website, ''),
+                COALESCE(footer_text, ''), accent_color, logo IS NOT NULL, invoice_prefix, receipt_prefix,
+                rounding_unit, staff_discount_limit, active_status, created_at, created_by, modified_at, modified_by`
+
+// Invoice and receipt numbers already issued keep the prefix they were issued with
+var invoicePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/-]{1,10}$`)
+
+// Logos are printed at a few centimeters, larger files only slow down every PDF
+const maxLogoBytes = 1024 * 1024
+
+// maxLogoPixels caps the decoded size: a small, highly compressed file can declare huge dimensions
+// and make every PDF render allocate gigabytes
+const maxLogoPixels = 4000 * 4000
+
+func scanClinicProfile(row interface{ Scan(...interface{}) error }, p *structs.ClinicProfile) error {
+    return row.Scan(
+        &p.Id, &p.Name, &p.Address, &p.Phone, &p.Email, &p.Website,
+        &p.FooterText, &p.AccentColor, &p.HasLogo, &p.InvoicePrefix, &p.ReceiptPrefix,
+        &p.RoundingUnit, &p.StaffDiscountLimit, &p.ActiveStatus, &p.CreatedAt, &p.CreatedBy, &p.ModifiedAt, &p.ModifiedBy,
+    )
+}
+
+// fetchClinicProfile loads the single clinic profile row
+func fetchClinicProfile(db *sql.DB) (structs.ClinicProfile, error) {
+    var profile structs.ClinicProfile
+    err := scanClinicProfile(db.QueryRow(`SELECT `+clinicProfileColumns+` FROM "ClinicProfile"
+                    WHERE active_status=1 ORDER BY created_at ASC LIMIT 1`), &profile)
+    return profile, err
+}
+
+func GetClinicProfile(c *gin.Context, db *sql.DB) {
+    profile, err := fetchClinicProfile(db)
+    if err != nil {
+        log.Println("Error fetching ClinicProfile:", err)
+        c.JSON(http.StatusNotFound, gin.H{"error": "Clinic profile not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, profile)
+}
+
+func UpdateClinicProfile(c *gin.Context, db *sql.DB) {
+    // 1. Fetch existing profile
+    existing, err := fetchClinicProfile(db)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Clinic profile not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.ClinicProfile
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateClinicProfile:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if name := strings.TrimSpace(req.Name); name != "" {
+        existing.Name = name
+    }
+    if req.Address != "" {
+        existing.Address = strings.TrimSpace(req.Address)
+    }
+    if req.Phone != "" {
+        existing.Phone = strings.TrimSpace(req.Phone)
+    }
+    if req.Email != "" {
+        existing.Email = strings.TrimSpace(req.Email)
+    }
+    if req.Website != "" {
+        existing.Website = strings.TrimSpace(req.Website)
+    }
+    if req.FooterText != "" {
+        existing.FooterText = strings.TrimSpace(req.FooterText)
+    }
+    if req.AccentColor != "" {
+        if !hexColorPattern.MatchString(req.AccentColor) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "AccentColor must be a hex color like #4A90D9"})
+            return
+        }
+        existing.AccentColor = req.AccentColor
+    }
+    if req.InvoicePrefix != "" {
+        if !invoicePrefixPattern.MatchString(req.InvoicePrefix) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "InvoicePrefix must be up to 10 letters, digits, - or /"})
+            return
+        }
+        existing.InvoicePrefix = req.InvoicePrefix
+    }
+    if req.ReceiptPrefix != "" {
+        if !invoicePrefixPattern.MatchString(req.ReceiptPrefix) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "ReceiptPrefix must be up to 10 letters, digits, - or /"})
+            return
+        }
+        existing.ReceiptPrefix = req.ReceiptPrefix
+    }
+    if req.RoundingUnit != 0 {
+        if req.RoundingUnit < 1 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "RoundingUnit must be at least 1"})
+            return
+        }
+        existing.RoundingUnit = req.RoundingUnit
+    }
+    if req.StaffDiscountLimit != nil {
+        if *req.StaffDiscountLimit < 0 || *req.StaffDiscountLimit > 100 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "StaffDiscountLimit must be a percent between 0 and 100"})
+            return
+        }
+        existing.StaffDiscountLimit = req.StaffDiscountLimit
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "ClinicProfile"
+                    SET name=$1, address=NULLIF($2, ''), phone=NULLIF($3, ''), email=NULLIF($4, ''), website=NULLIF($5, ''),
+                        footer_text=NULLIF($6, ''), accent_color=$7, invoice_prefix=$8, receipt_prefix=$9,
+                        rounding_unit=$10, staff_discount_limit=$11, modified_at=$12, modified_by=$13
+                    WHERE id=$14 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.Name, existing.Address, existing.Phone, existing.Email, existing.Website,
+        existing.FooterText, existing.AccentColor, existing.InvoicePrefix, existing.ReceiptPrefix,
+        existing.RoundingUnit, existing.StaffDiscountLimit, time.Now(), modifiedBy, existing.Id,
+    )
+    if err != nil {
+        log.Println("Error updating ClinicProfile:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update clinic profile"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Clinic profile updated successfully"})
+}
+
+// UpdateClinicLogo replaces the logo with a PNG or JPEG sent as multipart "file"
+func UpdateClinicLogo(c *gin.Context, db *sql.DB) {
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A PNG or JPEG image is required in the file field"})
+        return
+    }
+    if fileHeader.Size > maxLogoBytes {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Logo must be 1 MB or smaller"})
+        return
+    }
+    file, err := fileHeader.Open()
+    if err != nil {
+        log.Println("Error opening clinic logo upload:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+    defer file.Close()
+
+    logo, err := io.ReadAll(io.LimitReader(file, maxLogoBytes+1))
+    if err != nil || len(logo) > maxLogoBytes {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+    // Trust the bytes, not the declared type, and make sure the PDF writer can decode it
+    mime := http.DetectContentType(logo)
+    if mime != "image/png" && mime != "image/jpeg" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be a PNG or JPEG image"})
+        return
+    }
+    cfg, _, err := image.DecodeConfig(bytes.NewReader(logo))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Logo image can't be read"})
+        return
+    }
+    if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxLogoPixels {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Logo must be at most 16 megapixels (e.g. 4000x4000)"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "ClinicProfile"
+                    SET logo=$1, logo_mime=$2, modified_at=$3, modified_by=$4
+                    WHERE active_status=1`, logo, mime, time.Now(), modifiedBy)
+    if err != nil {
+        log.Println("Error updating ClinicProfile logo:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update logo"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Clinic profile not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Logo updated successfully"})
+}
+
+func GetClinicLogo(c *gin.Context, db *sql.DB) {
+    var logo []byte
+    var mime string
+    err := db.QueryRow(`SELECT logo, logo_mime FROM "ClinicProfile"
+                        WHERE active_status=1 AND logo IS NOT NULL
+                        ORDER BY created_at ASC LIMIT 1`).Scan(&logo, &mime)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Logo not found"})
+        return
+    }
+
+    c.Data(http.StatusOK, mime, logo)
+}
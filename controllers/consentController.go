@@ -0,0 +1,617 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const consentTemplateColumns = `id, name, appointment_type_id, body, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanConsentTemplate(row interface{ Scan(...interface{}) error }, t *structs.ConsentTemplate) error {
+    return row.Scan(
+        &t.Id, &t.Name, &t.AppointmentTypeId, &t.Body, &t.ActiveStatus, &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
+    )
+}
+
+const consentColumns = `id, appointment_id, consent_template_id, procedure, estimate, body, body_sha256, status,
+                COALESCE(signer_name, ''), signature_image IS NOT NULL, signed_at, COALESCE(signer_ip, ''),
+                revoked_at, COALESCE(revoked_reason, ''), active_status, created_at, created_by, modified_at, modified_by`
+
+func scanConsent(row interface{ Scan(...interface{}) error }, c *structs.Consent) error {
+    return row.Scan(
+        &c.Id, &c.AppointmentId, &c.ConsentTemplateId, &c.Procedure, &c.Estimate, &c.Body, &c.BodySha256, &c.Status,
+        &c.SignerName, &c.HasSignature, &c.SignedAt, &c.SignerIp,
+        &c.RevokedAt, &c.RevokedReason, &c.ActiveStatus, &c.CreatedAt, &c.CreatedBy, &c.ModifiedAt, &c.ModifiedBy,
+    )
+}
+
+var consentPlaceholderPattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+var consentPlaceholders = map[string]bool{
+    "owner_name": true, "pet_name": true, "species": true, "breed": true,
+    "doctor_name": true, "procedure": true, "date": true, "estimate": true,
+}
+
+// Drawn signatures are small; anything larger is most likely not a signature pad export
+const maxSignatureBytes = 512 * 1024
+
+// checkConsentTemplate returns an empty string when valid, otherwise the error message for the client
+func checkConsentTemplate(t structs.ConsentTemplate) string {
+    if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.Body) == "" {
+        return "Name and Body are required"
+    }
+    for _, m := range consentPlaceholderPattern.FindAllStringSubmatch(t.Body, -1) {
+        if !consentPlaceholders[m[1]] {
+            return "Unknown placeholder {{" + m[1] + "}}, use owner_name, pet_name, species, breed, doctor_name, procedure, date or estimate"
+        }
+    }
+    return ""
+}
+
+// renderConsent fills the template placeholders, unknown ones are left as written
+func renderConsent(body string, values map[string]string) string {
+    return consentPlaceholderPattern.ReplaceAllStringFunc(body, func(m string) string {
+        key := consentPlaceholderPattern.FindStringSubmatch(m)[1]
+        if v, ok := values[key]; ok {
+            return v
+        }
+        return m
+    })
+}
+
+// formatAmount groups thousands, 1500000 -> 1,500,000
+func formatAmount(amount int) string {
+    digits := strconv.Itoa(amount)
+    sign := ""
+    if amount < 0 {
+        sign, digits = "-", digits[1:]
+    }
+    for i := len(digits) - 3; i > 0; i -= 3 {
+        digits = digits[:i] + "," + digits[i:]
+    }
+    return sign + digits
+}
+
+// appointmentConsentMissing tells whether the appointment's type requires consent and none is signed yet
+func appointmentConsentMissing(db *sql.DB, appointmentId string) (bool, error) {
+    var missing bool
+    err := db.QueryRow(`SELECT COALESCE(t.requires_consent, false) AND NOT EXISTS (
+                            SELECT 1 FROM "Consents" c
+                            WHERE c.appointment_id = a.id AND c.status='Signed' AND c.active_status=1)
+                        FROM "Appointments" a
+                        LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                        WHERE a.id=$1 AND a.active_status=1`, appointmentId).Scan(&missing)
+    return missing, err
+}
+
+func CreateConsentTemplate(c *gin.Context, db *sql.DB) {
+    var template structs.ConsentTemplate
+    if err := c.ShouldBindJSON(&template); err != nil {
+        log.Println("Error binding JSON for new ConsentTemplate:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    template.Name = strings.TrimSpace(template.Name)
+    if msg := checkConsentTemplate(template); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if template.AppointmentTypeId != nil {
+        var typeExists bool
+        if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "AppointmentTypes" WHERE id=$1 AND active_status=1)`,
+            *template.AppointmentTypeId).Scan(&typeExists); err != nil {
+            log.Println("Error checking AppointmentType of ConsentTemplate:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create consent template"})
+            return
+        }
+        if !typeExists {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Appointment type not found"})
+            return
+        }
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    template.Id = uuid.New()
+    template.ActiveStatus = 1
+    template.CreatedAt = time.Now()
+    template.CreatedBy = createdBy
+    template.ModifiedAt = template.CreatedAt
+    template.ModifiedBy = createdBy
+
+    query := `INSERT INTO "ConsentTemplates"
+        (id, name, appointment_type_id, body, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`
+
+    _, err := db.Exec(query,
+        template.Id, template.Name, template.AppointmentTypeId, template.Body,
+        template.ActiveStatus, template.CreatedAt, template.CreatedBy, template.ModifiedAt, template.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting ConsentTemplate:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create consent template"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, template)
+}
+
+// GetConsentTemplates lists active templates, optionally by ?type_id=
+func GetConsentTemplates(c *gin.Context, db *sql.DB) {
+    typeId, ok := appointmentTypeFilter(c)
+    if !ok {
+        return
+    }
+
+    rows, err := db.Query(`SELECT `+consentTemplateColumns+`
+                        FROM "ConsentTemplates"
+                        WHERE active_status=1 AND ($1::uuid IS NULL OR appointment_type_id=$1)
+                        ORDER BY name ASC`, typeId)
+    if err != nil {
+        log.Println("Error fetching consent templates:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consent templates"})
+        return
+    }
+    defer rows.Close()
+
+    templates := []structs.ConsentTemplate{}
+    for rows.Next() {
+        var t structs.ConsentTemplate
+        if err := scanConsentTemplate(rows, &t); err != nil {
+            log.Println("Error scanning consent template row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse consent templates"})
+            return
+        }
+        templates = append(templates, t)
+    }
+
+    c.JSON(http.StatusOK, templates)
+}
+
+// UpdateConsentTemplate changes the template for new consents; consents already generated keep their text
+func UpdateConsentTemplate(c *gin.Context, db *sql.DB) {
+    templateId := c.Param("id")
+
+    // 1. Fetch existing template
+    var existing structs.ConsentTemplate
+    err := scanConsentTemplate(db.QueryRow(`SELECT `+consentTemplateColumns+` FROM "ConsentTemplates" WHERE id=$1 AND active_status=1`, templateId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Consent template not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.ConsentTemplate
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateConsentTemplate:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if name := strings.TrimSpace(req.Name); name != "" {
+        existing.Name = name
+    }
+    if req.Body != "" {
+        existing.Body = req.Body
+    }
+    if req.AppointmentTypeId != nil {
+        var typeExists bool
+        if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "AppointmentTypes" WHERE id=$1 AND active_status=1)`,
+            *req.AppointmentTypeId).Scan(&typeExists); err != nil {
+            log.Println("Error checking AppointmentType of ConsentTemplate:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update consent template"})
+            return
+        }
+        if !typeExists {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Appointment type not found"})
+            return
+        }
+        existing.AppointmentTypeId = req.AppointmentTypeId
+    }
+    if msg := checkConsentTemplate(existing); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "ConsentTemplates"
+                    SET name=$1, appointment_type_id=$2, body=$3, modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.Name, existing.AppointmentTypeId, existing.Body, time.Now(), modifiedBy, templateId,
+    )
+    if err != nil {
+        log.Println("Error updating ConsentTemplate:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update consent template"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Consent template updated successfully"})
+}
+
+func UpdateConsentTemplateActiveStatus(c *gin.Context, db *sql.DB) {
+    templateId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "ConsentTemplates"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, templateId)
+    if err != nil {
+        log.Println("Error soft deleting ConsentTemplate:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate consent template"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             templateId,
+        "deactivated_by": modifiedBy,
+        "message":        "Consent template deactivated successfully",
+    })
+}
+
+// CreateAppointmentConsent renders a consent for the appointment. Without a template id the default template
+// of the appointment type is used; procedure and estimate default to the type's name and price.
+func CreateAppointmentConsent(c *gin.Context, db *sql.DB) {
+    appointmentId := c.Param("id")
+    var req struct {
+        ConsentTemplateId *uuid.UUID `json:"consent_template_id"`
+        Procedure         string     `json:"procedure"`
+        Estimate          *int       `json:"estimate"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for new Consent:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.Estimate != nil && *req.Estimate < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Estimate can't be negative"})
+        return
+    }
+
+    var (
+        status                             string
+        appointmentDatetime                time.Time
+        typeId                             *uuid.UUID
+        typeName                           string
+        typePrice                          int
+        petName, species, breed, ownerName string
+        doctorName                         string
+    )
+    err := db.QueryRow(`SELECT a.status, a.appointment_datetime, a.appointment_type_id,
+                            COALESCE(t.name, ''), COALESCE(t.default_price, 0),
+                            p.name, COALESCE(p.species, ''), COALESCE(p.breed, ''), COALESCE(p.owner_name, ''), COALESCE(u.name, '')
+                        FROM "Appointments" a
+                        JOIN "Pets" p ON p.id = a.pet_id
+                        LEFT JOIN "Users" u ON u.id = a.doctor_id
+                        LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                        WHERE a.id=$1 AND a.active_status=1`, appointmentId).Scan(
+        &status, &appointmentDatetime, &typeId, &typeName, &typePrice,
+        &petName, &species, &breed, &ownerName, &doctorName,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+    if status == "Cancelled" || status == "Completed" || status == "NoShow" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment is " + status + ", consents can't be added"})
+        return
+    }
+
+    var template structs.ConsentTemplate
+    if req.ConsentTemplateId != nil {
+        err = scanConsentTemplate(db.QueryRow(`SELECT `+consentTemplateColumns+` FROM "ConsentTemplates" WHERE id=$1 AND active_status=1`,
+            *req.ConsentTemplateId), &template)
+    } else if typeId != nil {
+        err = scanConsentTemplate(db.QueryRow(`SELECT `+consentTemplateColumns+` FROM "ConsentTemplates"
+                                WHERE appointment_type_id=$1 AND active_status=1
+                                ORDER BY created_at ASC LIMIT 1`, *typeId), &template)
+    } else {
+        err = sql.ErrNoRows
+    }
+    if err != nil {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Consent template not found, pass consent_template_id"})
+        return
+    }
+
+    // One open consent per template, a revoked one can be generated again
+    var duplicate bool
+    if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Consents"
+                    WHERE appointment_id=$1 AND consent_template_id=$2 AND status IN ('Pending', 'Signed') AND active_status=1)`,
+        appointmentId, template.Id).Scan(&duplicate); err != nil {
+        log.Println("Error checking existing Consents:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create consent"})
+        return
+    }
+    if duplicate {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment already has this consent, revoke it to generate a new one"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    consent := structs.Consent{
+        Id:                uuid.New(),
+        ConsentTemplateId: template.Id,
+        Procedure:         strings.TrimSpace(req.Procedure),
+        Estimate:          typePrice,
+        Status:            "Pending",
+        ActiveStatus:      1,
+        CreatedAt:         time.Now(),
+        CreatedBy:         createdBy,
+    }
+    consent.AppointmentId, _ = uuid.Parse(appointmentId)
+    if consent.Procedure == "" {
+        consent.Procedure = typeName
+    }
+    if consent.Procedure == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Procedure is required"})
+        return
+    }
+    if req.Estimate != nil {
+        consent.Estimate = *req.Estimate
+    }
+    consent.ModifiedAt = consent.CreatedAt
+    consent.ModifiedBy = createdBy
+
+    consent.Body = renderConsent(template.Body, map[string]string{
+        "owner_name":  ownerName,
+        "pet_name":    petName,
+        "species":     species,
+        "breed":       breed,
+        "doctor_name": doctorName,
+        "procedure":   consent.Procedure,
+        "date":        appointmentDatetime.In(utils.ClinicLocation()).Format("2 January 2006"),
+        "estimate":    formatAmount(consent.Estimate),
+    })
+    sum := sha256.Sum256([]byte(consent.Body))
+    consent.BodySha256 = hex.EncodeToString(sum[:])
+
+    query := `INSERT INTO "Consents"
+        (id, appointment_id, consent_template_id, procedure, estimate, body, body_sha256, status,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`
+
+    _, err = db.Exec(query,
+        consent.Id, consent.AppointmentId, consent.ConsentTemplateId, consent.Procedure, consent.Estimate,
+        consent.Body, consent.BodySha256, consent.Status,
+        consent.ActiveStatus, consent.CreatedAt, consent.CreatedBy, consent.ModifiedAt, consent.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting Consent:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create consent"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, consent)
+}
+
+func GetAppointmentConsents(c *gin.Context, db *sql.DB) {
+    rows, err := db.Query(`SELECT `+consentColumns+`
+                        FROM "Consents"
+                        WHERE appointment_id=$1 AND active_status=1
+                        ORDER BY created_at ASC`, c.Param("id"))
+    if err != nil {
+        log.Println("Error fetching consents:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch consents"})
+        return
+    }
+    defer rows.Close()
+
+    consents := []structs.Consent{}
+    for rows.Next() {
+        var consent structs.Consent
+        if err := scanConsent(rows, &consent); err != nil {
+            log.Println("Error scanning consent row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse consents"})
+            return
+        }
+        consents = append(consents, consent)
+    }
+
+    c.JSON(http.StatusOK, consents)
+}
+
+func GetConsent(c *gin.Context, db *sql.DB) {
+    var consent structs.Consent
+    err := scanConsent(db.QueryRow(`SELECT `+consentColumns+` FROM "Consents" WHERE id=$1 AND active_status=1`,
+        c.Param("id")), &consent)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Consent not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, consent)
+}
+
+// GetConsentSignature serves the captured signature image
+func GetConsentSignature(c *gin.Context, db *sql.DB) {
+    var image []byte
+    var mime string
+    err := db.QueryRow(`SELECT signature_image, signature_mime FROM "Consents"
+                        WHERE id=$1 AND active_status=1 AND signature_image IS NOT NULL`, c.Param("id")).Scan(&image, &mime)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Signature not found"})
+        return
+    }
+
+    c.Data(http.StatusOK, mime, image)
+}
+
+// SignConsent captures the drawn signature (a PNG or JPEG data URL) with the signer's name, the time and the IP
+func SignConsent(c *gin.Context, db *sql.DB) {
+    consentId := c.Param("id")
+    var req struct {
+        SignerName string `json:"signer_name"`
+        Signature  string `json:"signature"` // data:image/png;base64,...
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for SignConsent:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    req.SignerName = strings.TrimSpace(req.SignerName)
+    if req.SignerName == "" || req.Signature == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "SignerName and Signature are required"})
+        return
+    }
+
+    header, data, found := strings.Cut(req.Signature, ",")
+    if !found || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Signature must be a base64 data URL"})
+        return
+    }
+    if base64.StdEncoding.DecodedLen(len(data)) > maxSignatureBytes+3 {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Signature image is too large"})
+        return
+    }
+    image, err := base64.StdEncoding.DecodeString(data)
+    if err != nil || len(image) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Signature is not valid base64"})
+        return
+    }
+    // Trust the bytes, not the declared type
+    mime := http.DetectContentType(image)
+    if mime != "image/png" && mime != "image/jpeg" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Signature must be a PNG or JPEG image"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    now := time.Now()
+    var consent structs.Consent
+    err = scanConsent(db.QueryRow(`UPDATE "Consents"
+                    SET status='Signed', signer_name=$1, signature_image=$2, signature_mime=$3,
+                        signed_at=$4, signer_ip=$5, modified_at=$4, modified_by=$6
+                    WHERE id=$7 AND status='Pending' AND active_status=1
+                    RETURNING `+consentColumns,
+        req.SignerName, image, mime, now, c.ClientIP(), modifiedBy, consentId), &consent)
+    if err == sql.ErrNoRows {
+        var status string
+        if db.QueryRow(`SELECT status FROM "Consents" WHERE id=$1 AND active_status=1`, consentId).Scan(&status) != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Consent not found"})
+            return
+        }
+        c.JSON(http.StatusConflict, gin.H{"error": "Consent is " + status + ", only pending consents can be signed"})
+        return
+    }
+    if err != nil {
+        log.Println("Error signing Consent:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign consent"})
+        return
+    }
+
+    c.JSON(http.StatusOK, consent)
+}
+
+// RevokeConsent withdraws a pending or signed consent; the signature stays on record
+func RevokeConsent(c *gin.Context, db *sql.DB) {
+    consentId := c.Param("id")
+    var req struct {
+        Reason string `json:"reason"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for RevokeConsent:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    req.Reason = strings.TrimSpace(req.Reason)
+    if req.Reason == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "Consents"
+                    SET status='Revoked', revoked_at=$1, revoked_reason=$2, modified_at=$1, modified_by=$3
+                    WHERE id=$4 AND status IN ('Pending', 'Signed') AND active_status=1`,
+        time.Now(), req.Reason, modifiedBy, consentId)
+    if err != nil {
+        log.Println("Error revoking Consent:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke consent"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Consent not found or already revoked"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Consent revoked successfully"})
+}
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const diagnosisCodeColumns = `id, code, term, synonyms, species, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanDiagnosisCode(row interface{ Scan(...interface{}) error }, d *structs.DiagnosisCode) error {
+    return row.Scan(
+        &d.Id, &d.Code, &d.Term, pq.Array(&d.Synonyms), pq.Array(&d.Species),
+        &d.ActiveStatus, &d.CreatedAt, &d.CreatedBy, &d.ModifiedAt, &d.ModifiedBy,
+    )
+}
+
+// splitList splits a "|" separated CSV cell, dropping empty values
+func splitList(value string) []string {
+    list := []string{}
+    for _, v := range strings.Split(value, "|") {
+        if v = strings.TrimSpace(v); v != "" {
+            list = append(list, v)
+        }
+    }
+    return list
+}
+
+// cleanList trims values and drops empty ones (nil stays nil so partial updates can skip it)
+func cleanList(values []string) []string {
+    if values == nil {
+        return nil
+    }
+    return splitList(strings.Join(values, "|"))
+}
+
+func CreateDiagnosisCode(c *gin.Context, db *sql.DB) {
+    var code structs.DiagnosisCode
+    if err := c.ShouldBindJSON(&code); err != nil {
+        log.Println("Error binding JSON for new DiagnosisCode:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    code.Code = strings.TrimSpace(code.Code)
+    code.Term = strings.TrimSpace(code.Term)
+    if code.Code == "" || code.Term == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Code and Term are required"})
+        return
+    }
+    code.Synonyms = cleanList(code.Synonyms)
+    code.Species = cleanList(code.Species)
+    if code.Synonyms == nil {
+        code.Synonyms = []string{}
+    }
+    if code.Species == nil {
+        code.Species = []string{}
+    }
+
+    var taken bool
+    if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "DiagnosisCatalog" WHERE code=$1)`, code.Code).Scan(&taken); err != nil {
+        log.Println("Error checking DiagnosisCode:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create diagnosis code"})
+        return
+    }
+    if taken {
+        c.JSON(http.StatusConflict, gin.H{"error": "Code already exists"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    code.Id = uuid.New()
+    code.ActiveStatus = 1
+    code.CreatedAt = time.Now()
+    code.CreatedBy = createdBy
+    code.ModifiedAt = code.CreatedAt
+    code.ModifiedBy = createdBy
+
+    query := `INSERT INTO "DiagnosisCatalog" (` + diagnosisCodeColumns + `)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+
+    _, err := db.Exec(query,
+        code.Id, code.Code, code.Term, pq.Array(code.Synonyms), pq.Array(code.Species),
+        code.ActiveStatus, code.CreatedAt, code.CreatedBy, code.ModifiedAt, code.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting DiagnosisCode:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create diagnosis code"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, code)
+}
+
+// SearchDiagnosisCodes: GET /api/diagnoses?q=&species=&limit=
+// Autocomplete over code, term and synonyms; exact codes first, then term prefixes, then the rest.
+func SearchDiagnosisCodes(c *gin.Context, db *sql.DB) {
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+    if err != nil || limit < 1 || limit > 100 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+        return
+    }
+
+    // the search text is matched literally
+    raw := strings.TrimSpace(c.Query("q"))
+    q := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(raw)
+
+    query := `SELECT ` + diagnosisCodeColumns + `
+            FROM "DiagnosisCatalog"
+            WHERE active_status=1
+            AND ($1 = '' OR code ILIKE $1 || '%' OR term ILIKE '%' || $1 || '%'
+                OR EXISTS (SELECT 1 FROM unnest(synonyms) s WHERE s ILIKE '%' || $1 || '%'))
+            AND ($2 = '' OR cardinality(species) = 0 OR EXISTS (SELECT 1 FROM unnest(species) s WHERE lower(s) = lower($2)))
+            ORDER BY lower(code) = lower($4) DESC, term ILIKE $1 || '%' DESC, term ASC
+            LIMIT $3`
+
+    rows, err := db.Query(query, q, strings.TrimSpace(c.Query("species")), limit, raw)
+    if err != nil {
+        log.Println("Error searching diagnosis catalog:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search diagnosis codes"})
+        return
+    }
+    defer rows.Close()
+
+    codes := []structs.DiagnosisCode{}
+    for rows.Next() {
+        var d structs.DiagnosisCode
+        if err := scanDiagnosisCode(rows, &d); err != nil {
+            log.Println("Error scanning diagnosis code row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse diagnosis codes"})
+            return
+        }
+        codes = append(codes, d)
+    }
+
+    c.JSON(http.StatusOK, codes)
+}
+
+func UpdateDiagnosisCode(c *gin.Context, db *sql.DB) {
+    codeId := c.Param("id")
+
+    // 1. Fetch existing code
+    var existing structs.DiagnosisCode
+    err := scanDiagnosisCode(db.QueryRow(`SELECT `+diagnosisCodeColumns+` FROM "DiagnosisCatalog" WHERE id=$1 AND active_status=1`, codeId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Diagnosis code not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.DiagnosisCode
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateDiagnosisCode:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields (the code itself is the stable key and can't change)
+    if term := strings.TrimSpace(req.Term); term != "" {
+        existing.Term = term
+    }
+    if req.Synonyms != nil {
+        existing.Synonyms = cleanList(req.Synonyms)
+    }
+    if req.Species != nil {
+        existing.Species = cleanList(req.Species)
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "DiagnosisCatalog"
+                    SET term=$1, synonyms=$2, species=$3, modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.Term, pq.Array(existing.Synonyms), pq.Array(existing.Species), time.Now(), modifiedBy, codeId,
+    )
+    if err != nil {
+        log.Println("Error updating DiagnosisCode:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update diagnosis code"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Diagnosis code updated successfully"})
+}
+
+func UpdateDiagnosisCodeActiveStatus(c *gin.Context, db *sql.DB) {
+    codeId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    // Records keep their link, the code just can't be picked anymore
+    query := `UPDATE "DiagnosisCatalog"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, codeId)
+    if err != nil {
+        log.Println("Error soft deleting DiagnosisCode:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate diagnosis code"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             codeId,
+        "deactivated_by": modifiedBy,
+        "message":        "Diagnosis code deactivated successfully",
+    })
+}
+
+// ImportDiagnosisCatalog: POST /api/diagnoses/import (multipart "file")
+// CSV with the header code,term,synonyms,species; synonyms and species are "|" separated.
+// Existing codes are updated (and reactivated). Nothing is imported when a line is invalid.
+func ImportDiagnosisCatalog(c *gin.Context, db *sql.DB) {
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required in the file field"})
+        return
+    }
+    file, err := fileHeader.Open()
+    if err != nil {
+        log.Println("Error opening diagnosis catalog upload:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+    defer file.Close()
+
+    reader := csv.NewReader(file)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The file is empty or not valid CSV"})
+        return
+    }
+    columns := map[string]int{}
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+    }
+    if _, ok := columns["code"]; !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The header must contain code and term"})
+        return
+    }
+    if _, ok := columns["term"]; !ok {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The header must contain code and term"})
+        return
+    }
+    cell := func(row []string, name string) string {
+        i, ok := columns[name]
+        if !ok || i >= len(row) {
+            return ""
+        }
+        return strings.TrimSpace(row[i])
+    }
+
+    var entries []structs.DiagnosisCode
+    var lineErrors []string
+    seen := map[string]int{}
+    line := 1
+    for {
+        row, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        line++
+        if err != nil {
+            lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": "+err.Error())
+            continue
+        }
+
+        entry := structs.DiagnosisCode{
+            Code:     cell(row, "code"),
+            Term:     cell(row, "term"),
+            Synonyms: splitList(cell(row, "synonyms")),
+            Species:  splitList(cell(row, "species")),
+        }
+        if entry.Code == "" && entry.Term == "" {
+            continue
+        }
+        if entry.Code == "" || entry.Term == "" {
+            lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": code and term are required")
+            continue
+        }
+        if first, dup := seen[entry.Code]; dup {
+            lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": code "+entry.Code+" already on line "+strconv.Itoa(first))
+            continue
+        }
+        seen[entry.Code] = line
+        entries = append(entries, entry)
+    }
+    if len(lineErrors) > 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The file has invalid lines, nothing was imported", "lines": lineErrors})
+        return
+    }
+    if len(entries) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The file has no diagnosis codes"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for diagnosis catalog import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import diagnosis catalog"})
+        return
+    }
+    defer tx.Rollback()
+
+    now := time.Now()
+    created, updated := 0, 0
+    for _, entry := range entries {
+        var inserted bool
+        err := tx.QueryRow(`INSERT INTO "DiagnosisCatalog" (`+diagnosisCodeColumns+`)
+                        VALUES ($1,$2,$3,$4,$5,1,$6,$7,$6,$7)
+                        ON CONFLICT (code) DO UPDATE
+                        SET term=EXCLUDED.term, synonyms=EXCLUDED.synonyms, species=EXCLUDED.species,
+                            active_status=1, modified_at=EXCLUDED.modified_at, modified_by=EXCLUDED.modified_by
+                        RETURNING (xmax = 0)`,
+            uuid.New(), entry.Code, entry.Term, pq.Array(entry.Synonyms), pq.Array(entry.Species), now, createdBy,
+        ).Scan(&inserted)
+        if err != nil {
+            log.Println("Error importing DiagnosisCode:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import diagnosis catalog"})
+            return
+        }
+        if inserted {
+            created++
+        } else {
+            updated++
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing diagnosis catalog import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import diagnosis catalog"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "created": created,
+        "updated": updated,
+        "message": "Diagnosis catalog imported successfully",
+    })
+}
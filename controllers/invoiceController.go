@@ -0,0 +1,1187 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const invoiceColumns = `id, clinic_profile_id, COALESCE(invoice_number, ''), appointment_id, pet_id, owner_id,
+                bill_to_name, COALESCE(bill_to_phone, ''), status,
+                COALESCE(discount_type, ''), COALESCE(discount_value, 0), COALESCE(discount_reason, ''), COALESCE(discount_approved_by, ''),
+                discount_amount, subtotal, discount_total, tax_total, rounding_adjustment, total, COALESCE(notes, ''),
+                issued_at, COALESCE(issued_by, ''), voided_at, COALESCE(voided_by, ''), COALESCE(void_reason, ''),
+                (SELECT COALESCE(SUM(pa.amount), 0) FROM "PaymentAllocations" pa
+                    JOIN "Payments" py ON py.id = pa.payment_id AND py.status='Recorded' AND py.active_status=1
+                    WHERE pa.invoice_id = "Invoices".id AND pa.active_status=1),
+                active_status, created_at, created_by, modified_at, modified_by`
+
+func scanInvoice(row interface{ Scan(...interface{}) error }, i *structs.Invoice) error {
+    err := row.Scan(
+        &i.Id, &i.ClinicProfileId, &i.InvoiceNumber, &i.AppointmentId, &i.PetId, &i.OwnerId,
+        &i.BillToName, &i.BillToPhone, &i.Status,
+        &i.DiscountType, &i.DiscountValue, &i.DiscountReason, &i.DiscountApprovedBy,
+        &i.DiscountAmount, &i.Subtotal, &i.DiscountTotal, &i.TaxTotal, &i.RoundingAdjustment, &i.Total, &i.Notes,
+        &i.IssuedAt, &i.IssuedBy, &i.VoidedAt, &i.VoidedBy, &i.VoidReason, &i.Paid,
+        &i.ActiveStatus, &i.CreatedAt, &i.CreatedBy, &i.ModifiedAt, &i.ModifiedBy,
+    )
+    i.PaymentStatus = services.PaymentStatus(i.Status, i.Total, i.Paid)
+    if i.Status == "Issued" {
+        i.Balance = i.Total - i.Paid
+    }
+    return err
+}
+
+const invoiceLineColumns = `id, invoice_id, source, source_id, description, category, quantity, unit_price, amount,
+                COALESCE(discount_type, ''), COALESCE(discount_value, 0), COALESCE(discount_reason, ''), COALESCE(discount_approved_by, ''),
+                discount_amount, invoice_discount_share, tax_rate, tax_amount, total, sort_order,
+                active_status, created_at, created_by, modified_at, modified_by`
+
+func scanInvoiceLine(row interface{ Scan(...interface{}) error }, l *structs.InvoiceLine) error {
+    return row.Scan(
+        &l.Id, &l.InvoiceId, &l.Source, &l.SourceId, &l.Description, &l.Category, &l.Quantity, &l.UnitPrice, &l.Amount,
+        &l.DiscountType, &l.DiscountValue, &l.DiscountReason, &l.DiscountApprovedBy,
+        &l.DiscountAmount, &l.InvoiceDiscountShare, &l.TaxRate, &l.TaxAmount, &l.Total, &l.SortOrder,
+        &l.ActiveStatus, &l.CreatedAt, &l.CreatedBy, &l.ModifiedAt, &l.ModifiedBy,
+    )
+}
+
+// fetchInvoiceLines lists the active lines of an invoice, generated lines first, then the ones added by hand
+func fetchInvoiceLines(db *sql.DB, invoiceId uuid.UUID) ([]structs.InvoiceLine, error) {
+    rows, err := db.Query(`SELECT `+invoiceLineColumns+` FROM "InvoiceLines"
+                        WHERE invoice_id=$1 AND active_status=1
+                        ORDER BY source = 'Manual' ASC, sort_order ASC, created_at ASC`, invoiceId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    lines := []structs.InvoiceLine{}
+    for rows.Next() {
+        var l structs.InvoiceLine
+        if err := scanInvoiceLine(rows, &l); err != nil {
+            return nil, err
+        }
+        lines = append(lines, l)
+    }
+    return lines, rows.Err()
+}
+
+// fetchInvoice loads an active invoice with its lines
+func fetchInvoice(db *sql.DB, invoiceId string) (structs.Invoice, error) {
+    var invoice structs.Invoice
+    err := scanInvoice(db.QueryRow(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE id=$1 AND active_status=1`, invoiceId), &invoice)
+    if err != nil {
+        return invoice, err
+    }
+    invoice.Lines, err = fetchInvoiceLines(db, invoice.Id)
+    return invoice, err
+}
+
+// insertInvoiceLines stores generated lines numbered in the order given, their amounts are left to services.RecalculateInvoice
+func insertInvoiceLines(tx *sql.Tx, invoiceId uuid.UUID, lines []structs.InvoiceLine, createdBy string, now time.Time) error {
+    for i, line := range lines {
+        _, err := tx.Exec(`INSERT INTO "InvoiceLines"
+            (id, invoice_id, source, source_id, description, category, quantity, unit_price, amount,
+            discount_type, discount_value, discount_reason, discount_approved_by, sort_order,
+            active_status, created_at, created_by, modified_at, modified_by)
+            VALUES ($1,$2,$3,$4,$5,$6,$7,$8,0,NULLIF($9, ''),NULLIF($10::numeric, 0),NULLIF($11, ''),NULLIF($12, ''),$13,1,$14,$15,$14,$15)`,
+            uuid.New(), invoiceId, line.Source, line.SourceId, line.Description, line.Category, line.Quantity, line.UnitPrice,
+            line.DiscountType, line.DiscountValue, line.DiscountReason, line.DiscountApprovedBy, i,
+            now, createdBy,
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// draftInvoiceStatus answers 404/409 and returns false unless the invoice exists and is still a Draft
+func draftInvoiceStatus(c *gin.Context, db *sql.DB, invoiceId string) bool {
+    var status string
+    if err := db.QueryRow(`SELECT status FROM "Invoices" WHERE id=$1 AND active_status=1`, invoiceId).Scan(&status); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return false
+    }
+    if status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is " + status + ", its lines are frozen"})
+        return false
+    }
+    return true
+}
+
+// checkInvoiceLine returns an empty string when valid, otherwise the error message for the client
+func checkInvoiceLine(l structs.InvoiceLine) string {
+    if strings.TrimSpace(l.Description) == "" {
+        return "Description is required"
+    }
+    if l.Quantity < 1 {
+        return "Quantity must be at least 1"
+    }
+    if l.UnitPrice < 0 {
+        return "UnitPrice can't be negative"
+    }
+    return ""
+}
+
+// CreateInvoice makes a Draft invoice from an appointment: the service catalog price of its type and its active treatments
+func CreateInvoice(c *gin.Context, db *sql.DB) {
+    appointmentId, err := uuid.Parse(c.Param("appointment_id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid appointment id"})
+        return
+    }
+
+    var invoice structs.Invoice
+    var appointmentStatus string
+    err = db.QueryRow(`SELECT a.status, a.pet_id, p.owner_id,
+                            COALESCE(o.name, NULLIF(p.owner_name, ''), ''), COALESCE(o.phone, p.owner_phone, '')
+                        FROM "Appointments" a
+                        JOIN "Pets" p ON p.id = a.pet_id
+                        LEFT JOIN "Owners" o ON o.id = p.owner_id AND o.active_status=1
+                        WHERE a.id=$1 AND a.active_status=1`, appointmentId).Scan(
+        &appointmentStatus, &invoice.PetId, &invoice.OwnerId, &invoice.BillToName, &invoice.BillToPhone,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+    if appointmentStatus == "Cancelled" || appointmentStatus == "NoShow" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": appointmentStatus + " appointments can't be invoiced"})
+        return
+    }
+    profile, err := fetchClinicProfile(db)
+    if err != nil {
+        log.Println("Error fetching ClinicProfile for invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+    defer tx.Rollback()
+
+    lines, err := services.BillableLines(tx, appointmentId)
+    if err != nil {
+        log.Println("Error fetching billable lines:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+
+    now := time.Now()
+    invoice.Id = uuid.New()
+    _, err = tx.Exec(`INSERT INTO "Invoices"
+        (id, clinic_profile_id, appointment_id, pet_id, owner_id, bill_to_name, bill_to_phone, status,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,NULLIF($7, ''),'Draft',1,$8,$9,$8,$9)`,
+        invoice.Id, profile.Id, appointmentId, invoice.PetId, invoice.OwnerId, invoice.BillToName, invoice.BillToPhone,
+        now, createdBy,
+    )
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "invoices_appointment_id_open_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment already has an invoice, void it to make a new one"})
+        return
+    }
+    if err != nil {
+        log.Println("Error inserting new Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+    if err := insertInvoiceLines(tx, invoice.Id, lines, createdBy, now); err != nil {
+        log.Println("Error inserting InvoiceLines:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+    if err := services.RecalculateInvoice(tx, invoice.Id); err != nil {
+        log.Println("Error calculating Invoice totals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invoice"})
+        return
+    }
+
+    created, err := fetchInvoice(db, invoice.Id.String())
+    if err != nil {
+        log.Println("Error fetching new Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoice"})
+        return
+    }
+    c.JSON(http.StatusCreated, created)
+}
+
+func GetInvoice(c *gin.Context, db *sql.DB) {
+    invoice, err := fetchInvoice(db, c.Param("id"))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if err != nil {
+        log.Println("Error fetching invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoice"})
+        return
+    }
+
+    c.JSON(http.StatusOK, invoice)
+}
+
+// listInvoices answers with the active invoices matching where, newest first (without lines)
+func listInvoices(c *gin.Context, db *sql.DB, where string, args ...interface{}) {
+    rows, err := db.Query(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE active_status=1 AND `+where+`
+                    ORDER BY COALESCE(issued_at, created_at) DESC`, args...)
+    if err != nil {
+        log.Println("Error fetching invoices:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+        return
+    }
+    defer rows.Close()
+
+    invoices := []structs.Invoice{}
+    for rows.Next() {
+        var i structs.Invoice
+        if err := scanInvoice(rows, &i); err != nil {
+            log.Println("Error scanning invoice row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse invoices"})
+            return
+        }
+        invoices = append(invoices, i)
+    }
+
+    c.JSON(http.StatusOK, invoices)
+}
+
+// GetInvoices: GET /api/invoices?status=&from=&to= (issue date, or creation date for drafts)
+func GetInvoices(c *gin.Context, db *sql.DB) {
+    where := "($1='' OR status=$1)"
+    args := []interface{}{c.Query("status")}
+    if raw := c.Query("from"); raw != "" {
+        from, _, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        args = append(args, from)
+        where += " AND COALESCE(issued_at, created_at) >= $" + strconv.Itoa(len(args))
+    }
+    if raw := c.Query("to"); raw != "" {
+        to, dateOnly, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        if dateOnly {
+            to = to.AddDate(0, 0, 1)
+        }
+        args = append(args, to)
+        where += " AND COALESCE(issued_at, created_at) < $" + strconv.Itoa(len(args))
+    }
+    listInvoices(c, db, where, args...)
+}
+
+func GetInvoicesByAppointment(c *gin.Context, db *sql.DB) {
+    listInvoices(c, db, "appointment_id=$1", c.Param("appointment_id"))
+}
+
+// ownerInvoicesWhere matches invoices billed to the owner, and those of pets linked to the owner after the invoice was made
+const ownerInvoicesWhere = `(owner_id=$1 OR pet_id IN (SELECT id FROM "Pets" WHERE owner_id=$1))`
+
+// GetInvoicesByOwner lists an owner's invoices, optionally filtered by ?status=
+func GetInvoicesByOwner(c *gin.Context, db *sql.DB) {
+    ownerId, err := uuid.Parse(c.Param("owner_id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner id"})
+        return
+    }
+    listInvoices(c, db, ownerInvoicesWhere+" AND ($2='' OR status=$2)", ownerId, c.Query("status"))
+}
+
+// GetMyInvoices lists the logged in owner's issued and void invoices, drafts are internal
+func GetMyInvoices(c *gin.Context, db *sql.DB) {
+    listInvoices(c, db, ownerInvoicesWhere+" AND status <> 'Draft'", c.GetString("user_id"))
+}
+
+// UpdateInvoice edits the bill-to details and notes of a Draft
+func UpdateInvoice(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    // 1. Fetch existing invoice
+    existing, err := fetchInvoice(db, invoiceId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if existing.Status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only Draft invoices can be edited"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.Invoice
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateInvoice:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if name := strings.TrimSpace(req.BillToName); name != "" {
+        existing.BillToName = name
+    }
+    if req.BillToPhone != "" {
+        existing.BillToPhone = strings.TrimSpace(req.BillToPhone)
+    }
+    if req.Notes != "" {
+        existing.Notes = req.Notes
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "Invoices"
+                    SET bill_to_name=$1, bill_to_phone=NULLIF($2, ''), notes=NULLIF($3, ''), modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND status='Draft' AND active_status=1`
+
+    result, err := db.Exec(updateQuery,
+        existing.BillToName, existing.BillToPhone, existing.Notes, time.Now(), modifiedBy, existing.Id,
+    )
+    if err != nil {
+        log.Println("Error updating Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only Draft invoices can be edited"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Invoice updated successfully"})
+}
+
+// RefreshInvoice copies the appointment type price and treatments again into a Draft,
+// replacing the generated lines; lines added by hand are kept
+func RefreshInvoice(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    var invoice structs.Invoice
+    err := scanInvoice(db.QueryRow(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE id=$1 AND active_status=1`, invoiceId), &invoice)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for invoice refresh:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh invoice"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Lock the invoice so it can't be issued halfway through
+    var status string
+    if err := tx.QueryRow(`SELECT status FROM "Invoices" WHERE id=$1 FOR UPDATE`, invoice.Id).Scan(&status); err != nil || status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only Draft invoices can be refreshed"})
+        return
+    }
+    lines, err := services.BillableLines(tx, invoice.AppointmentId)
+    if err == nil {
+        err = keepLineDiscounts(tx, invoice.Id, lines)
+    }
+    if err != nil {
+        log.Println("Error fetching billable lines:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh invoice"})
+        return
+    }
+    now := time.Now()
+    _, err = tx.Exec(`UPDATE "InvoiceLines" SET active_status=0, modified_at=$1, modified_by=$2
+                    WHERE invoice_id=$3 AND source <> 'Manual' AND active_status=1`, now, modifiedBy, invoice.Id)
+    if err == nil {
+        err = insertInvoiceLines(tx, invoice.Id, lines, modifiedBy, now)
+    }
+    if err == nil {
+        err = services.RecalculateInvoice(tx, invoice.Id)
+    }
+    if err == nil {
+        _, err = tx.Exec(`UPDATE "Invoices" SET modified_at=$1, modified_by=$2 WHERE id=$3`, now, modifiedBy, invoice.Id)
+    }
+    if err != nil {
+        log.Println("Error refreshing Invoice lines:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh invoice"})
+        return
+    }
+    // kept line discounts can weigh more when treatments got cheaper or were removed
+    if !discountWithinLimit(c, tx, invoice.Id) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing Invoice refresh:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh invoice"})
+        return
+    }
+
+    refreshed, err := fetchInvoice(db, invoiceId)
+    if err != nil {
+        log.Println("Error fetching refreshed Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoice"})
+        return
+    }
+    c.JSON(http.StatusOK, refreshed)
+}
+
+// keepLineDiscounts copies the discounts of the generated lines about to be replaced to the new lines of the same source
+func keepLineDiscounts(tx *sql.Tx, invoiceId uuid.UUID, lines []structs.InvoiceLine) error {
+    rows, err := tx.Query(`SELECT source, source_id, discount_type, discount_value, COALESCE(discount_reason, ''), COALESCE(discount_approved_by, '')
+                    FROM "InvoiceLines"
+                    WHERE invoice_id=$1 AND source <> 'Manual' AND discount_type IS NOT NULL AND active_status=1`, invoiceId)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    discounts := map[string]structs.InvoiceLine{}
+    for rows.Next() {
+        var d structs.InvoiceLine
+        var sourceId uuid.UUID
+        if err := rows.Scan(&d.Source, &sourceId, &d.DiscountType, &d.DiscountValue, &d.DiscountReason, &d.DiscountApprovedBy); err != nil {
+            return err
+        }
+        discounts[d.Source+sourceId.String()] = d
+    }
+    for i := range lines {
+        if d, ok := discounts[lines[i].Source+lines[i].SourceId.String()]; ok {
+            lines[i].DiscountType, lines[i].DiscountValue = d.DiscountType, d.DiscountValue
+            lines[i].DiscountReason, lines[i].DiscountApprovedBy = d.DiscountReason, d.DiscountApprovedBy
+        }
+    }
+    return rows.Err()
+}
+
+// AddInvoiceLine adds a line by hand to a Draft: {description, category, quantity, unit_price}
+func AddInvoiceLine(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    var line structs.InvoiceLine
+    if err := c.ShouldBindJSON(&line); err != nil {
+        log.Println("Error binding JSON for new InvoiceLine:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if line.Quantity == 0 {
+        line.Quantity = 1
+    }
+    line.Description = strings.TrimSpace(line.Description)
+    line.Category = strings.TrimSpace(line.Category)
+    if line.Category == "" {
+        line.Category = "Other"
+    }
+    if msg := checkInvoiceLine(line); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if msg := checkBillingCategory(db, line.Category); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if !draftInvoiceStatus(c, db, invoiceId) {
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    line.Id = uuid.New()
+    line.InvoiceId, _ = uuid.Parse(invoiceId)
+    line.Source = "Manual"
+    line.SourceId = nil
+    line.ActiveStatus = 1
+    line.CreatedAt = time.Now()
+    line.CreatedBy = createdBy
+    line.ModifiedAt = line.CreatedAt
+    line.ModifiedBy = createdBy
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new InvoiceLine:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add invoice line"})
+        return
+    }
+    defer tx.Rollback()
+
+    // The status is checked again under the row lock, an invoice issued in the meantime stays frozen
+    var status string
+    if err := tx.QueryRow(`SELECT status FROM "Invoices" WHERE id=$1 FOR UPDATE`, line.InvoiceId).Scan(&status); err != nil || status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is no longer a Draft, its lines are frozen"})
+        return
+    }
+    _, err = tx.Exec(`INSERT INTO "InvoiceLines"
+        (id, invoice_id, source, source_id, description, category, quantity, unit_price, amount, sort_order,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,NULL,$4,$5,$6,$7,0,
+            (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM "InvoiceLines" WHERE invoice_id=$2 AND source='Manual'),
+            $8,$9,$10,$11,$12)`,
+        line.Id, line.InvoiceId, line.Source, line.Description, line.Category, line.Quantity, line.UnitPrice,
+        line.ActiveStatus, line.CreatedAt, line.CreatedBy, line.ModifiedAt, line.ModifiedBy,
+    )
+    if err == nil {
+        err = services.RecalculateInvoice(tx, line.InvoiceId)
+    }
+    if err != nil {
+        log.Println("Error inserting InvoiceLine:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add invoice line"})
+        return
+    }
+    if !discountWithinLimit(c, tx, line.InvoiceId) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new InvoiceLine:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add invoice line"})
+        return
+    }
+
+    // Reread the line for the amounts worked out with the rest of the invoice
+    if err := scanInvoiceLine(db.QueryRow(`SELECT `+invoiceLineColumns+` FROM "InvoiceLines" WHERE id=$1`, line.Id), &line); err != nil {
+        log.Println("Error fetching new InvoiceLine:", err)
+    }
+    c.JSON(http.StatusCreated, line)
+}
+
+// UpdateInvoiceLine changes the description, category, quantity or unit price of a line on a Draft.
+// Generated lines are replaced by the next refresh.
+func UpdateInvoiceLine(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+    lineId := c.Param("line_id")
+
+    if !draftInvoiceStatus(c, db, invoiceId) {
+        return
+    }
+
+    // 1. Fetch existing line
+    var existing structs.InvoiceLine
+    err := scanInvoiceLine(db.QueryRow(`SELECT `+invoiceLineColumns+` FROM "InvoiceLines"
+                    WHERE id=$1 AND invoice_id=$2 AND active_status=1`, lineId, invoiceId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice line not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON (a pointer for the price, so a line can be made free)
+    var req struct {
+        structs.InvoiceLine
+        UnitPrice *int `json:"unit_price"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateInvoiceLine:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if desc := strings.TrimSpace(req.Description); desc != "" {
+        existing.Description = desc
+    }
+    if req.Quantity != 0 {
+        existing.Quantity = req.Quantity
+    }
+    if req.UnitPrice != nil {
+        existing.UnitPrice = *req.UnitPrice
+    }
+    if category := strings.TrimSpace(req.Category); category != "" {
+        if msg := checkBillingCategory(db, category); msg != "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+            return
+        }
+        existing.Category = category
+    }
+    if msg := checkInvoiceLine(existing); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateInvoiceLine:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice line"})
+        return
+    }
+    defer tx.Rollback()
+
+    var status string
+    if err := tx.QueryRow(`SELECT status FROM "Invoices" WHERE id=$1 FOR UPDATE`, existing.InvoiceId).Scan(&status); err != nil || status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is no longer a Draft, its lines are frozen"})
+        return
+    }
+    _, err = tx.Exec(`UPDATE "InvoiceLines"
+                    SET description=$1, category=$2, quantity=$3, unit_price=$4, modified_at=$5, modified_by=$6
+                    WHERE id=$7 AND active_status=1`,
+        existing.Description, existing.Category, existing.Quantity, existing.UnitPrice, time.Now(), modifiedBy, existing.Id,
+    )
+    if err == nil {
+        err = services.RecalculateInvoice(tx, existing.InvoiceId)
+    }
+    if err != nil {
+        log.Println("Error updating InvoiceLine:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice line"})
+        return
+    }
+    if !discountWithinLimit(c, tx, existing.InvoiceId) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing InvoiceLine update:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice line"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Invoice line updated successfully"})
+}
+
+// UpdateInvoiceLineActiveStatus removes (or restores) a line of a Draft
+func UpdateInvoiceLineActiveStatus(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+    lineId := c.Param("line_id")
+
+    var req struct {
+        ActiveStatus int `json:"active_status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateInvoiceLineActiveStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateInvoiceLineActiveStatus:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    defer tx.Rollback()
+
+    var status string
+    if err := tx.QueryRow(`SELECT status FROM "Invoices" WHERE id=$1 AND active_status=1 FOR UPDATE`, invoiceId).Scan(&status); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is " + status + ", its lines are frozen"})
+        return
+    }
+    result, err := tx.Exec(`UPDATE "InvoiceLines" SET active_status=$1, modified_at=$2, modified_by=$3
+                    WHERE id=$4 AND invoice_id=$5`, req.ActiveStatus, time.Now(), modifiedBy, lineId, invoiceId)
+    if err != nil {
+        log.Println("Error updating InvoiceLine active_status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice line not found"})
+        return
+    }
+    invoiceUUID, _ := uuid.Parse(invoiceId)
+    if err := services.RecalculateInvoice(tx, invoiceUUID); err != nil {
+        log.Println("Error recalculating Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    // a Fixed discount is capped at what is left, removing lines can push it up to 100%
+    if !discountWithinLimit(c, tx, invoiceUUID) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing InvoiceLine active_status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Active status updated successfully"})
+}
+
+// discountRequest is the body of the discount endpoints of invoices and their lines
+type discountRequest struct {
+    Type   string  `json:"type"` // Percent, Fixed
+    Value  float64 `json:"value"` // percent with up to two decimals, or a whole amount
+    Reason string  `json:"reason"`
+}
+
+// checkDiscount returns an empty string when valid, otherwise the error message for the client
+func checkDiscount(d discountRequest) string {
+    switch d.Type {
+    case services.DiscountPercent:
+        if d.Value <= 0 || d.Value > 100 {
+            return "A Percent discount must be more than 0 and at most 100"
+        }
+    case services.DiscountFixed:
+        if d.Value <= 0 || d.Value != math.Trunc(d.Value) {
+            return "A Fixed discount must be a whole amount more than 0"
+        }
+    default:
+        return "Type must be Percent or Fixed"
+    }
+    if strings.TrimSpace(d.Reason) == "" {
+        return "Reason is required"
+    }
+    return ""
+}
+
+// discountFits answers 422 and returns false when a Fixed discount is more than the base it comes off
+func discountFits(c *gin.Context, base int, d discountRequest) bool {
+    if d.Type == services.DiscountFixed && int(d.Value) > base {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Discount is more than the amount it comes off (" + formatAmount(base) + ")"})
+        return false
+    }
+    return true
+}
+
+// discountWithinLimit answers 403 and returns false when the user isn't Admin and the invoice ends up with
+// line and invoice discounts together over the staff_discount_limit percent of its subtotal. Call it after
+// services.RecalculateInvoice, before commit, whenever lines or discounts change: several small discounts add up,
+// and removing lines or lowering prices raises the share of discounts that were within the limit when given.
+func discountWithinLimit(c *gin.Context, tx *sql.Tx, invoiceId uuid.UUID) bool {
+    if role, _ := c.Get("role"); role == "Admin" {
+        return true
+    }
+
+    var subtotal, discountTotal int
+    var limit float64
+    err := tx.QueryRow(`SELECT i.subtotal, i.discount_total, cp.staff_discount_limit
+                    FROM "Invoices" i
+                    JOIN "ClinicProfile" cp ON cp.id = i.clinic_profile_id
+                    WHERE i.id=$1`, invoiceId).Scan(&subtotal, &discountTotal, &limit)
+    if err != nil {
+        log.Println("Error fetching staff discount limit:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the discount limit"})
+        return false
+    }
+    if discountTotal > 0 && float64(discountTotal)*100 > float64(subtotal)*limit {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Discounts over " + strconv.FormatFloat(limit, 'f', -1, 64) +
+            "% of the invoice need Admin approval (this change would make them " + formatAmount(discountTotal) +
+            " of " + formatAmount(subtotal) + ")"})
+        return false
+    }
+    return true
+}
+
+// applyDiscount sets (d not nil) or removes the discount of a Draft invoice, or of one of its lines when lineId
+// isn't empty, and answers with the invoice and its new totals. The user applying it is recorded as the approver.
+func applyDiscount(c *gin.Context, db *sql.DB, lineId string, d *discountRequest) {
+    invoiceId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    approvedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for discount:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply discount"})
+        return
+    }
+    defer tx.Rollback()
+
+    var invoiceUUID uuid.UUID
+    var status string
+    err = tx.QueryRow(`SELECT id, status FROM "Invoices" WHERE id=$1 AND active_status=1 FOR UPDATE`, invoiceId).Scan(
+        &invoiceUUID, &status,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is " + status + ", discounts can only change on a Draft"})
+        return
+    }
+
+    // A line discount comes off the line amount, the invoice discount off the lines after their own discounts
+    var base int
+    if lineId != "" {
+        err = tx.QueryRow(`SELECT quantity * unit_price FROM "InvoiceLines"
+                        WHERE id=$1 AND invoice_id=$2 AND active_status=1`, lineId, invoiceUUID).Scan(&base)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Invoice line not found"})
+            return
+        }
+    } else if err = tx.QueryRow(`SELECT COALESCE(SUM(amount - discount_amount), 0) FROM "InvoiceLines"
+                        WHERE invoice_id=$1 AND active_status=1`, invoiceUUID).Scan(&base); err != nil {
+        log.Println("Error fetching Invoice amount for discount:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply discount"})
+        return
+    }
+
+    var discountType, reason, approver string
+    var value float64
+    if d != nil {
+        if !discountFits(c, base, *d) {
+            return
+        }
+        discountType, value, reason, approver = d.Type, d.Value, strings.TrimSpace(d.Reason), approvedBy
+    }
+
+    now := time.Now()
+    if lineId != "" {
+        _, err = tx.Exec(`UPDATE "InvoiceLines"
+                    SET discount_type=NULLIF($1, ''), discount_value=NULLIF($2::numeric, 0), discount_reason=NULLIF($3, ''),
+                        discount_approved_by=NULLIF($4, ''), modified_at=$5, modified_by=$6
+                    WHERE id=$7`, discountType, value, reason, approver, now, approvedBy, lineId)
+    } else {
+        _, err = tx.Exec(`UPDATE "Invoices"
+                    SET discount_type=NULLIF($1, ''), discount_value=NULLIF($2::numeric, 0), discount_reason=NULLIF($3, ''),
+                        discount_approved_by=NULLIF($4, ''), modified_at=$5, modified_by=$6
+                    WHERE id=$7`, discountType, value, reason, approver, now, approvedBy, invoiceUUID)
+    }
+    if err == nil {
+        err = services.RecalculateInvoice(tx, invoiceUUID)
+    }
+    if err != nil {
+        log.Println("Error applying discount:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply discount"})
+        return
+    }
+    if d != nil && !discountWithinLimit(c, tx, invoiceUUID) {
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing discount:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply discount"})
+        return
+    }
+
+    invoice, err := fetchInvoice(db, invoiceId)
+    if err != nil {
+        log.Println("Error fetching discounted Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoice"})
+        return
+    }
+    c.JSON(http.StatusOK, invoice)
+}
+
+// bindDiscount reads and checks a discount body, answering 400 when it isn't valid
+func bindDiscount(c *gin.Context) (*discountRequest, bool) {
+    var req discountRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for discount:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return nil, false
+    }
+    if msg := checkDiscount(req); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return nil, false
+    }
+    return &req, true
+}
+
+// SetInvoiceDiscount gives a discount on the whole of a Draft: {type, value, reason}
+func SetInvoiceDiscount(c *gin.Context, db *sql.DB) {
+    if req, ok := bindDiscount(c); ok {
+        applyDiscount(c, db, "", req)
+    }
+}
+
+func RemoveInvoiceDiscount(c *gin.Context, db *sql.DB) {
+    applyDiscount(c, db, "", nil)
+}
+
+// SetInvoiceLineDiscount gives a discount on one line of a Draft: {type, value, reason}
+func SetInvoiceLineDiscount(c *gin.Context, db *sql.DB) {
+    if req, ok := bindDiscount(c); ok {
+        applyDiscount(c, db, c.Param("line_id"), req)
+    }
+}
+
+func RemoveInvoiceLineDiscount(c *gin.Context, db *sql.DB) {
+    applyDiscount(c, db, c.Param("line_id"), nil)
+}
+
+// IssueInvoice gives a Draft the next invoice number of the clinic; its lines can't change afterwards
+func IssueInvoice(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    issuedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for IssueInvoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+        return
+    }
+    defer tx.Rollback()
+
+    var invoice structs.Invoice
+    err = scanInvoice(tx.QueryRow(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE id=$1 AND active_status=1 FOR UPDATE`, invoiceId), &invoice)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if invoice.Status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is already " + invoice.Status})
+        return
+    }
+    var lineCount int
+    if err := tx.QueryRow(`SELECT COUNT(*) FROM "InvoiceLines" WHERE invoice_id=$1 AND active_status=1`, invoice.Id).Scan(&lineCount); err != nil {
+        log.Println("Error counting InvoiceLines:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+        return
+    }
+    if lineCount == 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invoice has no lines"})
+        return
+    }
+
+    number, err := services.NextInvoiceNumber(tx, invoice.ClinicProfileId)
+    if err != nil {
+        log.Println("Error taking invoice number:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+        return
+    }
+    now := time.Now()
+    err = services.RecalculateInvoice(tx, invoice.Id)
+    if err == nil {
+        _, err = tx.Exec(`UPDATE "Invoices"
+                    SET status='Issued', invoice_number=$1, issued_at=$2, issued_by=$3, modified_at=$2, modified_by=$3
+                    WHERE id=$4`, number, now, issuedBy, invoice.Id)
+    }
+    if err == nil {
+        err = tx.Commit()
+    }
+    if err != nil {
+        log.Println("Error issuing Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Invoice issued successfully", "invoice_number": number})
+}
+
+// VoidInvoice cancels a Draft or an issued invoice, which keeps its number. Voiding an issued invoice is for Admin.
+func VoidInvoice(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    var req struct {
+        Reason string `json:"reason"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for VoidInvoice:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    req.Reason = strings.TrimSpace(req.Reason)
+    if req.Reason == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    voidedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for VoidInvoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void invoice"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Payments lock the invoice too, so none can be added between the check and the update
+    var invoiceUUID uuid.UUID
+    var status string
+    err = tx.QueryRow(`SELECT id, status FROM "Invoices" WHERE id=$1 AND active_status=1 FOR UPDATE`, invoiceId).Scan(&invoiceUUID, &status)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if status == "Void" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice is already Void"})
+        return
+    }
+    if role, _ := c.Get("role"); status == "Issued" && role != "Admin" {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only Admin can void an issued invoice"})
+        return
+    }
+    if paid, err := services.InvoicePaid(tx, invoiceUUID); err != nil {
+        log.Println("Error fetching Invoice payments:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void invoice"})
+        return
+    } else if paid > 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Invoice has payments, void them first"})
+        return
+    }
+
+    now := time.Now()
+    _, err = tx.Exec(`UPDATE "Invoices"
+                    SET status='Void', voided_at=$1, voided_by=$2, void_reason=$3, modified_at=$1, modified_by=$2
+                    WHERE id=$4`, now, voidedBy, req.Reason, invoiceUUID)
+    if err == nil {
+        err = tx.Commit()
+    }
+    if err != nil {
+        log.Println("Error voiding Invoice:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void invoice"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Invoice voided successfully"})
+}
+
+// UpdateInvoiceActiveStatus soft deletes a Draft that never got a number; numbered invoices (Issued or Void)
+// stay on record so the number sequence has no gaps
+func UpdateInvoiceActiveStatus(c *gin.Context, db *sql.DB) {
+    invoiceId := c.Param("id")
+
+    var req struct {
+        ActiveStatus int `json:"active_status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateInvoiceActiveStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    var numbered bool
+    if err := db.QueryRow(`SELECT invoice_number IS NOT NULL FROM "Invoices" WHERE id=$1`, invoiceId).Scan(&numbered); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+        return
+    }
+    if numbered {
+        c.JSON(http.StatusConflict, gin.H{"error": "Numbered invoices can't be deleted, void them instead"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "Invoices" SET active_status=$1, modified_at=$2, modified_by=$3
+                    WHERE id=$4 AND invoice_number IS NULL`, req.ActiveStatus, time.Now(), modifiedBy, invoiceId)
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "invoices_appointment_id_open_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment already has another invoice"})
+        return
+    }
+    if err != nil {
+        log.Println("Error updating Invoice active_status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    // issued meanwhile
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Numbered invoices can't be deleted, void them instead"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Active status updated successfully"})
+}
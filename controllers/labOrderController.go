@@ -0,0 +1,763 @@
+package controllers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const labOrderColumns = `id, accession_number, appointment_id, medicalrecord_id, pet_id, doctor_id, panel, sample_type, status,
+                COALESCE(notes, ''), ordered_at, collected_at, resulted_at, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanLabOrder(row interface{ Scan(...interface{}) error }, o *structs.LabOrder) error {
+    return row.Scan(
+        &o.Id, &o.AccessionNumber, &o.AppointmentId, &o.MedicalRecordId, &o.PetId, &o.DoctorId, &o.Panel, &o.SampleType, &o.Status,
+        &o.Notes, &o.OrderedAt, &o.CollectedAt, &o.ResultedAt, &o.ActiveStatus, &o.CreatedAt, &o.CreatedBy, &o.ModifiedAt, &o.ModifiedBy,
+    )
+}
+
+// openLabStatuses can still receive results from an import
+const openLabStatuses = `('Ordered', 'Collected', 'InProgress')`
+
+// labStatusTransitions lists the statuses that can be set by hand from each status (Resulted comes with the results)
+var labStatusTransitions = map[string][]string{
+    "Ordered":    {"Collected", "Cancelled"},
+    "Collected":  {"InProgress", "Cancelled"},
+    "InProgress": {"Cancelled"},
+}
+
+func fetchLabResults(db *sql.DB, orderId uuid.UUID) ([]structs.LabResult, error) {
+    rows, err := db.Query(`SELECT id, lab_order_id, analyte_code, analyte, value, numeric_value, COALESCE(unit, ''),
+                            reference_low, reference_high, COALESCE(flag, ''), source, observed_at,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "LabResults"
+                        WHERE lab_order_id=$1 AND active_status=1
+                        ORDER BY created_at ASC, analyte ASC`, orderId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    results := []structs.LabResult{}
+    for rows.Next() {
+        var r structs.LabResult
+        if err := rows.Scan(
+            &r.Id, &r.LabOrderId, &r.AnalyteCode, &r.Analyte, &r.Value, &r.NumericValue, &r.Unit,
+            &r.ReferenceLow, &r.ReferenceHigh, &r.Flag, &r.Source, &r.ObservedAt,
+            &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+        ); err != nil {
+            return nil, err
+        }
+        results = append(results, r)
+    }
+    return results, nil
+}
+
+// checkLabResult fills the analyte code/name from each other and the numeric value.
+// Returns an empty string when valid, otherwise the error message for the client.
+func checkLabResult(r *structs.LabResult) string {
+    r.AnalyteCode = strings.TrimSpace(r.AnalyteCode)
+    r.Analyte = strings.TrimSpace(r.Analyte)
+    r.Value = strings.TrimSpace(r.Value)
+    if r.AnalyteCode == "" {
+        r.AnalyteCode = r.Analyte
+    }
+    if r.Analyte == "" {
+        r.Analyte = r.AnalyteCode
+    }
+    if r.AnalyteCode == "" || r.Value == "" {
+        return "Every result needs an analyte and a value"
+    }
+    // an outside lab's flag is kept when we have no reference range for the analyte
+    if r.Flag != "" && r.Flag != "Normal" && r.Flag != "Low" && r.Flag != "High" && r.Flag != "Critical" {
+        return "Flag must be Normal, Low, High or Critical"
+    }
+    r.NumericValue = nil
+    if v, err := strconv.ParseFloat(r.Value, 64); err == nil {
+        r.NumericValue = &v
+    }
+    return ""
+}
+
+// storeLabResults writes the results of an order, flags them against the reference ranges and marks the order Resulted.
+// A new value for an analyte replaces the previous one (analyzers resend corrected results).
+// Critical values are sent to the ordering doctor through the notification outbox.
+func storeLabResults(tx *sql.Tx, orderId uuid.UUID, results []structs.LabResult, source string, createdBy string, now time.Time) error {
+    var critical []structs.LabResult
+    for i := range results {
+        r := &results[i]
+        r.Id = uuid.New()
+        r.LabOrderId = orderId
+        r.Source = source
+        if r.ObservedAt == nil {
+            r.ObservedAt = &now
+        }
+        if err := flagLabResult(tx, orderId, r); err != nil {
+            return err
+        }
+        if r.Flag == "Critical" {
+            critical = append(critical, *r)
+        }
+        r.ActiveStatus = 1
+        r.CreatedAt = now
+        r.CreatedBy = createdBy
+        r.ModifiedAt = now
+        r.ModifiedBy = createdBy
+
+        _, err := tx.Exec(`UPDATE "LabResults" SET active_status=0, modified_at=$1, modified_by=$2
+                        WHERE lab_order_id=$3 AND analyte_code=$4 AND active_status=1`, now, createdBy, orderId, r.AnalyteCode)
+        if err != nil {
+            return err
+        }
+        _, err = tx.Exec(`INSERT INTO "LabResults"
+                        (id, lab_order_id, analyte_code, analyte, value, numeric_value, unit,
+                        reference_low, reference_high, flag, source, observed_at,
+                        active_status, created_at, created_by, modified_at, modified_by)
+                        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NULLIF($10, ''),$11,$12,$13,$14,$15,$16,$17)`,
+            r.Id, r.LabOrderId, r.AnalyteCode, r.Analyte, r.Value, r.NumericValue, r.Unit,
+            r.ReferenceLow, r.ReferenceHigh, r.Flag, r.Source, r.ObservedAt,
+            r.ActiveStatus, r.CreatedAt, r.CreatedBy, r.ModifiedAt, r.ModifiedBy,
+        )
+        if err != nil {
+            return err
+        }
+    }
+
+    // orders placed before the record was written get linked to it now
+    _, err := tx.Exec(`UPDATE "LabOrders" o
+                    SET status='Resulted', resulted_at=$1, modified_at=$1, modified_by=$2,
+                        medicalrecord_id = COALESCE(o.medicalrecord_id,
+                            (SELECT m.id FROM "MedicalRecords" m WHERE m.appointment_id = o.appointment_id AND m.active_status=1 LIMIT 1))
+                    WHERE o.id=$3`, now, createdBy, orderId)
+    if err != nil {
+        return err
+    }
+
+    if len(critical) > 0 {
+        return notifyCriticalLabResults(tx, orderId, critical, now)
+    }
+    return nil
+}
+
+func CreateLabOrder(c *gin.Context, db *sql.DB) {
+    var order structs.LabOrder
+    if err := c.ShouldBindJSON(&order); err != nil {
+        log.Println("Error binding JSON for new LabOrder:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    order.Panel = strings.TrimSpace(order.Panel)
+    order.SampleType = strings.TrimSpace(order.SampleType)
+    if order.AppointmentId == uuid.Nil || order.Panel == "" || order.SampleType == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "AppointmentId, Panel and SampleType are required"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    var appointmentDoctor *uuid.UUID
+    err := db.QueryRow(`SELECT a.pet_id, a.doctor_id,
+                            (SELECT m.id FROM "MedicalRecords" m WHERE m.appointment_id = a.id AND m.active_status=1 LIMIT 1)
+                        FROM "Appointments" a
+                        WHERE a.id=$1 AND a.active_status=1`, order.AppointmentId).Scan(&order.PetId, &appointmentDoctor, &order.MedicalRecordId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+
+    // Doctors order in their own name, Admin names the doctor (default: the appointment's doctor)
+    if role, _ := c.Get("role"); role == "Doctor" {
+        order.DoctorId, _ = uuid.Parse(createdBy)
+    } else if order.DoctorId == uuid.Nil && appointmentDoctor != nil {
+        order.DoctorId = *appointmentDoctor
+    }
+    var doctorRole string
+    err = db.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, order.DoctorId).Scan(&doctorRole)
+    if err != nil || doctorRole != "Doctor" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The ordering doctor must be an active Doctor"})
+        return
+    }
+
+    order.Id = uuid.New()
+    order.Status = "Ordered"
+    order.ActiveStatus = 1
+    order.CreatedAt = time.Now()
+    order.OrderedAt = order.CreatedAt
+    order.CreatedBy = createdBy
+    order.ModifiedAt = order.CreatedAt
+    order.ModifiedBy = createdBy
+
+    query := `INSERT INTO "LabOrders"
+        (id, appointment_id, medicalrecord_id, pet_id, doctor_id, panel, sample_type, status, notes, ordered_at,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+        RETURNING accession_number`
+
+    err = db.QueryRow(query,
+        order.Id, order.AppointmentId, order.MedicalRecordId, order.PetId, order.DoctorId,
+        order.Panel, order.SampleType, order.Status, order.Notes, order.OrderedAt,
+        order.ActiveStatus, order.CreatedAt, order.CreatedBy, order.ModifiedAt, order.ModifiedBy,
+    ).Scan(&order.AccessionNumber)
+    if err != nil {
+        log.Println("Error inserting LabOrder:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lab order"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, order)
+}
+
+func GetLabOrder(c *gin.Context, db *sql.DB) {
+    var order structs.LabOrder
+    err := scanLabOrder(db.QueryRow(`SELECT `+labOrderColumns+` FROM "LabOrders" WHERE id=$1 AND active_status=1`, c.Param("id")), &order)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+        return
+    }
+
+    order.Results, err = fetchLabResults(db, order.Id)
+    if err != nil {
+        log.Println("Error fetching LabResults:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab order"})
+        return
+    }
+
+    c.JSON(http.StatusOK, order)
+}
+
+func listLabOrders(c *gin.Context, db *sql.DB, where string, order string, args ...interface{}) {
+    rows, err := db.Query(`SELECT `+labOrderColumns+`
+                        FROM "LabOrders"
+                        WHERE active_status=1 AND `+where+`
+                        ORDER BY ordered_at `+order, args...)
+    if err != nil {
+        log.Println("Error fetching lab orders:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab orders"})
+        return
+    }
+    defer rows.Close()
+
+    orders := []structs.LabOrder{}
+    for rows.Next() {
+        var o structs.LabOrder
+        if err := scanLabOrder(rows, &o); err != nil {
+            log.Println("Error scanning lab order row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse lab orders"})
+            return
+        }
+        orders = append(orders, o)
+    }
+
+    c.JSON(http.StatusOK, orders)
+}
+
+// GetOpenLabOrders is the lab bench worklist: orders still waiting for results, oldest first
+func GetOpenLabOrders(c *gin.Context, db *sql.DB) {
+    listLabOrders(c, db, `status IN `+openLabStatuses, "ASC")
+}
+
+func GetLabOrdersByAppointmentId(c *gin.Context, db *sql.DB) {
+    listLabOrders(c, db, "appointment_id=$1", "DESC", c.Param("appointment_id"))
+}
+
+// GetPetLabOrders lists a pet's lab orders, optionally filtered by ?status=
+func GetPetLabOrders(c *gin.Context, db *sql.DB) {
+    listLabOrders(c, db, "pet_id=$1 AND ($2='' OR status=$2)", "DESC", c.Param("pet_id"), c.Query("status"))
+}
+
+func UpdateLabOrderStatus(c *gin.Context, db *sql.DB) {
+    orderId := c.Param("id")
+
+    var req struct {
+        Status string `json:"status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateLabOrderStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    var current string
+    if err := db.QueryRow(`SELECT status FROM "LabOrders" WHERE id=$1 AND active_status=1`, orderId).Scan(&current); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+        return
+    }
+    allowed := false
+    for _, next := range labStatusTransitions[current] {
+        if next == req.Status {
+            allowed = true
+        }
+    }
+    if !allowed {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A " + current + " lab order can't be set to " + req.Status})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    now := time.Now()
+    result, err := db.Exec(`UPDATE "LabOrders"
+                    SET status=$1, collected_at=CASE WHEN $1='Collected' THEN $2 ELSE collected_at END,
+                        modified_at=$2, modified_by=$3
+                    WHERE id=$4 AND status=$5 AND active_status=1`, req.Status, now, modifiedBy, orderId, current)
+    if err != nil {
+        log.Println("Error updating LabOrder status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lab order status"})
+        return
+    }
+    // results arrived or someone else changed the status since it was read
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Lab order status was changed meanwhile, please retry"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Lab order status updated successfully"})
+}
+
+// AddLabResults enters results by hand: {"results": [{analyte_code, analyte, value, unit, observed_at}]}
+func AddLabResults(c *gin.Context, db *sql.DB) {
+    orderId := c.Param("id")
+
+    var req struct {
+        Results []structs.LabResult `json:"results"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for AddLabResults:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if len(req.Results) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Results are required"})
+        return
+    }
+    for i := range req.Results {
+        if msg := checkLabResult(&req.Results[i]); msg != "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+            return
+        }
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for AddLabResults:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add lab results"})
+        return
+    }
+    defer tx.Rollback()
+
+    var id uuid.UUID
+    var status string
+    err = tx.QueryRow(`SELECT id, status FROM "LabOrders" WHERE id=$1 AND active_status=1 FOR UPDATE`, orderId).Scan(&id, &status)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+        return
+    }
+    if status == "Cancelled" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Lab order is cancelled"})
+        return
+    }
+
+    if err := storeLabResults(tx, id, req.Results, "Manual", createdBy, time.Now()); err != nil {
+        log.Println("Error inserting LabResults:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add lab results"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing LabResults:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add lab results"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, req.Results)
+}
+
+// labImportGroup is the results of one sample in an import file
+type labImportGroup struct {
+    AccessionNumber string              `json:"accession_number"`
+    PetId           string              `json:"pet_id"`
+    Panel           string              `json:"panel"`
+    LabOrderId      *uuid.UUID          `json:"lab_order_id,omitempty"`
+    Reason          string              `json:"reason,omitempty"` // why it wasn't matched
+    Results         []structs.LabResult `json:"-"`
+    ResultCount     int                 `json:"result_count"`
+}
+
+// matchLabOrder finds the order for an imported sample: by accession number (a Resulted order too,
+// analyzers resend corrected results), or else by pet and panel when exactly one such order is open.
+// Returns the order id, or the reason it couldn't be matched.
+func matchLabOrder(tx *sql.Tx, group labImportGroup) (uuid.UUID, string, error) {
+    var id uuid.UUID
+    var status string
+    if group.AccessionNumber != "" {
+        err := tx.QueryRow(`SELECT id, status FROM "LabOrders" WHERE accession_number=$1 AND active_status=1 FOR UPDATE`,
+            group.AccessionNumber).Scan(&id, &status)
+        if err == sql.ErrNoRows {
+            return uuid.Nil, "No lab order with this accession number", nil
+        }
+        if err != nil {
+            return uuid.Nil, "", err
+        }
+        if status == "Cancelled" {
+            return uuid.Nil, "Lab order is " + status, nil
+        }
+        return id, "", nil
+    }
+
+    if _, err := uuid.Parse(group.PetId); err != nil || group.Panel == "" {
+        return uuid.Nil, "No accession number, and no pet id and panel to match on", nil
+    }
+    rows, err := tx.Query(`SELECT id FROM "LabOrders"
+                        WHERE pet_id=$1 AND lower(panel)=lower($2) AND status IN `+openLabStatuses+` AND active_status=1
+                        FOR UPDATE`, group.PetId, group.Panel)
+    if err != nil {
+        return uuid.Nil, "", err
+    }
+    defer rows.Close()
+    matches := 0
+    for rows.Next() {
+        if err := rows.Scan(&id); err != nil {
+            return uuid.Nil, "", err
+        }
+        matches++
+    }
+    switch {
+    case matches == 0:
+        return uuid.Nil, "No open lab order for this pet and panel", nil
+    case matches > 1:
+        return uuid.Nil, "Several open lab orders for this pet and panel, use the accession number", nil
+    }
+    return id, "", nil
+}
+
+// parseLabCSV reads accession_number, pet_id, panel, analyte_code, analyte, value, unit, observed_at columns
+// (accession_number or pet_id + panel identify the sample), one row per analyte.
+func parseLabCSV(r io.Reader) ([]labImportGroup, []string) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+
+    header, err := reader.Read()
+    if err != nil {
+        return nil, []string{"The file is empty or not valid CSV"}
+    }
+    columns := map[string]int{}
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+    }
+    if _, ok := columns["accession"]; ok {
+        columns["accession_number"] = columns["accession"]
+    }
+    if _, ok := columns["value"]; !ok {
+        return nil, []string{"The header must contain a value column"}
+    }
+    cell := func(row []string, name string) string {
+        i, ok := columns[name]
+        if !ok || i >= len(row) {
+            return ""
+        }
+        return strings.TrimSpace(row[i])
+    }
+
+    var groups []labImportGroup
+    index := map[string]int{}
+    var lineErrors []string
+    line := 1
+    for {
+        row, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        line++
+        if err != nil {
+            lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": "+err.Error())
+            continue
+        }
+
+        result := structs.LabResult{
+            AnalyteCode: cell(row, "analyte_code"),
+            Analyte:     cell(row, "analyte"),
+            Value:       cell(row, "value"),
+            Unit:        cell(row, "unit"),
+        }
+        if result.AnalyteCode == "" && result.Analyte == "" && result.Value == "" {
+            continue
+        }
+        if msg := checkLabResult(&result); msg != "" {
+            lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": "+msg)
+            continue
+        }
+        if raw := cell(row, "observed_at"); raw != "" {
+            observedAt, _, err := parseRangeBound(raw)
+            if err != nil {
+                lineErrors = append(lineErrors, "line "+strconv.Itoa(line)+": observed_at must be RFC3339 or YYYY-MM-DD")
+                continue
+            }
+            result.ObservedAt = &observedAt
+        }
+
+        group := labImportGroup{AccessionNumber: cell(row, "accession_number"), PetId: cell(row, "pet_id"), Panel: cell(row, "panel")}
+        key := group.AccessionNumber + "|" + group.PetId + "|" + strings.ToLower(group.Panel)
+        i, ok := index[key]
+        if !ok {
+            i = len(groups)
+            index[key] = i
+            groups = append(groups, group)
+        }
+        groups[i].Results = append(groups[i].Results, result)
+    }
+    return groups, lineErrors
+}
+
+// hl7AbnormalFlags maps OBX-8 (HL7 table 0078) to our flags
+var hl7AbnormalFlags = map[string]string{
+    "N": "Normal", "L": "Low", "H": "High", "LL": "Critical", "HH": "Critical", "AA": "Critical",
+}
+
+// parseLabHL7 turns the OBR/OBX segments of ORU messages into import groups
+func parseLabHL7(message string) ([]labImportGroup, []string) {
+    orders, err := utils.ParseHL7ORU(message)
+    if err != nil {
+        return nil, []string{err.Error()}
+    }
+
+    var groups []labImportGroup
+    var errs []string
+    for i, order := range orders {
+        group := labImportGroup{
+            AccessionNumber: order.PlacerNumber,
+            PetId:           order.PatientId,
+            Panel:           order.Panel,
+        }
+        if group.AccessionNumber == "" {
+            group.AccessionNumber = order.FillerNumber
+        }
+        for _, obx := range order.Observations {
+            result := structs.LabResult{
+                AnalyteCode: obx.Code,
+                Analyte:     obx.Name,
+                Value:       obx.Value,
+                Unit:        obx.Unit,
+                Flag:        hl7AbnormalFlags[strings.ToUpper(obx.AbnormalFlag)],
+                ObservedAt:  obx.ObservedAt,
+            }
+            if result.ObservedAt == nil {
+                result.ObservedAt = order.ObservedAt
+            }
+            if msg := checkLabResult(&result); msg != "" {
+                errs = append(errs, "OBR "+strconv.Itoa(i+1)+": "+msg)
+                continue
+            }
+            group.Results = append(group.Results, result)
+        }
+        groups = append(groups, group)
+    }
+    return groups, errs
+}
+
+// ImportLabResults: POST /api/lab-orders/import?format=csv|hl7 (multipart "file")
+// The format is detected from the content when not given. Every sample is matched to an open order;
+// matched samples are stored, the others are listed with the reason so they can be entered by hand.
+func ImportLabResults(c *gin.Context, db *sql.DB) {
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "An analyzer export is required in the file field"})
+        return
+    }
+    file, err := fileHeader.Open()
+    if err != nil {
+        log.Println("Error opening lab results upload:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+    defer file.Close()
+    content, err := io.ReadAll(io.LimitReader(file, 10<<20))
+    if err != nil {
+        log.Println("Error reading lab results upload:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+
+    format := strings.ToLower(c.Query("format"))
+    if format == "" {
+        format = "csv"
+        if strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(string(content), "\ufeff")), "MSH") {
+            format = "hl7"
+        }
+    }
+
+    var groups []labImportGroup
+    var parseErrors []string
+    source := "CSV"
+    switch format {
+    case "csv":
+        groups, parseErrors = parseLabCSV(strings.NewReader(string(content)))
+    case "hl7":
+        source = "HL7"
+        groups, parseErrors = parseLabHL7(string(content))
+    default:
+        c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or hl7"})
+        return
+    }
+    if len(parseErrors) > 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The file has invalid lines, nothing was imported", "lines": parseErrors})
+        return
+    }
+    if len(groups) == 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The file has no results"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for lab results import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import lab results"})
+        return
+    }
+    defer tx.Rollback()
+
+    now := time.Now()
+    matched := []labImportGroup{}
+    unmatched := []labImportGroup{}
+    for _, group := range groups {
+        group.ResultCount = len(group.Results)
+        if group.ResultCount == 0 {
+            group.Reason = "No results for this sample"
+            unmatched = append(unmatched, group)
+            continue
+        }
+
+        orderId, reason, err := matchLabOrder(tx, group)
+        if err != nil {
+            log.Println("Error matching LabOrder:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import lab results"})
+            return
+        }
+        if reason != "" {
+            group.Reason = reason
+            unmatched = append(unmatched, group)
+            continue
+        }
+
+        if err := storeLabResults(tx, orderId, group.Results, source, createdBy, now); err != nil {
+            log.Println("Error inserting imported LabResults:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import lab results"})
+            return
+        }
+        group.LabOrderId = &orderId
+        matched = append(matched, group)
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing lab results import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import lab results"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "format":    format,
+        "matched":   matched,
+        "unmatched": unmatched,
+    })
+}
+
+// UpdateLabOrderActiveStatus removes an order entered by mistake (no results yet)
+func UpdateLabOrderActiveStatus(c *gin.Context, db *sql.DB) {
+    orderId := c.Param("id")
+
+    var hasResults bool
+    err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "LabResults" r WHERE r.lab_order_id = o.id AND r.active_status=1)
+                        FROM "LabOrders" o WHERE o.id=$1 AND o.active_status=1`, orderId).Scan(&hasResults)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Lab order not found"})
+        return
+    }
+    if hasResults {
+        c.JSON(http.StatusConflict, gin.H{"error": "Lab order already has results"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "LabOrders"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err = db.Exec(query, time.Now(), modifiedBy, orderId)
+    if err != nil {
+        log.Println("Error soft deleting LabOrder:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate lab order"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             orderId,
+        "deactivated_by": modifiedBy,
+        "message":        "Lab order deactivated successfully",
+    })
+}
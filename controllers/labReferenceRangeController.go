@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const labReferenceRangeColumns = `id, analyte_code, species, age_min_months, age_max_months, COALESCE(unit, ''),
+                low, high, critical_low, critical_high, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanLabReferenceRange(row interface{ Scan(...interface{}) error }, r *structs.LabReferenceRange) error {
+    return row.Scan(
+        &r.Id, &r.AnalyteCode, &r.Species, &r.AgeMinMonths, &r.AgeMaxMonths, &r.Unit,
+        &r.Low, &r.High, &r.CriticalLow, &r.CriticalHigh, &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+    )
+}
+
+// checkLabReferenceRange returns an empty string when valid, otherwise the error message for the client
+func checkLabReferenceRange(r structs.LabReferenceRange) string {
+    if r.AnalyteCode == "" || r.Species == "" {
+        return "AnalyteCode and Species are required"
+    }
+    if r.Low == nil && r.High == nil && r.CriticalLow == nil && r.CriticalHigh == nil {
+        return "At least one limit is required"
+    }
+    if r.AgeMinMonths < 0 || (r.AgeMaxMonths != nil && *r.AgeMaxMonths <= r.AgeMinMonths) {
+        return "AgeMaxMonths must be greater than AgeMinMonths"
+    }
+    if r.Low != nil && r.High != nil && *r.Low > *r.High {
+        return "Low can't be greater than High"
+    }
+    if r.CriticalLow != nil && r.Low != nil && *r.CriticalLow > *r.Low {
+        return "CriticalLow can't be greater than Low"
+    }
+    if r.CriticalHigh != nil && r.High != nil && *r.CriticalHigh < *r.High {
+        return "CriticalHigh can't be less than High"
+    }
+    return ""
+}
+
+// flagLabResult compares a numeric result with the narrowest matching range for the pet's species and age
+// at the time of the observation. Ranges with a unit only apply to results in that unit.
+// Pets without a birth date only match ranges that cover every age.
+func flagLabResult(tx *sql.Tx, orderId uuid.UUID, r *structs.LabResult) error {
+    if r.NumericValue == nil {
+        return nil
+    }
+
+    var low, high, criticalLow, criticalHigh *float64
+    err := tx.QueryRow(`SELECT rr.low, rr.high, rr.critical_low, rr.critical_high
+                    FROM "LabOrders" o
+                    JOIN "Pets" p ON p.id = o.pet_id
+                    JOIN "LabReferenceRanges" rr ON lower(rr.analyte_code) = lower($2) AND lower(rr.species) = lower(p.species)
+                    CROSS JOIN LATERAL (
+                        SELECT (date_part('year', age($4::date, p.birth_date)) * 12 + date_part('month', age($4::date, p.birth_date)))::int AS months
+                    ) age
+                    WHERE o.id=$1 AND rr.active_status=1
+                    AND (COALESCE(rr.unit, '') = '' OR $3 = '' OR lower(rr.unit) = lower($3))
+                    AND CASE WHEN p.birth_date IS NULL THEN rr.age_min_months = 0 AND rr.age_max_months IS NULL
+                        ELSE age.months >= rr.age_min_months AND (rr.age_max_months IS NULL OR age.months < rr.age_max_months) END
+                    ORDER BY COALESCE(rr.age_max_months - rr.age_min_months, 2147483647) ASC, rr.unit IS NULL ASC
+                    LIMIT 1`, orderId, r.AnalyteCode, r.Unit, *r.ObservedAt).Scan(&low, &high, &criticalLow, &criticalHigh)
+    if err == sql.ErrNoRows {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    v := *r.NumericValue
+    r.ReferenceLow = low
+    r.ReferenceHigh = high
+    switch {
+    case (criticalLow != nil && v <= *criticalLow) || (criticalHigh != nil && v >= *criticalHigh):
+        r.Flag = "Critical"
+    case low != nil && v < *low:
+        r.Flag = "Low"
+    case high != nil && v > *high:
+        r.Flag = "High"
+    default:
+        r.Flag = "Normal"
+    }
+    return nil
+}
+
+// notifyCriticalLabResults queues one email to the ordering doctor listing the critical values of an order
+func notifyCriticalLabResults(tx *sql.Tx, orderId uuid.UUID, critical []structs.LabResult, now time.Time) error {
+    var appointmentId uuid.UUID
+    var accession, panel, petName, doctorEmail string
+    err := tx.QueryRow(`SELECT o.appointment_id, o.accession_number, o.panel, p.name, u.email
+                    FROM "LabOrders" o
+                    JOIN "Pets" p ON p.id = o.pet_id
+                    JOIN "Users" u ON u.id = o.doctor_id
+                    WHERE o.id=$1`, orderId).Scan(&appointmentId, &accession, &panel, &petName, &doctorEmail)
+    if err != nil {
+        return err
+    }
+
+    lines := make([]string, 0, len(critical))
+    for _, r := range critical {
+        lines = append(lines, strings.TrimSpace(fmt.Sprintf("%s: %s %s", r.Analyte, r.Value, r.Unit)))
+    }
+    message := fmt.Sprintf("Critical lab results for %s (%s, accession %s): %s.",
+        petName, panel, accession, strings.Join(lines, "; "))
+
+    _, err = tx.Exec(`INSERT INTO "Notifications"
+                    (id, appointment_id, kind, channel, recipient, subject, message, status,
+                    attempts, next_attempt_at, active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,'CriticalLabResult','Email',$3,$4,$5,'Pending',0,$6,1,$6,'system',$6,'system')`,
+        uuid.New(), appointmentId, doctorEmail, "Critical lab result: "+petName, message, now,
+    )
+    return err
+}
+
+func CreateLabReferenceRange(c *gin.Context, db *sql.DB) {
+    var rr structs.LabReferenceRange
+    if err := c.ShouldBindJSON(&rr); err != nil {
+        log.Println("Error binding JSON for new LabReferenceRange:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    rr.AnalyteCode = strings.TrimSpace(rr.AnalyteCode)
+    rr.Species = strings.TrimSpace(rr.Species)
+    rr.Unit = strings.TrimSpace(rr.Unit)
+    if msg := checkLabReferenceRange(rr); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    rr.Id = uuid.New()
+    rr.ActiveStatus = 1
+    rr.CreatedAt = time.Now()
+    rr.CreatedBy = createdBy
+    rr.ModifiedAt = rr.CreatedAt
+    rr.ModifiedBy = createdBy
+
+    query := `INSERT INTO "LabReferenceRanges"
+        (id, analyte_code, species, age_min_months, age_max_months, unit, low, high, critical_low, critical_high,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,NULLIF($6, ''),$7,$8,$9,$10,$11,$12,$13,$14,$15)`
+
+    _, err := db.Exec(query,
+        rr.Id, rr.AnalyteCode, rr.Species, rr.AgeMinMonths, rr.AgeMaxMonths, rr.Unit,
+        rr.Low, rr.High, rr.CriticalLow, rr.CriticalHigh,
+        rr.ActiveStatus, rr.CreatedAt, rr.CreatedBy, rr.ModifiedAt, rr.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting LabReferenceRange:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reference range"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, rr)
+}
+
+// GetLabReferenceRanges lists active ranges, optionally by ?analyte_code= and ?species=
+func GetLabReferenceRanges(c *gin.Context, db *sql.DB) {
+    query := `SELECT ` + labReferenceRangeColumns + `
+            FROM "LabReferenceRanges"
+            WHERE active_status=1
+            AND ($1='' OR lower(analyte_code)=lower($1))
+            AND ($2='' OR lower(species)=lower($2))
+            ORDER BY analyte_code ASC, species ASC, age_min_months ASC`
+
+    rows, err := db.Query(query, c.Query("analyte_code"), c.Query("species"))
+    if err != nil {
+        log.Println("Error fetching reference ranges:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reference ranges"})
+        return
+    }
+    defer rows.Close()
+
+    ranges := []structs.LabReferenceRange{}
+    for rows.Next() {
+        var rr structs.LabReferenceRange
+        if err := scanLabReferenceRange(rows, &rr); err != nil {
+            log.Println("Error scanning reference range row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse reference ranges"})
+            return
+        }
+        ranges = append(ranges, rr)
+    }
+
+    c.JSON(http.StatusOK, ranges)
+}
+
+// UpdateLabReferenceRange replaces the limits and age band of a range; results already flagged keep their flag
+func UpdateLabReferenceRange(c *gin.Context, db *sql.DB) {
+    rangeId := c.Param("id")
+
+    // 1. Fetch existing range
+    var existing structs.LabReferenceRange
+    err := scanLabReferenceRange(db.QueryRow(`SELECT `+labReferenceRangeColumns+` FROM "LabReferenceRanges" WHERE id=$1 AND active_status=1`, rangeId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Reference range not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.LabReferenceRange
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateLabReferenceRange:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields (limits are replaced as a whole, a missing limit means none)
+    if code := strings.TrimSpace(req.AnalyteCode); code != "" {
+        existing.AnalyteCode = code
+    }
+    if species := strings.TrimSpace(req.Species); species != "" {
+        existing.Species = species
+    }
+    existing.Unit = strings.TrimSpace(req.Unit)
+    existing.AgeMinMonths = req.AgeMinMonths
+    existing.AgeMaxMonths = req.AgeMaxMonths
+    existing.Low = req.Low
+    existing.High = req.High
+    existing.CriticalLow = req.CriticalLow
+    existing.CriticalHigh = req.CriticalHigh
+    if msg := checkLabReferenceRange(existing); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "LabReferenceRanges"
+                    SET analyte_code=$1, species=$2, age_min_months=$3, age_max_months=$4, unit=NULLIF($5, ''),
+                        low=$6, high=$7, critical_low=$8, critical_high=$9, modified_at=$10, modified_by=$11
+                    WHERE id=$12 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.AnalyteCode, existing.Species, existing.AgeMinMonths, existing.AgeMaxMonths, existing.Unit,
+        existing.Low, existing.High, existing.CriticalLow, existing.CriticalHigh, time.Now(), modifiedBy, rangeId,
+    )
+    if err != nil {
+        log.Println("Error updating LabReferenceRange:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reference range"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Reference range updated successfully"})
+}
+
+func UpdateLabReferenceRangeActiveStatus(c *gin.Context, db *sql.DB) {
+    rangeId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "LabReferenceRanges"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, rangeId)
+    if err != nil {
+        log.Println("Error soft deleting LabReferenceRange:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate reference range"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             rangeId,
+        "deactivated_by": modifiedBy,
+        "message":        "Reference range deactivated successfully",
+    })
+}
@@ -2,15 +2,322 @@ package controllers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
+	"vetclinic-rest-api/services"
 	"vetclinic-rest-api/structs"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+const medicalRecordColumns = `id, appointment_id, pet_id, diagnosis, COALESCE(notes, ''),
+            COALESCE(subjective, ''), COALESCE(objective, ''), COALESCE(assessment, ''), COALESCE(plan, ''),
+            signed_at, COALESCE(signed_by, ''),
+            active_status, created_at, created_by, modified_at, modified_by`
+
+func scanMedicalRecord(row interface{ Scan(...interface{}) error }, r *structs.MedicalRecord) error {
+    r.SOAP = &structs.SOAPNote{}
+    return row.Scan(
+        &r.Id, &r.AppointmentId, &r.PetId, &r.Diagnosis, &r.Notes,
+        &r.SOAP.Subjective, &r.SOAP.Objective.Notes, &r.SOAP.Assessment.Notes, &r.SOAP.Plan,
+        &r.SignedAt, &r.SignedBy,
+        &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+    )
+}
+
+// fetchMedicalRecordByAppointment loads the active medical record of an appointment with its SOAP sections
+func fetchMedicalRecordByAppointment(db *sql.DB, appointmentId string) (structs.MedicalRecord, error) {
+    var record structs.MedicalRecord
+    err := scanMedicalRecord(db.QueryRow(`SELECT `+medicalRecordColumns+`
+                    FROM "MedicalRecords"
+                    WHERE appointment_id=$1 AND active_status=1`, appointmentId), &record)
+    if err != nil {
+        return record, err
+    }
+    if err := loadSOAPDetails(db, &record); err != nil {
+        return record, err
+    }
+    record.Addenda, err = fetchAddenda(db, record.Id)
+    return record, err
+}
+
+// medicalRecordSigned reports whether an active medical record is signed (sql.ErrNoRows when it doesn't exist)
+func medicalRecordSigned(db *sql.DB, recordId interface{}) (bool, error) {
+    var signed bool
+    err := db.QueryRow(`SELECT signed_at IS NOT NULL FROM "MedicalRecords" WHERE id=$1 AND active_status=1`, recordId).Scan(&signed)
+    return signed, err
+}
+
+// lockUnsignedMedicalRecord locks an active medical record for the rest of the transaction so it can't be
+// signed meanwhile. Answers 404/409 and returns false when it doesn't exist or is already signed.
+func lockUnsignedMedicalRecord(c *gin.Context, tx *sql.Tx, recordId interface{}, signedMessage string) bool {
+    var unsigned bool
+    err := tx.QueryRow(`SELECT signed_at IS NULL FROM "MedicalRecords" WHERE id=$1 AND active_status=1 FOR UPDATE`,
+        recordId).Scan(&unsigned)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return false
+    }
+    if !unsigned {
+        c.JSON(http.StatusConflict, gin.H{"error": signedMessage})
+        return false
+    }
+    return true
+}
+
+// respondRuleViolation writes the response for a failed clinical rule check (409/422 for a
+// services.RuleViolation, 500 otherwise) and returns true when err is not nil
+func respondRuleViolation(c *gin.Context, err error, failure string) bool {
+    if err == nil {
+        return false
+    }
+    var violation *services.RuleViolation
+    if errors.As(err, &violation) {
+        c.JSON(violation.Status, gin.H{"error": violation.Message})
+        return true
+    }
+    log.Println("Error checking clinical rules:", err)
+    c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+    return true
+}
+
+// loadSOAPDetails adds vitals and diagnoses. Records written before SOAP have neither,
+// their diagnosis column is shown as the (only) assessment diagnosis.
+func loadSOAPDetails(db *sql.DB, r *structs.MedicalRecord) error {
+    soap := r.SOAP
+    soap.Objective.Vitals = []structs.Vitals{}
+    soap.Assessment.Diagnoses = []structs.MedicalRecordDiagnosis{}
+
+    rows, err := db.Query(`SELECT id, medicalrecord_id, weight_kg, temperature_c, heart_rate, respiratory_rate,
+                            capillary_refill_seconds, COALESCE(mucous_membranes, ''), body_condition_score, pain_score,
+                            recorded_at, active_status, created_at, created_by, modified_at, modified_by
+                        FROM "Vitals"
+                        WHERE medicalrecord_id=$1 AND active_status=1
+                        ORDER BY recorded_at ASC`, r.Id)
+    if err != nil {
+        return err
+    }
+    for rows.Next() {
+        var v structs.Vitals
+        if err := rows.Scan(
+            &v.Id, &v.MedicalRecordId, &v.WeightKg, &v.TemperatureC, &v.HeartRate, &v.RespiratoryRate,
+            &v.CapillaryRefillSeconds, &v.MucousMembranes, &v.BodyConditionScore, &v.PainScore,
+            &v.RecordedAt, &v.ActiveStatus, &v.CreatedAt, &v.CreatedBy, &v.ModifiedAt, &v.ModifiedBy,
+        ); err != nil {
+            rows.Close()
+            return err
+        }
+        soap.Objective.Vitals = append(soap.Objective.Vitals, v)
+    }
+    rows.Close()
+
+    rows, err = db.Query(`SELECT d.id, d.medicalrecord_id, d.description, COALESCE(c.code, ''), d.diagnosis_catalog_id, COALESCE(c.term, ''),
+                            d.certainty, d.is_primary, d.active_status, d.created_at, d.created_by, d.modified_at, d.modified_by
+                        FROM "MedicalRecordDiagnoses" d
+                        LEFT JOIN "DiagnosisCatalog" c ON c.id = d.diagnosis_catalog_id
+                        WHERE d.medicalrecord_id=$1 AND d.active_status=1
+                        ORDER BY d.is_primary DESC, d.created_at ASC`, r.Id)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var d structs.MedicalRecordDiagnosis
+        if err := rows.Scan(
+            &d.Id, &d.MedicalRecordId, &d.Description, &d.Code, &d.CatalogId, &d.Term,
+            &d.Certainty, &d.IsPrimary, &d.ActiveStatus, &d.CreatedAt, &d.CreatedBy, &d.ModifiedAt, &d.ModifiedBy,
+        ); err != nil {
+            return err
+        }
+        soap.Assessment.Diagnoses = append(soap.Assessment.Diagnoses, d)
+    }
+
+    soap.Structured = soap.Subjective != "" || soap.Objective.Notes != "" || soap.Assessment.Notes != "" ||
+        soap.Plan != "" || len(soap.Objective.Vitals) > 0 || len(soap.Assessment.Diagnoses) > 0
+    if !soap.Structured && r.Diagnosis != "" {
+        soap.Assessment.Diagnoses = append(soap.Assessment.Diagnoses, structs.MedicalRecordDiagnosis{
+            MedicalRecordId: r.Id,
+            Description:     r.Diagnosis,
+            Certainty:       "Confirmed",
+            IsPrimary:       true,
+            ActiveStatus:    1,
+            CreatedAt:       r.CreatedAt,
+            CreatedBy:       r.CreatedBy,
+            ModifiedAt:      r.ModifiedAt,
+            ModifiedBy:      r.ModifiedBy,
+        })
+    }
+    return nil
+}
+
+// resolveDiagnosisCodes links diagnoses that carry a catalog code to the catalog entry,
+// checks it applies to the pet's species and uses the catalog term when no free text was given.
+// Returns an empty string when valid, otherwise the error message for the client.
+func resolveDiagnosisCodes(db *sql.DB, soap *structs.SOAPNote, petId uuid.UUID) (string, error) {
+    if soap == nil {
+        return "", nil
+    }
+    for i := range soap.Assessment.Diagnoses {
+        d := &soap.Assessment.Diagnoses[i]
+        d.CatalogId = nil
+        if d.Code == "" {
+            continue
+        }
+
+        var catalogId uuid.UUID
+        var term string
+        var applies bool
+        err := db.QueryRow(`SELECT c.id, c.term,
+                                cardinality(c.species) = 0 OR EXISTS (
+                                    SELECT 1 FROM unnest(c.species) s
+                                    JOIN "Pets" p ON lower(p.species) = lower(s)
+                                    WHERE p.id = $2
+                                ) OR NOT EXISTS (SELECT 1 FROM "Pets" p WHERE p.id = $2)
+                            FROM "DiagnosisCatalog" c
+                            WHERE c.code=$1 AND c.active_status=1`, d.Code, petId).Scan(&catalogId, &term, &applies)
+        if err == sql.ErrNoRows {
+            return "Unknown diagnosis code: " + d.Code, nil
+        }
+        if err != nil {
+            return "", err
+        }
+        if !applies {
+            return "Diagnosis code " + d.Code + " does not apply to this species", nil
+        }
+        d.CatalogId = &catalogId
+        d.Term = term
+        if d.Description == "" {
+            d.Description = term
+        }
+    }
+    return "", nil
+}
+
+// checkSOAP validates the structured sections and marks the first diagnosis primary when none is.
+// Returns an empty string when valid, otherwise the error message for the client.
+func checkSOAP(soap *structs.SOAPNote) string {
+    if soap == nil {
+        return ""
+    }
+    primaries := 0
+    for i := range soap.Assessment.Diagnoses {
+        d := &soap.Assessment.Diagnoses[i]
+        if d.Description == "" {
+            return "Every diagnosis needs a Description"
+        }
+        if d.Certainty == "" {
+            d.Certainty = "Confirmed"
+        }
+        if d.Certainty != "Tentative" && d.Certainty != "Confirmed" && d.Certainty != "RuledOut" {
+            return "Certainty must be Tentative, Confirmed or RuledOut"
+        }
+        if d.IsPrimary {
+            primaries++
+        }
+    }
+    if primaries > 1 {
+        return "Only one diagnosis can be primary"
+    }
+    if primaries == 0 && len(soap.Assessment.Diagnoses) > 0 {
+        soap.Assessment.Diagnoses[0].IsPrimary = true
+    }
+    for _, v := range soap.Objective.Vitals {
+        if msg := checkVitals(v); msg != "" {
+            return msg
+        }
+    }
+    return ""
+}
+
+func checkVitals(v structs.Vitals) string {
+    if v.WeightKg != nil && *v.WeightKg <= 0 {
+        return "WeightKg must be positive"
+    }
+    if v.BodyConditionScore != nil && (*v.BodyConditionScore < 1 || *v.BodyConditionScore > 9) {
+        return "BodyConditionScore must be between 1 and 9"
+    }
+    if v.PainScore != nil && (*v.PainScore < 0 || *v.PainScore > 10) {
+        return "PainScore must be between 0 and 10"
+    }
+    return ""
+}
+
+// primaryDiagnosis is what the legacy diagnosis column holds for a SOAP record
+func primaryDiagnosis(diagnoses []structs.MedicalRecordDiagnosis) string {
+    for _, d := range diagnoses {
+        if d.IsPrimary {
+            return d.Description
+        }
+    }
+    return ""
+}
+
+func insertVitals(tx *sql.Tx, recordId uuid.UUID, vitals []structs.Vitals, createdBy string, now time.Time) error {
+    for i := range vitals {
+        v := &vitals[i]
+        v.Id = uuid.New()
+        v.MedicalRecordId = recordId
+        if v.RecordedAt.IsZero() {
+            v.RecordedAt = now
+        }
+        v.ActiveStatus = 1
+        v.CreatedAt = now
+        v.CreatedBy = createdBy
+        v.ModifiedAt = now
+        v.ModifiedBy = createdBy
+
+        _, err := tx.Exec(`INSERT INTO "Vitals"
+                        (id, medicalrecord_id, weight_kg, temperature_c, heart_rate, respiratory_rate,
+                        capillary_refill_seconds, mucous_membranes, body_condition_score, pain_score, recorded_at,
+                        active_status, created_at, created_by, modified_at, modified_by)
+                        VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8, ''),$9,$10,$11,$12,$13,$14,$15,$16)`,
+            v.Id, v.MedicalRecordId, v.WeightKg, v.TemperatureC, v.HeartRate, v.RespiratoryRate,
+            v.CapillaryRefillSeconds, v.MucousMembranes, v.BodyConditionScore, v.PainScore, v.RecordedAt,
+            v.ActiveStatus, v.CreatedAt, v.CreatedBy, v.ModifiedAt, v.ModifiedBy,
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// replaceDiagnoses swaps the assessment diagnoses of a record for the given list
+func replaceDiagnoses(tx *sql.Tx, recordId uuid.UUID, diagnoses []structs.MedicalRecordDiagnosis, modifiedBy string, now time.Time) error {
+    _, err := tx.Exec(`UPDATE "MedicalRecordDiagnoses"
+                    SET active_status=0, modified_at=$1, modified_by=$2
+                    WHERE medicalrecord_id=$3 AND active_status=1`, now, modifiedBy, recordId)
+    if err != nil {
+        return err
+    }
+    for i := range diagnoses {
+        d := &diagnoses[i]
+        d.Id = uuid.New()
+        d.MedicalRecordId = recordId
+        d.ActiveStatus = 1
+        d.CreatedAt = now
+        d.CreatedBy = modifiedBy
+        d.ModifiedAt = now
+        d.ModifiedBy = modifiedBy
+
+        _, err := tx.Exec(`INSERT INTO "MedicalRecordDiagnoses"
+                        (id, medicalrecord_id, description, diagnosis_catalog_id, certainty, is_primary,
+                        active_status, created_at, created_by, modified_at, modified_by)
+                        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+            d.Id, d.MedicalRecordId, d.Description, d.CatalogId, d.Certainty, d.IsPrimary,
+            d.ActiveStatus, d.CreatedAt, d.CreatedBy, d.ModifiedAt, d.ModifiedBy,
+        )
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
 func CreateMedicalRecord(c *gin.Context, db *sql.DB) {
     var record structs.MedicalRecord
     if err := c.ShouldBindJSON(&record); err != nil {
@@ -18,9 +325,41 @@ func CreateMedicalRecord(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
         return
     }
+    if record.AppointmentId == uuid.Nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "AppointmentId is required"})
+        return
+    }
 
-    if record.AppointmentId == uuid.Nil || record.PetId == uuid.Nil || record.Diagnosis == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "AppointmentId, PetId, and Diagnosis are required"})
+    // The record belongs to the appointment's pet; one active record per appointment
+    petId, err := services.CheckMedicalRecordAppointment(db, record.AppointmentId, record.PetId)
+    if respondRuleViolation(c, err, "Failed to create medical record") {
+        return
+    }
+    record.PetId = petId
+
+    msg, err := resolveDiagnosisCodes(db, record.SOAP, record.PetId)
+    if err != nil {
+        log.Println("Error resolving diagnosis codes:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+        return
+    }
+    if msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if msg := checkSOAP(record.SOAP); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if record.SOAP == nil {
+        record.SOAP = &structs.SOAPNote{}
+    }
+    // the coded diagnosis list wins over a free-text diagnosis sent along with it
+    if len(record.SOAP.Assessment.Diagnoses) > 0 {
+        record.Diagnosis = primaryDiagnosis(record.SOAP.Assessment.Diagnoses)
+    }
+    if record.Diagnosis == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Diagnosis (or an assessment diagnosis) is required"})
         return
     }
 
@@ -43,39 +382,63 @@ func CreateMedicalRecord(c *gin.Context, db *sql.DB) {
     record.ModifiedAt = record.CreatedAt
     record.ModifiedBy = createdBy
 
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new MedicalRecord:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+        return
+    }
+    defer tx.Rollback()
+
+    soap := record.SOAP
     query := `INSERT INTO "MedicalRecords"
-        (id, appointment_id, pet_id, diagnosis, notes,
+        (id, appointment_id, pet_id, diagnosis, notes, subjective, objective, assessment, plan,
         active_status, created_at, created_by, modified_at, modified_by)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+        VALUES ($1,$2,$3,$4,$5,NULLIF($6, ''),NULLIF($7, ''),NULLIF($8, ''),NULLIF($9, ''),$10,$11,$12,$13,$14)`
 
-    _, err := db.Exec(query,
+    _, err = tx.Exec(query,
         record.Id, record.AppointmentId, record.PetId, record.Diagnosis, record.Notes,
+        soap.Subjective, soap.Objective.Notes, soap.Assessment.Notes, soap.Plan,
         record.ActiveStatus, record.CreatedAt, record.CreatedBy, record.ModifiedAt, record.ModifiedBy,
     )
-    if err != nil {
-        log.Println("Error inserting MedicalRecord:", err)
+    if respondRuleViolation(c, services.DuplicateMedicalRecord(err), "Failed to create medical record") {
+        return
+    }
+    if err := insertVitals(tx, record.Id, soap.Objective.Vitals, createdBy, record.CreatedAt); err != nil {
+        log.Println("Error inserting Vitals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+        return
+    }
+    if err := replaceDiagnoses(tx, record.Id, soap.Assessment.Diagnoses, createdBy, record.CreatedAt); err != nil {
+        log.Println("Error inserting MedicalRecordDiagnoses:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+        return
+    }
+    if err := recordMedicalRecordVersion(tx, record.Id, createdBy, record.CreatedAt); err != nil {
+        log.Println("Error inserting MedicalRecordVersion:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
         return
     }
 
-    c.JSON(http.StatusCreated, record)
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new MedicalRecord:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create medical record"})
+        return
+    }
+
+    created, err := fetchMedicalRecordByAppointment(db, record.AppointmentId.String())
+    if err != nil {
+        log.Println("Error fetching created MedicalRecord:", err)
+        c.JSON(http.StatusCreated, record)
+        return
+    }
+    c.JSON(http.StatusCreated, created)
 }
 
 func GetMedicalRecordByAppointmentId(c *gin.Context, db *sql.DB) {
     appointmentId := c.Param("appointment_id")
 
-    query := `SELECT id, appointment_id, pet_id, diagnosis, notes,
-            active_status, created_at, created_by, modified_at, modified_by
-            FROM "MedicalRecords"
-            WHERE appointment_id=$1 AND active_status=1`
-
-    var record structs.MedicalRecord
-
-    err := db.QueryRow(query, appointmentId).Scan(
-        &record.Id, &record.AppointmentId, &record.PetId, &record.Diagnosis,
-        &record.Notes, &record.ActiveStatus, &record.CreatedAt, &record.CreatedBy,
-        &record.ModifiedAt, &record.ModifiedBy,
-    )
+    record, err := fetchMedicalRecordByAppointment(db, appointmentId)
     if err != nil {
         log.Println("Error fetching MedicalRecord:", err)
         c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
@@ -90,22 +453,19 @@ func UpdateMedicalRecord(c *gin.Context, db *sql.DB) {
 
     // 1. Fetch existing record
     var existing structs.MedicalRecord
-    fetchQuery := `SELECT id, appointment_id, pet_id, diagnosis, notes,
-                          active_status, created_at, created_by,
-                          modified_at, modified_by
+    fetchQuery := `SELECT ` + medicalRecordColumns + `
                    FROM "MedicalRecords"
                    WHERE id=$1 AND active_status=1`
 
-    err := db.QueryRow(fetchQuery, recordId).Scan(
-        &existing.Id, &existing.AppointmentId, &existing.PetId,
-        &existing.Diagnosis, &existing.Notes, &existing.ActiveStatus,
-        &existing.CreatedAt, &existing.CreatedBy,
-        &existing.ModifiedAt, &existing.ModifiedBy,
-    )
+    err := scanMedicalRecord(db.QueryRow(fetchQuery, recordId), &existing)
     if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
         return
     }
+    if existing.SignedAt != nil {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, add an addendum instead"})
+        return
+    }
 
     // 2. Bind incoming JSON
     var req structs.MedicalRecord
@@ -122,6 +482,39 @@ func UpdateMedicalRecord(c *gin.Context, db *sql.DB) {
     if req.Notes != "" {
         existing.Notes = req.Notes
     }
+    msg, err := resolveDiagnosisCodes(db, req.SOAP, existing.PetId)
+    if err != nil {
+        log.Println("Error resolving diagnosis codes:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+        return
+    }
+    if msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if msg := checkSOAP(req.SOAP); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if req.SOAP != nil {
+        if req.SOAP.Subjective != "" {
+            existing.SOAP.Subjective = req.SOAP.Subjective
+        }
+        if req.SOAP.Objective.Notes != "" {
+            existing.SOAP.Objective.Notes = req.SOAP.Objective.Notes
+        }
+        if req.SOAP.Assessment.Notes != "" {
+            existing.SOAP.Assessment.Notes = req.SOAP.Assessment.Notes
+        }
+        if req.SOAP.Plan != "" {
+            existing.SOAP.Plan = req.SOAP.Plan
+        }
+        // a new diagnosis list replaces the old one, the primary becomes the record diagnosis
+        // (also when a free-text diagnosis is sent along, the two must not disagree)
+        if len(req.SOAP.Assessment.Diagnoses) > 0 {
+            existing.Diagnosis = primaryDiagnosis(req.SOAP.Assessment.Diagnoses)
+        }
+    }
 
     // 4. Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
@@ -131,19 +524,65 @@ func UpdateMedicalRecord(c *gin.Context, db *sql.DB) {
     }
     modifiedBy := userIdVal.(string)
 
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateMedicalRecord:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+        return
+    }
+    defer tx.Rollback()
+
     // 5. Update query
+    now := time.Now()
+    soap := existing.SOAP
     updateQuery := `UPDATE "MedicalRecords"
-                    SET diagnosis=$1, notes=$2, modified_at=$3, modified_by=$4
-                    WHERE id=$5 AND active_status=1`
+                    SET diagnosis=$1, notes=$2, subjective=NULLIF($3, ''), objective=NULLIF($4, ''),
+                        assessment=NULLIF($5, ''), plan=NULLIF($6, ''), modified_at=$7, modified_by=$8
+                    WHERE id=$9 AND active_status=1 AND signed_at IS NULL`
 
-    _, err = db.Exec(updateQuery,
-        existing.Diagnosis, existing.Notes, time.Now(), modifiedBy, recordId,
+    result, err := tx.Exec(updateQuery,
+        existing.Diagnosis, existing.Notes, soap.Subjective, soap.Objective.Notes,
+        soap.Assessment.Notes, soap.Plan, now, modifiedBy, recordId,
     )
     if err != nil {
         log.Println("Error updating MedicalRecord:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
         return
     }
+    // signed (or removed) since it was fetched
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, add an addendum instead"})
+        return
+    }
+
+    // 6. New vitals are added as further measurements, a diagnosis list replaces the previous one
+    if req.SOAP != nil {
+        if err := insertVitals(tx, existing.Id, req.SOAP.Objective.Vitals, modifiedBy, now); err != nil {
+            log.Println("Error inserting Vitals:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+            return
+        }
+        if len(req.SOAP.Assessment.Diagnoses) > 0 {
+            if err := replaceDiagnoses(tx, existing.Id, req.SOAP.Assessment.Diagnoses, modifiedBy, now); err != nil {
+                log.Println("Error replacing MedicalRecordDiagnoses:", err)
+                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+                return
+            }
+        }
+    }
+
+    // 7. Every update is kept as a new version
+    if err := recordMedicalRecordVersion(tx, existing.Id, modifiedBy, now); err != nil {
+        log.Println("Error inserting MedicalRecordVersion:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing MedicalRecord update:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update medical record"})
+        return
+    }
 
     c.JSON(http.StatusOK, gin.H{"message": "Medical record updated successfully"})
 }
@@ -151,6 +590,16 @@ func UpdateMedicalRecord(c *gin.Context, db *sql.DB) {
 func UpdateMedicalRecordActiveStatus(c *gin.Context, db *sql.DB) {
     recordId := c.Param("id")
 
+    signed, err := medicalRecordSigned(db, recordId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Signed medical records can only be removed through an Admin correction"})
+        return
+    }
+
     // Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
     if !exists {
@@ -163,20 +612,377 @@ func UpdateMedicalRecordActiveStatus(c *gin.Context, db *sql.DB) {
         return
     }
 
+    // the record may have been signed since the check above
     query := `UPDATE "MedicalRecords"
             SET active_status=0, modified_at=$1, modified_by=$2
-            WHERE id=$3`
+            WHERE id=$3 AND signed_at IS NULL`
 
-    _, err := db.Exec(query, time.Now(), modifiedBy, recordId)
+    result, err := db.Exec(query, time.Now(), modifiedBy, recordId)
     if err != nil {
         log.Println("Error soft deleting MedicalRecord:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate medical record"})
         return
     }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Signed medical records can only be removed through an Admin correction"})
+        return
+    }
 
     c.JSON(http.StatusOK, gin.H{
         "id":             recordId,
         "deactivated_by": modifiedBy,
         "message":        "Medical record deactivated successfully",
     })
-}
\ No newline at end of file
+}
+
+// AddVitals records a further vitals measurement for a medical record (e.g. taken by a nurse)
+func AddVitals(c *gin.Context, db *sql.DB) {
+    recordId := c.Param("id")
+
+    var vitals structs.Vitals
+    if err := c.ShouldBindJSON(&vitals); err != nil {
+        log.Println("Error binding JSON for new Vitals:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if msg := checkVitals(vitals); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    var parsedRecordId uuid.UUID
+    var signed bool
+    err := db.QueryRow(`SELECT id, signed_at IS NOT NULL FROM "MedicalRecords" WHERE id=$1 AND active_status=1`,
+        recordId).Scan(&parsedRecordId, &signed)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, add an addendum instead"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new Vitals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vitals"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Lock the record so it can't be signed while the vitals are added
+    if !lockUnsignedMedicalRecord(c, tx, parsedRecordId, "Medical record is signed, add an addendum instead") {
+        return
+    }
+
+    list := []structs.Vitals{vitals}
+    if err := insertVitals(tx, parsedRecordId, list, createdBy, time.Now()); err != nil {
+        log.Println("Error inserting Vitals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vitals"})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new Vitals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vitals"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, list[0])
+}
+
+// SignMedicalRecord finalizes a record. Only the attending doctor of the appointment can sign,
+// afterwards the record (with its vitals, diagnoses and treatments) is immutable.
+func SignMedicalRecord(c *gin.Context, db *sql.DB) {
+    recordId := c.Param("id")
+    userId := c.GetString("user_id")
+
+    var doctorId *uuid.UUID
+    var signedAt *time.Time
+    err := db.QueryRow(`SELECT a.doctor_id, m.signed_at
+                        FROM "MedicalRecords" m
+                        JOIN "Appointments" a ON a.id = m.appointment_id
+                        WHERE m.id=$1 AND m.active_status=1`, recordId).Scan(&doctorId, &signedAt)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    if doctorId == nil || doctorId.String() != userId {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Only the attending doctor can sign this medical record"})
+        return
+    }
+    if signedAt != nil {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is already signed"})
+        return
+    }
+
+    now := time.Now()
+    result, err := db.Exec(`UPDATE "MedicalRecords"
+                    SET signed_at=$1, signed_by=$2, modified_at=$3, modified_by=$2
+                    WHERE id=$4 AND active_status=1 AND signed_at IS NULL`, now, userId, now, recordId)
+    if err != nil {
+        log.Println("Error signing MedicalRecord:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign medical record"})
+        return
+    }
+    if affected, _ := result.RowsAffected(); affected == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is already signed"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":        recordId,
+        "signed_at": now,
+        "signed_by": userId,
+        "message":   "Medical record signed successfully",
+    })
+}
+
+func fetchAddenda(db *sql.DB, recordId uuid.UUID) ([]structs.MedicalRecordAddendum, error) {
+    rows, err := db.Query(`SELECT id, medicalrecord_id, content, signed_at, signed_by,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "MedicalRecordAddenda"
+                        WHERE medicalrecord_id=$1 AND active_status=1
+                        ORDER BY signed_at ASC`, recordId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    addenda := []structs.MedicalRecordAddendum{}
+    for rows.Next() {
+        var a structs.MedicalRecordAddendum
+        if err := rows.Scan(
+            &a.Id, &a.MedicalRecordId, &a.Content, &a.SignedAt, &a.SignedBy,
+            &a.ActiveStatus, &a.CreatedAt, &a.CreatedBy, &a.ModifiedAt, &a.ModifiedBy,
+        ); err != nil {
+            return nil, err
+        }
+        addenda = append(addenda, a)
+    }
+    return addenda, nil
+}
+
+// CreateAddendum appends a timestamped note to a signed record, signed by its author on creation
+func CreateAddendum(c *gin.Context, db *sql.DB) {
+    recordId := c.Param("id")
+
+    var addendum structs.MedicalRecordAddendum
+    if err := c.ShouldBindJSON(&addendum); err != nil {
+        log.Println("Error binding JSON for new MedicalRecordAddendum:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if addendum.Content == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Content is required"})
+        return
+    }
+
+    var parsedRecordId uuid.UUID
+    var signed bool
+    err := db.QueryRow(`SELECT id, signed_at IS NOT NULL FROM "MedicalRecords" WHERE id=$1 AND active_status=1`,
+        recordId).Scan(&parsedRecordId, &signed)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    if !signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is not signed yet, update it instead"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    addendum.Id = uuid.New()
+    addendum.MedicalRecordId = parsedRecordId
+    addendum.SignedAt = time.Now()
+    addendum.SignedBy = createdBy
+    addendum.ActiveStatus = 1
+    addendum.CreatedAt = addendum.SignedAt
+    addendum.CreatedBy = createdBy
+    addendum.ModifiedAt = addendum.SignedAt
+    addendum.ModifiedBy = createdBy
+
+    query := `INSERT INTO "MedicalRecordAddenda"
+        (id, medicalrecord_id, content, signed_at, signed_by,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+
+    _, err = db.Exec(query,
+        addendum.Id, addendum.MedicalRecordId, addendum.Content, addendum.SignedAt, addendum.SignedBy,
+        addendum.ActiveStatus, addendum.CreatedAt, addendum.CreatedBy, addendum.ModifiedAt, addendum.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting MedicalRecordAddendum:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create addendum"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, addendum)
+}
+
+// CreateMedicalRecordCorrection is the Admin-only way to remove a signed record:
+// the reason and a snapshot of the record are kept in the corrections audit
+func CreateMedicalRecordCorrection(c *gin.Context, db *sql.DB) {
+    recordId := c.Param("id")
+
+    var req struct {
+        Action string `json:"action"`
+        Reason string `json:"reason"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for new MedicalRecordCorrection:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.Action == "" {
+        req.Action = "Deactivate"
+    }
+    if req.Action != "Deactivate" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be Deactivate"})
+        return
+    }
+    if req.Reason == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+        return
+    }
+
+    var appointmentId string
+    err := db.QueryRow(`SELECT appointment_id FROM "MedicalRecords" WHERE id=$1 AND active_status=1`, recordId).Scan(&appointmentId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    record, err := fetchMedicalRecordByAppointment(db, appointmentId)
+    if err != nil {
+        log.Println("Error fetching MedicalRecord for correction:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+    snapshot, err := json.Marshal(record)
+    if err != nil {
+        log.Println("Error encoding MedicalRecord snapshot:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for MedicalRecordCorrection:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+    defer tx.Rollback()
+
+    now := time.Now()
+    correction := structs.MedicalRecordCorrection{
+        Id:              uuid.New(),
+        MedicalRecordId: record.Id,
+        Action:          req.Action,
+        Reason:          req.Reason,
+        Snapshot:        snapshot,
+        ActiveStatus:    1,
+        CreatedAt:       now,
+        CreatedBy:       createdBy,
+        ModifiedAt:      now,
+        ModifiedBy:      createdBy,
+    }
+    _, err = tx.Exec(`INSERT INTO "MedicalRecordCorrections"
+                    (id, medicalrecord_id, action, reason, snapshot,
+                    active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+        correction.Id, correction.MedicalRecordId, correction.Action, correction.Reason, string(correction.Snapshot),
+        correction.ActiveStatus, correction.CreatedAt, correction.CreatedBy, correction.ModifiedAt, correction.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting MedicalRecordCorrection:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+
+    _, err = tx.Exec(`UPDATE "MedicalRecords"
+                    SET active_status=0, modified_at=$1, modified_by=$2
+                    WHERE id=$3`, now, createdBy, record.Id)
+    if err != nil {
+        log.Println("Error deactivating corrected MedicalRecord:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing MedicalRecordCorrection:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct medical record"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, correction)
+}
+
+// GetMedicalRecordCorrections lists the correction audit of a record (Admin)
+func GetMedicalRecordCorrections(c *gin.Context, db *sql.DB) {
+    recordId := c.Param("id")
+
+    rows, err := db.Query(`SELECT id, medicalrecord_id, action, reason, snapshot,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "MedicalRecordCorrections"
+                        WHERE medicalrecord_id=$1
+                        ORDER BY created_at ASC`, recordId)
+    if err != nil {
+        log.Println("Error fetching medical record corrections:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch corrections"})
+        return
+    }
+    defer rows.Close()
+
+    corrections := []structs.MedicalRecordCorrection{}
+    for rows.Next() {
+        var mc structs.MedicalRecordCorrection
+        var snapshot []byte
+        if err := rows.Scan(
+            &mc.Id, &mc.MedicalRecordId, &mc.Action, &mc.Reason, &snapshot,
+            &mc.ActiveStatus, &mc.CreatedAt, &mc.CreatedBy, &mc.ModifiedAt, &mc.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning medical record correction row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse corrections"})
+            return
+        }
+        mc.Snapshot = snapshot
+        corrections = append(corrections, mc)
+    }
+
+    c.JSON(http.StatusOK, corrections)
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationsByAppointmentId lists reminders/offers of an appointment with their delivery status
+func GetNotificationsByAppointmentId(c *gin.Context, db *sql.DB) {
+    appointmentId := c.Param("id")
+
+    query := `SELECT id, appointment_id, waitlist_id, kind, channel, recipient, COALESCE(subject, ''), message,
+                status, attempts, next_attempt_at, COALESCE(last_error, ''), sent_at,
+                active_status, created_at, created_by, modified_at, modified_by
+            FROM "Notifications"
+            WHERE appointment_id=$1 AND active_status=1
+            ORDER BY created_at ASC`
+
+    rows, err := db.Query(query, appointmentId)
+    if err != nil {
+        log.Println("Error fetching notifications by appointment_id:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+        return
+    }
+    defer rows.Close()
+
+    var notifications []structs.Notification
+    for rows.Next() {
+        var n structs.Notification
+        if err := rows.Scan(
+            &n.Id, &n.AppointmentId, &n.WaitlistId, &n.Kind, &n.Channel, &n.Recipient, &n.Subject, &n.Message,
+            &n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.SentAt,
+            &n.ActiveStatus, &n.CreatedAt, &n.CreatedBy, &n.ModifiedAt, &n.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning notification row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse notifications"})
+            return
+        }
+        notifications = append(notifications, n)
+    }
+
+    c.JSON(http.StatusOK, notifications)
+}
@@ -0,0 +1,465 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// RegisterOwner creates an owner login (Staff/Admin). An owner already known by phone number
+// (e.g. backfilled from pet data) gets the login attached instead of a duplicate.
+func RegisterOwner(c *gin.Context, db *sql.DB) {
+    var req structs.Owner
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    if req.Name == "" || req.Email == "" || req.Phone == "" || req.Password == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Name, Email, Phone, and Password are required"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    hashedPassword, err := utils.HashPassword(req.Password)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+        return
+    }
+
+    now := time.Now()
+    owner := structs.Owner{
+        Name:         req.Name,
+        Email:        strings.ToLower(req.Email),
+        Phone:        req.Phone,
+        ActiveStatus: 1,
+        ModifiedAt:   now,
+        ModifiedBy:   createdBy,
+    }
+
+    var existingHash sql.NullString
+    err = db.QueryRow(`SELECT id, password_hash, created_at, created_by FROM "Owners" WHERE phone=$1 AND active_status=1`,
+        req.Phone).Scan(&owner.Id, &existingHash, &owner.CreatedAt, &owner.CreatedBy)
+    switch {
+    case err == sql.ErrNoRows:
+        owner.Id = uuid.New()
+        owner.CreatedAt = now
+        owner.CreatedBy = createdBy
+        _, err = db.Exec(`INSERT INTO "Owners"
+            (id, name, email, phone, password_hash, active_status, created_at, created_by, modified_at, modified_by)
+            VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+            owner.Id, owner.Name, owner.Email, owner.Phone, hashedPassword, owner.ActiveStatus,
+            owner.CreatedAt, owner.CreatedBy, owner.ModifiedAt, owner.ModifiedBy,
+        )
+    case err != nil:
+        log.Println("Error fetching Owner by phone:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register owner"})
+        return
+    case existingHash.Valid && existingHash.String != "":
+        c.JSON(http.StatusConflict, gin.H{"error": "An owner account already exists for this phone number"})
+        return
+    default:
+        _, err = db.Exec(`UPDATE "Owners"
+            SET name=$1, email=$2, password_hash=$3, modified_at=$4, modified_by=$5
+            WHERE id=$6`,
+            owner.Name, owner.Email, hashedPassword, owner.ModifiedAt, owner.ModifiedBy, owner.Id,
+        )
+    }
+    if err != nil {
+        log.Println("Error saving Owner:", err)
+        c.JSON(http.StatusConflict, gin.H{"error": "Failed to register owner, email may already be in use"})
+        return
+    }
+
+    // Link pets registered under the same phone number
+    _, err = db.Exec(`UPDATE "Pets" SET owner_id=$1 WHERE owner_phone=$2 AND owner_id IS NULL`, owner.Id, owner.Phone)
+    if err != nil {
+        log.Println("Error linking Pets to Owner:", err)
+    }
+
+    c.JSON(http.StatusCreated, owner)
+}
+
+func LoginOwner(c *gin.Context, db *sql.DB) {
+    var req struct {
+        Email    string `json:"email"`
+        Password string `json:"password"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    var ownerId uuid.UUID
+    var passwordHash sql.NullString
+    err := db.QueryRow(`SELECT id, password_hash FROM "Owners" WHERE lower(email)=lower($1) AND active_status=1`,
+        req.Email).Scan(&ownerId, &passwordHash)
+    if err != nil || !passwordHash.Valid || !utils.CheckPasswordHash(req.Password, passwordHash.String) {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+        return
+    }
+
+    // Restricted claim set: owners only ever get the Owner role
+    claims := jwt.MapClaims{
+        "user_id": ownerId.String(),
+        "role":    "Owner",
+        "exp":     time.Now().Add(time.Hour * 2).Unix(),
+    }
+
+    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+    tokenString, err := token.SignedString(utils.JwtSecret)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "token": tokenString,
+    })
+}
+
+// fetchOwner loads an active owner with their pets
+func fetchOwner(db *sql.DB, ownerId string) (structs.Owner, []structs.Pet, error) {
+    var owner structs.Owner
+    err := db.QueryRow(`SELECT id, name, COALESCE(email, ''), phone, active_status, created_at, created_by, modified_at, modified_by
+                        FROM "Owners" WHERE id=$1 AND active_status=1`, ownerId).Scan(
+        &owner.Id, &owner.Name, &owner.Email, &owner.Phone, &owner.ActiveStatus,
+        &owner.CreatedAt, &owner.CreatedBy, &owner.ModifiedAt, &owner.ModifiedBy,
+    )
+    if err != nil {
+        return owner, nil, err
+    }
+
+    rows, err := db.Query(`SELECT id, name, species, breed, gender, birth_date, owner_name, owner_phone, owner_id, active_status, created_at, created_by, modified_at, modified_by
+                        FROM "Pets"
+                        WHERE owner_id=$1 AND active_status=1
+                        ORDER BY name ASC`, ownerId)
+    if err != nil {
+        return owner, nil, err
+    }
+    defer rows.Close()
+
+    pets := []structs.Pet{}
+    for rows.Next() {
+        var pet structs.Pet
+        if err := rows.Scan(
+            &pet.Id, &pet.Name, &pet.Species, &pet.Breed, &pet.Gender,
+            &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone, &pet.OwnerId,
+            &pet.ActiveStatus, &pet.CreatedAt, &pet.CreatedBy,
+            &pet.ModifiedAt, &pet.ModifiedBy,
+        ); err != nil {
+            return owner, nil, err
+        }
+        pets = append(pets, pet)
+    }
+
+    noShows, lateCancellations, err := ownerAttendance(db, &owner.Id, uuid.Nil)
+    if err != nil {
+        return owner, nil, err
+    }
+    owner.NoShowCount = &noShows
+    owner.LateCancellationCount = &lateCancellations
+    return owner, pets, nil
+}
+
+// FetchOwnerProfile: owner profile with pets for clinic users
+func FetchOwnerProfile(c *gin.Context, db *sql.DB) {
+    owner, pets, err := fetchOwner(db, c.Param("id"))
+    if err != nil {
+        log.Println("Error fetching Owner profile:", err)
+        c.JSON(http.StatusNotFound, gin.H{"error": "Owner not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "owner": owner,
+        "pets":  pets,
+    })
+}
+
+// ---------------------------------------------------------
+// Owner self-service (JWT role Owner, user_id = owner id)
+// ---------------------------------------------------------
+
+func GetMyOwnerProfile(c *gin.Context, db *sql.DB) {
+    ownerId := c.GetString("user_id")
+
+    owner, pets, err := fetchOwner(db, ownerId)
+    if err != nil {
+        log.Println("Error fetching Owner profile:", err)
+        c.JSON(http.StatusNotFound, gin.H{"error": "Owner not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "owner": owner,
+        "pets":  pets,
+    })
+}
+
+func GetMyUpcomingAppointments(c *gin.Context, db *sql.DB) {
+    ownerId := c.GetString("user_id")
+
+    query := `SELECT a.id, a.pet_id, a.doctor_id, a.appointment_type_id, a.status, a.appointment_datetime, a.notes,
+                a.active_status, a.created_at, a.created_by, a.modified_at, a.modified_by
+            FROM "Appointments" a
+            JOIN "Pets" p ON p.id = a.pet_id
+            WHERE p.owner_id=$1 AND a.active_status=1 AND a.status <> 'Cancelled'
+            AND a.appointment_datetime >= $2
+            ORDER BY a.appointment_datetime ASC`
+
+    rows, err := db.Query(query, ownerId, time.Now())
+    if err != nil {
+        log.Println("Error fetching owner appointments:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointments"})
+        return
+    }
+    defer rows.Close()
+
+    var appointments []structs.Appointment
+    for rows.Next() {
+        var appt structs.Appointment
+        if err := rows.Scan(
+            &appt.Id, &appt.PetId, &appt.DoctorId, &appt.AppointmentTypeId, &appt.Status,
+            &appt.AppointmentDatetime, &appt.Notes, &appt.ActiveStatus,
+            &appt.CreatedAt, &appt.CreatedBy, &appt.ModifiedAt, &appt.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning appointment row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse appointments"})
+            return
+        }
+        appointments = append(appointments, appt)
+    }
+
+    c.JSON(http.StatusOK, appointments)
+}
+
+// GetAvailableSlots: GET /api/owner/slots?date=YYYY-MM-DD&doctor_id=&type_id=
+func GetAvailableSlots(c *gin.Context, db *sql.DB) {
+    day, err := utils.ParseClinicDate(c.Query("date"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+        return
+    }
+
+    var doctorId *uuid.UUID
+    if raw := c.Query("doctor_id"); raw != "" {
+        id, err := uuid.Parse(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid doctor_id"})
+            return
+        }
+        doctorId = &id
+    }
+    typeId, ok := appointmentTypeFilter(c)
+    if !ok {
+        return
+    }
+
+    slots, err := services.AvailableSlots(db, day, doctorId, typeId, services.LoadBookingRules())
+    if err != nil {
+        log.Println("Error computing available slots:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch available slots"})
+        return
+    }
+
+    c.JSON(http.StatusOK, slots)
+}
+
+// RequestMyAppointment books an appointment for one of the owner's pets within the clinic booking rules
+func RequestMyAppointment(c *gin.Context, db *sql.DB) {
+    ownerId := c.GetString("user_id")
+
+    var req structs.Appointment
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for RequestMyAppointment:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.PetId == uuid.Nil || req.DoctorId == uuid.Nil || req.AppointmentDatetime.IsZero() {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "PetId, DoctorId and AppointmentDatetime are required"})
+        return
+    }
+
+    // 1. Pet must belong to the owner
+    var petOwned bool
+    err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Pets" WHERE id=$1 AND owner_id=$2 AND active_status=1)`,
+        req.PetId, ownerId).Scan(&petOwned)
+    if err != nil || !petOwned {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+        return
+    }
+
+    // 2. Clinic booking rules; the user must be active and have the role the type requires,
+    // an appointment without a type is with a doctor (as listed by the available slots)
+    var doctorRole string
+    err = db.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, req.DoctorId).Scan(&doctorRole)
+    if err != nil || (req.AppointmentTypeId == nil && doctorRole != "Doctor") {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Doctor not found"})
+        return
+    }
+    if msg := checkAppointmentType(db, req.AppointmentTypeId, req.DoctorId); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    rules := services.LoadBookingRules()
+    duration, err := services.AppointmentDuration(db, req.AppointmentTypeId)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type not found"})
+        return
+    }
+    if !rules.WithinBookingWindow(req.AppointmentDatetime) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf(
+            "Appointments must be booked at least %d hours and at most %d days in advance",
+            rules.MinLeadHours, rules.MaxAdvanceDays)})
+        return
+    }
+    if !rules.WithinOpeningHours(req.AppointmentDatetime, duration) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Appointment is outside clinic opening hours"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for RequestMyAppointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 3. Serialize bookings of the same doctor, then check the slot is still free
+    if _, err := tx.Exec(`SELECT id FROM "Users" WHERE id=$1 FOR UPDATE`, req.DoctorId); err != nil {
+        log.Println("Error locking doctor for booking:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+    free, err := services.DoctorIsFree(tx, req.DoctorId, req.AppointmentDatetime, duration, nil)
+    if err != nil {
+        log.Println("Error checking doctor availability:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+    if !free {
+        c.JSON(http.StatusConflict, gin.H{"error": "This slot is no longer available"})
+        return
+    }
+
+    now := time.Now()
+    appointment := structs.Appointment{
+        Id:                  uuid.New(),
+        PetId:               req.PetId,
+        DoctorId:            req.DoctorId,
+        AppointmentTypeId:   req.AppointmentTypeId,
+        Status:              "Pending",
+        AppointmentDatetime: req.AppointmentDatetime,
+        Notes:               req.Notes,
+        ActiveStatus:        1,
+        CreatedAt:           now,
+        CreatedBy:           ownerId,
+        ModifiedAt:          now,
+        ModifiedBy:          ownerId,
+    }
+
+    _, err = tx.Exec(`INSERT INTO "Appointments"
+        (id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
+        appointment.Id, appointment.PetId, appointment.DoctorId, appointment.AppointmentTypeId, appointment.Status,
+        appointment.AppointmentDatetime, appointment.Notes, appointment.ActiveStatus,
+        appointment.CreatedAt, appointment.CreatedBy, appointment.ModifiedAt, appointment.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting owner Appointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+
+    // 4. Reserve the rooms/equipment the appointment type needs
+    if !allocateAppointmentResources(c, tx, appointment.Id, appointment.AppointmentTypeId, appointment.AppointmentDatetime, ownerId) {
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing owner Appointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, appointment)
+}
+
+// CancelMyAppointment cancels an owner's own appointment, no later than the clinic cancellation notice
+func CancelMyAppointment(c *gin.Context, db *sql.DB) {
+    ownerId := c.GetString("user_id")
+    appointmentId := c.Param("id")
+
+    var req cancellationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for CancelMyAppointment:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    req.CancelledByParty = "Owner"
+
+    var appointmentDatetime time.Time
+    var status string
+    err := db.QueryRow(`SELECT a.appointment_datetime, a.status
+                        FROM "Appointments" a
+                        JOIN "Pets" p ON p.id = a.pet_id
+                        WHERE a.id=$1 AND p.owner_id=$2 AND a.active_status=1`,
+        appointmentId, ownerId).Scan(&appointmentDatetime, &status)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+    if status != "Pending" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only pending appointments can be cancelled"})
+        return
+    }
+
+    rules := services.LoadBookingRules()
+    if time.Until(appointmentDatetime) < time.Duration(rules.CancelNoticeHours)*time.Hour {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf(
+            "Appointments can only be cancelled online at least %d hours in advance, please call the clinic",
+            rules.CancelNoticeHours)})
+        return
+    }
+    if msg := checkCancellationReason(db, req); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    cancelled, err := cancelAppointment(db, appointmentId, appointmentDatetime, req, ownerId, "Pending")
+    if err != nil {
+        log.Println("Error cancelling owner Appointment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel appointment"})
+        return
+    }
+    if !cancelled {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only pending appointments can be cancelled"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Appointment cancelled successfully"})
+}
@@ -0,0 +1,506 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const paymentColumns = `id, receipt_number, owner_id, payer_name, method, amount, COALESCE(reference, ''),
+                received_by, COALESCE((SELECT u.name FROM "Users" u WHERE u.id = "Payments".received_by), ''), received_at,
+                COALESCE(notes, ''), status, voided_at, COALESCE(voided_by, ''), COALESCE(void_reason, ''),
+                active_status, created_at, created_by, modified_at, modified_by`
+
+func scanPayment(row interface{ Scan(...interface{}) error }, p *structs.Payment) error {
+    return row.Scan(
+        &p.Id, &p.ReceiptNumber, &p.OwnerId, &p.PayerName, &p.Method, &p.Amount, &p.Reference,
+        &p.ReceivedBy, &p.ReceivedByName, &p.ReceivedAt,
+        &p.Notes, &p.Status, &p.VoidedAt, &p.VoidedBy, &p.VoidReason,
+        &p.ActiveStatus, &p.CreatedAt, &p.CreatedBy, &p.ModifiedAt, &p.ModifiedBy,
+    )
+}
+
+var paymentMethods = map[string]bool{"Cash": true, "Card": true, "Transfer": true}
+
+func fetchPaymentAllocations(db *sql.DB, paymentId uuid.UUID) ([]structs.PaymentAllocation, error) {
+    rows, err := db.Query(`SELECT pa.id, pa.payment_id, pa.invoice_id, COALESCE(i.invoice_number, ''), pa.amount,
+                            pa.active_status, pa.created_at, pa.created_by, pa.modified_at, pa.modified_by
+                        FROM "PaymentAllocations" pa
+                        JOIN "Invoices" i ON i.id = pa.invoice_id
+                        WHERE pa.payment_id=$1 AND pa.active_status=1
+                        ORDER BY i.issued_at ASC, i.invoice_number ASC`, paymentId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    allocations := []structs.PaymentAllocation{}
+    for rows.Next() {
+        var a structs.PaymentAllocation
+        if err := rows.Scan(
+            &a.Id, &a.PaymentId, &a.InvoiceId, &a.InvoiceNumber, &a.Amount,
+            &a.ActiveStatus, &a.CreatedAt, &a.CreatedBy, &a.ModifiedAt, &a.ModifiedBy,
+        ); err != nil {
+            return nil, err
+        }
+        allocations = append(allocations, a)
+    }
+    return allocations, rows.Err()
+}
+
+// fetchPayment loads an active payment with its allocations
+func fetchPayment(db *sql.DB, paymentId string) (structs.Payment, error) {
+    var payment structs.Payment
+    err := scanPayment(db.QueryRow(`SELECT `+paymentColumns+` FROM "Payments"
+                    WHERE id=$1 AND active_status=1`, paymentId), &payment)
+    if err != nil {
+        return payment, err
+    }
+    payment.Allocations, err = fetchPaymentAllocations(db, payment.Id)
+    return payment, err
+}
+
+// checkPayment returns an empty string when valid, otherwise the error message for the client
+func checkPayment(p structs.Payment) string {
+    if !paymentMethods[p.Method] {
+        return "Method must be Cash, Card or Transfer"
+    }
+    if len(p.Allocations) == 0 {
+        return "Allocations are required: [{invoice_id, amount}]"
+    }
+    sum := 0
+    seen := map[uuid.UUID]bool{}
+    for _, a := range p.Allocations {
+        if a.InvoiceId == uuid.Nil || a.Amount <= 0 {
+            return "Every allocation needs an invoice_id and a positive amount"
+        }
+        if seen[a.InvoiceId] {
+            return "An invoice can only be allocated once per payment"
+        }
+        seen[a.InvoiceId] = true
+        sum += a.Amount
+    }
+    if p.Amount != sum {
+        return "Allocations must add up to the amount"
+    }
+    return ""
+}
+
+// CreatePayment records money received and settles one or more issued invoices with it, fully or in part:
+// {method, amount, reference, payer_name, received_at, notes, allocations: [{invoice_id, amount}]}
+func CreatePayment(c *gin.Context, db *sql.DB) {
+    var payment structs.Payment
+    if err := c.ShouldBindJSON(&payment); err != nil {
+        log.Println("Error binding JSON for new Payment:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if payment.Amount == 0 {
+        for _, a := range payment.Allocations {
+            payment.Amount += a.Amount
+        }
+    }
+    if msg := checkPayment(payment); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    now := time.Now()
+    if payment.ReceivedAt.IsZero() {
+        payment.ReceivedAt = now
+    }
+    if payment.ReceivedAt.After(now.Add(5 * time.Minute)) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "ReceivedAt can't be in the future"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+    payment.ReceivedBy, _ = uuid.Parse(createdBy)
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new Payment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Lock the invoices in a fixed order so two payments for the same invoices can't deadlock.
+    // The owner of the payment is the owner of its invoices, whatever the client sent.
+    allocations := append([]structs.PaymentAllocation{}, payment.Allocations...)
+    sort.Slice(allocations, func(i, j int) bool { return allocations[i].InvoiceId.String() < allocations[j].InvoiceId.String() })
+    var clinicProfileId uuid.UUID
+    var billToName string
+    payment.OwnerId = nil
+    for i, a := range allocations {
+        var invoice structs.Invoice
+        err := tx.QueryRow(`SELECT i.status, COALESCE(i.invoice_number, ''), i.total, i.clinic_profile_id,
+                                COALESCE(i.owner_id, p.owner_id), i.bill_to_name
+                            FROM "Invoices" i
+                            JOIN "Pets" p ON p.id = i.pet_id
+                            WHERE i.id=$1 AND i.active_status=1
+                            FOR UPDATE OF i`, a.InvoiceId).Scan(
+            &invoice.Status, &invoice.InvoiceNumber, &invoice.Total, &invoice.ClinicProfileId, &invoice.OwnerId, &invoice.BillToName,
+        )
+        if err == sql.ErrNoRows {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invoice not found: " + a.InvoiceId.String()})
+            return
+        }
+        if err != nil {
+            log.Println("Error fetching Invoice for payment:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+            return
+        }
+        if invoice.Status != "Issued" {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Only issued invoices can be paid"})
+            return
+        }
+        paid, err := services.InvoicePaid(tx, a.InvoiceId)
+        if err != nil {
+            log.Println("Error fetching Invoice payments:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+            return
+        }
+        if a.Amount > invoice.Total-paid {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invoice " + invoice.InvoiceNumber + " has a balance of " +
+                formatAmount(invoice.Total-paid) + ", the allocation is " + formatAmount(a.Amount)})
+            return
+        }
+        if i == 0 {
+            clinicProfileId = invoice.ClinicProfileId
+            payment.OwnerId = invoice.OwnerId
+            billToName = invoice.BillToName
+        } else if !sameUUID(payment.OwnerId, invoice.OwnerId) || (invoice.OwnerId == nil && invoice.BillToName != billToName) {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "All invoices of a payment must belong to the same owner"})
+            return
+        }
+    }
+    if strings.TrimSpace(payment.PayerName) == "" {
+        payment.PayerName = billToName
+    }
+
+    payment.ReceiptNumber, err = services.NextReceiptNumber(tx, clinicProfileId)
+    if err != nil {
+        log.Println("Error taking receipt number:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+        return
+    }
+    payment.Id = uuid.New()
+    _, err = tx.Exec(`INSERT INTO "Payments"
+        (id, clinic_profile_id, receipt_number, owner_id, payer_name, method, amount, reference, received_by, received_at, notes,
+        status, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8, ''),$9,$10,NULLIF($11, ''),'Recorded',1,$12,$13,$12,$13)`,
+        payment.Id, clinicProfileId, payment.ReceiptNumber, payment.OwnerId, strings.TrimSpace(payment.PayerName), payment.Method,
+        payment.Amount, strings.TrimSpace(payment.Reference), payment.ReceivedBy, payment.ReceivedAt, payment.Notes,
+        now, createdBy,
+    )
+    for _, a := range allocations {
+        if err != nil {
+            break
+        }
+        _, err = tx.Exec(`INSERT INTO "PaymentAllocations"
+            (id, payment_id, invoice_id, amount, active_status, created_at, created_by, modified_at, modified_by)
+            VALUES ($1,$2,$3,$4,1,$5,$6,$5,$6)`, uuid.New(), payment.Id, a.InvoiceId, a.Amount, now, createdBy)
+    }
+    if err == nil {
+        err = tx.Commit()
+    }
+    if err != nil {
+        log.Println("Error inserting Payment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+        return
+    }
+
+    created, err := fetchPayment(db, payment.Id.String())
+    if err != nil {
+        log.Println("Error fetching new Payment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
+        return
+    }
+    c.JSON(http.StatusCreated, created)
+}
+
+func GetPayment(c *gin.Context, db *sql.DB) {
+    payment, err := fetchPayment(db, c.Param("id"))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+        return
+    }
+    if err != nil {
+        log.Println("Error fetching payment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
+        return
+    }
+
+    c.JSON(http.StatusOK, payment)
+}
+
+// listPayments answers with the active payments matching where, newest first, with their allocations
+func listPayments(c *gin.Context, db *sql.DB, where string, args ...interface{}) {
+    rows, err := db.Query(`SELECT `+paymentColumns+` FROM "Payments"
+                    WHERE active_status=1 AND `+where+`
+                    ORDER BY received_at DESC`, args...)
+    if err != nil {
+        log.Println("Error fetching payments:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+        return
+    }
+    payments := []structs.Payment{}
+    for rows.Next() {
+        var p structs.Payment
+        if err := scanPayment(rows, &p); err != nil {
+            rows.Close()
+            log.Println("Error scanning payment row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse payments"})
+            return
+        }
+        payments = append(payments, p)
+    }
+    rows.Close()
+
+    for i := range payments {
+        allocations, err := fetchPaymentAllocations(db, payments[i].Id)
+        if err != nil {
+            log.Println("Error fetching payment allocations:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+            return
+        }
+        payments[i].Allocations = allocations
+    }
+
+    c.JSON(http.StatusOK, payments)
+}
+
+// GetPayments: GET /api/payments?method=&status=&from=&to= (date received)
+func GetPayments(c *gin.Context, db *sql.DB) {
+    where := "($1='' OR method=$1) AND ($2='' OR status=$2)"
+    args := []interface{}{c.Query("method"), c.Query("status")}
+    if raw := c.Query("from"); raw != "" {
+        from, _, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        args = append(args, from)
+        where += " AND received_at >= $" + strconv.Itoa(len(args))
+    }
+    if raw := c.Query("to"); raw != "" {
+        to, dateOnly, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        if dateOnly {
+            to = to.AddDate(0, 0, 1)
+        }
+        args = append(args, to)
+        where += " AND received_at < $" + strconv.Itoa(len(args))
+    }
+    listPayments(c, db, where, args...)
+}
+
+func GetPaymentsByInvoice(c *gin.Context, db *sql.DB) {
+    listPayments(c, db, `id IN (SELECT payment_id FROM "PaymentAllocations" WHERE invoice_id=$1 AND active_status=1)`, c.Param("invoice_id"))
+}
+
+func GetPaymentsByOwner(c *gin.Context, db *sql.DB) {
+    ownerId, err := uuid.Parse(c.Param("owner_id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner id"})
+        return
+    }
+    listPayments(c, db, "owner_id=$1", ownerId)
+}
+
+// fetchOwnerBalance adds up the owner's issued invoices and what was paid on them
+func fetchOwnerBalance(db *sql.DB, ownerId uuid.UUID) (structs.OwnerBalance, error) {
+    balance := structs.OwnerBalance{OwnerId: ownerId, Invoices: []structs.Invoice{}}
+    rows, err := db.Query(`SELECT `+invoiceColumns+` FROM "Invoices"
+                    WHERE active_status=1 AND status='Issued' AND `+ownerInvoicesWhere+`
+                    ORDER BY issued_at ASC`, ownerId)
+    if err != nil {
+        return balance, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var i structs.Invoice
+        if err := scanInvoice(rows, &i); err != nil {
+            return balance, err
+        }
+        balance.Invoiced += i.Total
+        balance.Paid += i.Paid
+        if i.Balance > 0 {
+            balance.Invoices = append(balance.Invoices, i)
+        }
+    }
+    balance.Outstanding = balance.Invoiced - balance.Paid
+    return balance, rows.Err()
+}
+
+// GetOwnerBalance shows what an owner was invoiced, paid and still owes, with the open invoices
+func GetOwnerBalance(c *gin.Context, db *sql.DB) {
+    ownerId, err := uuid.Parse(c.Param("owner_id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner id"})
+        return
+    }
+    balance, err := fetchOwnerBalance(db, ownerId)
+    if err != nil {
+        log.Println("Error fetching owner balance:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance"})
+        return
+    }
+
+    c.JSON(http.StatusOK, balance)
+}
+
+// GetMyBalance is GetOwnerBalance for the logged in owner
+func GetMyBalance(c *gin.Context, db *sql.DB) {
+    ownerId, err := uuid.Parse(c.GetString("user_id"))
+    if err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid owner ID"})
+        return
+    }
+    balance, err := fetchOwnerBalance(db, ownerId)
+    if err != nil {
+        log.Println("Error fetching owner balance:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch balance"})
+        return
+    }
+
+    c.JSON(http.StatusOK, balance)
+}
+
+// VoidPayment cancels a payment recorded by mistake, the invoices it settled are open again
+func VoidPayment(c *gin.Context, db *sql.DB) {
+    paymentId := c.Param("id")
+
+    var req struct {
+        Reason string `json:"reason"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for VoidPayment:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    req.Reason = strings.TrimSpace(req.Reason)
+    if req.Reason == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    voidedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    now := time.Now()
+    result, err := db.Exec(`UPDATE "Payments"
+                    SET status='Void', voided_at=$1, voided_by=$2, void_reason=$3, modified_at=$1, modified_by=$2
+                    WHERE id=$4 AND status='Recorded' AND active_status=1`, now, voidedBy, req.Reason, paymentId)
+    if err != nil {
+        log.Println("Error voiding Payment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void payment"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Recorded payment not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Payment voided successfully"})
+}
+
+// GetPaymentReceiptPDF prints the receipt of a payment with the invoices it settled and what is left on each
+func GetPaymentReceiptPDF(c *gin.Context, db *sql.DB) {
+    payment, err := fetchPayment(db, c.Param("id"))
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+        return
+    }
+    if err != nil {
+        log.Println("Error fetching payment for receipt:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
+        return
+    }
+
+    pdf, err := newClinicPDF(db, "Receipt "+payment.ReceiptNumber)
+    if err != nil {
+        log.Println("Error preparing receipt PDF:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+        return
+    }
+    if payment.Status == "Void" {
+        pdf.SetColor(200, 0, 0)
+        pdf.Heading("VOID", 14)
+        pdf.SetColor(0, 0, 0)
+        pdf.Field("Reason", payment.VoidReason)
+        if payment.VoidedAt != nil {
+            pdf.Field("Voided", payment.VoidedAt.In(utils.ClinicLocation()).Format(pdfDateTime))
+        }
+    }
+
+    pdf.Field("Received from", payment.PayerName)
+    pdf.Field("Date", payment.ReceivedAt.In(utils.ClinicLocation()).Format(pdfDateTime))
+    pdf.Field("Method", payment.Method)
+    if payment.Reference != "" {
+        pdf.Field("Reference", payment.Reference)
+    }
+    pdf.Field("Received by", payment.ReceivedByName)
+    if payment.Notes != "" {
+        pdf.Field("Notes", payment.Notes)
+    }
+
+    pdf.Heading("Invoices paid", 12)
+    rows := [][]string{}
+    for _, a := range payment.Allocations {
+        var total, paid int
+        err := db.QueryRow(`SELECT total FROM "Invoices" WHERE id=$1`, a.InvoiceId).Scan(&total)
+        if err == nil {
+            paid, err = services.InvoicePaidAsOf(db, a.InvoiceId, payment.Id)
+        }
+        if err != nil {
+            log.Println("Error fetching invoice for receipt:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+            return
+        }
+        rows = append(rows, []string{a.InvoiceNumber, formatAmount(total), formatAmount(a.Amount), formatAmount(total - paid)})
+    }
+    pdf.Table([]utils.PDFColumn{
+        {Title: "Invoice", Width: 165}, {Title: "Invoice total", Width: 110, Right: true},
+        {Title: "Paid now", Width: 110, Right: true}, {Title: "Balance due", Width: 110, Right: true},
+    }, rows)
+    label := "Amount received  " + formatAmount(payment.Amount)
+    pdf.Space(4)
+    pdf.Text(utils.PDFPageWidth-utils.PDFMargin-3-utils.PDFTextWidth(label, 11, true), pdf.Y, 11, true, label)
+    pdf.Space(18)
+    pdf.Paragraph("Thank you for your payment.", 10)
+
+    sendPDF(c, pdfFileName("receipt", payment.ReceiptNumber), pdf)
+}
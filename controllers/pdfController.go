@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pdfVisit is an appointment with the names printed on reports
+type pdfVisit struct {
+    Datetime   time.Time
+    Status     string
+    Notes      string
+    TypeName   string
+    DoctorName string
+}
+
+const pdfDateTime = "2 January 2006 15:04"
+
+// newClinicPDF starts a document branded with the clinic profile: logo, name and contacts on every page
+func newClinicPDF(db *sql.DB, title string) (*utils.PDF, error) {
+    profile, err := fetchClinicProfile(db)
+    if err != nil {
+        return nil, err
+    }
+    var logo []byte
+    if profile.HasLogo {
+        if err := db.QueryRow(`SELECT logo FROM "ClinicProfile" WHERE id=$1`, profile.Id).Scan(&logo); err != nil {
+            return nil, err
+        }
+    }
+    r, g, b := hexToRGB(profile.AccentColor)
+
+    contacts := []string{}
+    for _, v := range []string{profile.Phone, profile.Email, profile.Website} {
+        if v != "" {
+            contacts = append(contacts, v)
+        }
+    }
+
+    pdf := utils.NewPDF(title)
+    pdf.SetHeader(func(p *utils.PDF) {
+        x := utils.PDFMargin
+        top := p.Y
+        if logo != nil {
+            if w, _, err := p.Image(logo, x, top, 60, 48); err == nil {
+                x += w + 12
+            } else {
+                log.Println("Error drawing clinic logo:", err)
+            }
+        }
+        p.SetColor(r, g, b)
+        p.Text(x, top, 16, true, profile.Name)
+        p.SetColor(90, 90, 90)
+        lineY := top + 20
+        for _, line := range []string{strings.ReplaceAll(profile.Address, "\n", ", "), strings.Join(contacts, "  |  ")} {
+            if line != "" {
+                p.Text(x, lineY, 9, false, line)
+                lineY += 12
+            }
+        }
+        p.SetColor(0, 0, 0)
+        if lineY < top+48 && logo != nil {
+            lineY = top + 48
+        }
+        p.SetStrokeColor(r, g, b)
+        p.Line(utils.PDFMargin, lineY+4, utils.PDFPageWidth-utils.PDFMargin, lineY+4, 1.5)
+        p.SetStrokeColor(0, 0, 0)
+        p.Y = lineY + 16
+    })
+
+    footer := "Generated " + time.Now().In(utils.ClinicLocation()).Format(pdfDateTime)
+    if profile.FooterText != "" {
+        footer = profile.FooterText + "  |  " + footer
+    }
+    pdf.SetFooter(footer)
+    pdf.AddPage()
+    pdf.Heading(title, 15)
+    return pdf, nil
+}
+
+// hexToRGB reads #RRGGBB, anything else is black
+func hexToRGB(hex string) (uint8, uint8, uint8) {
+    value, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+    if err != nil || len(hex) != 7 {
+        return 0, 0, 0
+    }
+    return uint8(value >> 16), uint8(value >> 8), uint8(value)
+}
+
+func sendPDF(c *gin.Context, filename string, pdf *utils.PDF) {
+    c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+    c.Data(http.StatusOK, "application/pdf", pdf.Bytes())
+}
+
+// pdfFileName keeps letters and digits of a name for use in a download file name
+func pdfFileName(parts ...string) string {
+    name := strings.Map(func(r rune) rune {
+        if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+            return r
+        }
+        if r == ' ' || r == '_' {
+            return '-'
+        }
+        return -1
+    }, strings.ToLower(strings.Join(parts, "-")))
+    return name + ".pdf"
+}
+
+func fetchPDFPet(db *sql.DB, petId string) (structs.Pet, error) {
+    var pet structs.Pet
+    err := db.QueryRow(`SELECT id, name, COALESCE(species, ''), COALESCE(breed, ''), COALESCE(gender, ''),
+                            COALESCE(to_char(birth_date, 'YYYY-MM-DD'), ''), COALESCE(owner_name, ''), COALESCE(owner_phone, '')
+                        FROM "Pets" WHERE id=$1 AND active_status=1`, petId).Scan(
+        &pet.Id, &pet.Name, &pet.Species, &pet.Breed, &pet.Gender, &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone,
+    )
+    return pet, err
+}
+
+func fetchPDFVisit(db *sql.DB, appointmentId string) (pdfVisit, string, error) {
+    var visit pdfVisit
+    var petId string
+    err := db.QueryRow(`SELECT a.pet_id, a.appointment_datetime, a.status, COALESCE(a.notes, ''),
+                            COALESCE(t.name, ''), COALESCE(u.name, '')
+                        FROM "Appointments" a
+                        LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                        LEFT JOIN "Users" u ON u.id = a.doctor_id
+                        WHERE a.id=$1 AND a.active_status=1`, appointmentId).Scan(
+        &petId, &visit.Datetime, &visit.Status, &visit.Notes, &visit.TypeName, &visit.DoctorName,
+    )
+    return visit, petId, err
+}
+
+// userName returns the name of a user id for printing, or the id itself when it isn't a user
+func userName(db *sql.DB, userId string) string {
+    var name string
+    if err := db.QueryRow(`SELECT name FROM "Users" WHERE id::text=$1`, userId).Scan(&name); err != nil {
+        return userId
+    }
+    return name
+}
+
+func writePetPDF(p *utils.PDF, pet structs.Pet) {
+    p.Heading("Patient", 12)
+    p.Field("Name", pet.Name)
+    p.Field("Species / breed", strings.Trim(pet.Species+" / "+pet.Breed, " /"))
+    p.Field("Gender", pet.Gender)
+    p.Field("Birth date", pet.BirthDate)
+    p.Field("Owner", strings.Trim(pet.OwnerName+", "+pet.OwnerPhone, ", "))
+}
+
+func writeMedicalRecordPDF(db *sql.DB, p *utils.PDF, record structs.MedicalRecord) {
+    soap := record.SOAP
+    if soap.Structured {
+        if soap.Subjective != "" {
+            p.Field("Subjective", soap.Subjective)
+        }
+        if soap.Objective.Notes != "" {
+            p.Field("Objective", soap.Objective.Notes)
+        }
+    }
+
+    if len(soap.Objective.Vitals) > 0 {
+        p.Space(4)
+        rows := [][]string{}
+        for _, v := range soap.Objective.Vitals {
+            rows = append(rows, []string{
+                v.RecordedAt.In(utils.ClinicLocation()).Format("15:04"),
+                pdfFloat(v.WeightKg, " kg"), pdfFloat(v.TemperatureC, " °C"), pdfInt(v.HeartRate), pdfInt(v.RespiratoryRate),
+                pdfFloat(v.CapillaryRefillSeconds, " s"), v.MucousMembranes, pdfInt(v.BodyConditionScore), pdfInt(v.PainScore),
+            })
+        }
+        p.Table([]utils.PDFColumn{
+            {Title: "Time", Width: 45}, {Title: "Weight", Width: 60}, {Title: "Temp", Width: 55},
+            {Title: "HR", Width: 40}, {Title: "RR", Width: 40}, {Title: "CRT", Width: 45},
+            {Title: "Mucous membranes", Width: 110}, {Title: "BCS", Width: 50}, {Title: "Pain", Width: 50},
+        }, rows)
+        p.Space(4)
+    }
+
+    if soap.Structured && soap.Assessment.Notes != "" {
+        p.Field("Assessment", soap.Assessment.Notes)
+    }
+    diagnoses := []string{}
+    for _, d := range soap.Assessment.Diagnoses {
+        line := d.Description
+        if d.Code != "" {
+            line += " (" + d.Code + ")"
+        }
+        line += ", " + d.Certainty
+        if d.IsPrimary {
+            line += ", primary"
+        }
+        diagnoses = append(diagnoses, line)
+    }
+    p.Field("Diagnoses", strings.Join(diagnoses, "\n"))
+    if soap.Structured && soap.Plan != "" {
+        p.Field("Plan", soap.Plan)
+    }
+    if record.Notes != "" {
+        p.Field("Notes", record.Notes)
+    }
+
+    if record.SignedAt != nil {
+        p.Field("Signed", userName(db, record.SignedBy)+", "+record.SignedAt.In(utils.ClinicLocation()).Format(pdfDateTime))
+    } else {
+        p.Field("Signed", "Not signed")
+    }
+    for _, a := range record.Addenda {
+        p.Field("Addendum", a.Content+"\n("+userName(db, a.SignedBy)+", "+a.SignedAt.In(utils.ClinicLocation()).Format(pdfDateTime)+")")
+    }
+}
+
+// writeTreatmentsPDF prints the treatments of a record with their total and returns the total
+func writeTreatmentsPDF(db *sql.DB, p *utils.PDF, recordId interface{}) (int, error) {
+    rows, err := db.Query(`SELECT t.description, COALESCE(u.name, ''), t.cost
+                        FROM "Treatments" t
+                        LEFT JOIN "Users" u ON u.id = t.doctor_id
+                        WHERE t.medicalrecord_id=$1 AND t.active_status=1
+                        ORDER BY t.created_at ASC`, recordId)
+    if err != nil {
+        return 0, err
+    }
+    defer rows.Close()
+
+    lines := [][]string{}
+    total := 0
+    for rows.Next() {
+        var description, doctor string
+        var cost int
+        if err := rows.Scan(&description, &doctor, &cost); err != nil {
+            return 0, err
+        }
+        lines = append(lines, []string{description, doctor, formatAmount(cost)})
+        total += cost
+    }
+    if err := rows.Err(); err != nil {
+        return 0, err
+    }
+    if len(lines) == 0 {
+        p.Paragraph("No treatments.", 10)
+        return 0, nil
+    }
+
+    p.Table([]utils.PDFColumn{
+        {Title: "Treatment", Width: 295}, {Title: "Doctor", Width: 120}, {Title: "Cost", Width: 80, Right: true},
+    }, lines)
+    label := "Total cost  " + formatAmount(total)
+    p.Space(4)
+    p.Text(utils.PDFPageWidth-utils.PDFMargin-3-utils.PDFTextWidth(label, 10, true), p.Y, 10, true, label)
+    p.Space(16)
+    return total, nil
+}
+
+func writePrescriptionsPDF(db *sql.DB, p *utils.PDF, recordId interface{}) error {
+    rows, err := db.Query(`SELECT drug_name, COALESCE(strength, ''), dose, route, frequency, quantity, COALESCE(unit, ''),
+                            refills_allowed, status
+                        FROM "Prescriptions"
+                        WHERE medicalrecord_id=$1 AND active_status=1
+                        ORDER BY prescribed_at ASC`, recordId)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    lines := [][]string{}
+    for rows.Next() {
+        var drug, strength, dose, route, frequency, unit, status string
+        var quantity float64
+        var refills int
+        if err := rows.Scan(&drug, &strength, &dose, &route, &frequency, &quantity, &unit, &refills, &status); err != nil {
+            return err
+        }
+        lines = append(lines, []string{
+            strings.TrimSpace(drug + " " + strength), dose, route, frequency,
+            strings.TrimSpace(strconv.FormatFloat(quantity, 'f', -1, 64) + " " + unit), strconv.Itoa(refills), status,
+        })
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    if len(lines) == 0 {
+        return nil
+    }
+
+    p.Heading("Prescriptions", 11)
+    p.Table([]utils.PDFColumn{
+        {Title: "Drug", Width: 130}, {Title: "Dose", Width: 70}, {Title: "Route", Width: 50}, {Title: "Frequency", Width: 85},
+        {Title: "Quantity", Width: 60}, {Title: "Refills", Width: 45}, {Title: "Status", Width: 55},
+    }, lines)
+    p.Space(6)
+    return nil
+}
+
+func writeLabResultsPDF(db *sql.DB, p *utils.PDF, appointmentId interface{}) error {
+    rows, err := db.Query(`SELECT o.panel, r.analyte, r.value, COALESCE(r.unit, ''), r.reference_low, r.reference_high, COALESCE(r.flag, '')
+                        FROM "LabOrders" o
+                        JOIN "LabResults" r ON r.lab_order_id = o.id AND r.active_status=1
+                        WHERE o.appointment_id=$1 AND o.active_status=1
+                        ORDER BY o.ordered_at ASC, r.analyte ASC`, appointmentId)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    lines := [][]string{}
+    for rows.Next() {
+        var panel, analyte, value, unit, flag string
+        var low, high *float64
+        if err := rows.Scan(&panel, &analyte, &value, &unit, &low, &high, &flag); err != nil {
+            return err
+        }
+        reference := ""
+        if low != nil || high != nil {
+            reference = strings.TrimSpace(pdfFloat(low, "") + " - " + pdfFloat(high, ""))
+        }
+        lines = append(lines, []string{panel, analyte, value, unit, reference, flag})
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    if len(lines) == 0 {
+        return nil
+    }
+
+    p.Heading("Lab results", 11)
+    p.Table([]utils.PDFColumn{
+        {Title: "Panel", Width: 90}, {Title: "Analyte", Width: 130}, {Title: "Value", Width: 65, Right: true},
+        {Title: "Unit", Width: 60}, {Title: "Reference", Width: 90}, {Title: "Flag", Width: 60},
+    }, lines)
+    p.Space(6)
+    return nil
+}
+
+func pdfFloat(v *float64, unit string) string {
+    if v == nil {
+        return ""
+    }
+    return strconv.FormatFloat(*v, 'f', -1, 64) + unit
+}
+
+func pdfInt(v *int) string {
+    if v == nil {
+        return ""
+    }
+    return strconv.Itoa(*v)
+}
+
+// buildVisitSummaryPDF prints an appointment with its medical record (nil when there is none), treatments and total cost
+func buildVisitSummaryPDF(db *sql.DB, visit pdfVisit, pet structs.Pet, record *structs.MedicalRecord) (*utils.PDF, error) {
+    pdf, err := newClinicPDF(db, "Visit Summary")
+    if err != nil {
+        return nil, err
+    }
+    writePetPDF(pdf, pet)
+
+    pdf.Heading("Appointment", 12)
+    pdf.Field("Date", visit.Datetime.In(utils.ClinicLocation()).Format(pdfDateTime))
+    pdf.Field("Type", visit.TypeName)
+    pdf.Field("Doctor", visit.DoctorName)
+    pdf.Field("Status", visit.Status)
+    if visit.Notes != "" {
+        pdf.Field("Notes", visit.Notes)
+    }
+
+    pdf.Heading("Medical record", 12)
+    if record == nil {
+        pdf.Paragraph("No medical record for this appointment.", 10)
+        return pdf, nil
+    }
+    writeMedicalRecordPDF(db, pdf, *record)
+
+    pdf.Heading("Treatments", 12)
+    if _, err := writeTreatmentsPDF(db, pdf, record.Id); err != nil {
+        return nil, err
+    }
+    return pdf, nil
+}
+
+// GetVisitSummaryPDF prints the appointment with its medical record, treatments and total cost
+func GetVisitSummaryPDF(c *gin.Context, db *sql.DB) {
+    appointmentId := c.Param("id")
+
+    visit, petId, err := fetchPDFVisit(db, appointmentId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+    pet, err := fetchPDFPet(db, petId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+        return
+    }
+    record, err := fetchMedicalRecordByAppointment(db, appointmentId)
+    hasMedicalRecord := err == nil
+    if err != nil && err != sql.ErrNoRows {
+        log.Println("Error fetching medical record for visit summary:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medical record"})
+        return
+    }
+
+    var recordPtr *structs.MedicalRecord
+    if hasMedicalRecord {
+        recordPtr = &record
+    }
+    pdf, err := buildVisitSummaryPDF(db, visit, pet, recordPtr)
+    if err != nil {
+        log.Println("Error generating visit summary PDF:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+        return
+    }
+
+    sendPDF(c, pdfFileName("visit-summary", pet.Name, visit.Datetime.In(utils.ClinicLocation()).Format("2006-01-02")), pdf)
+}
+
+// GetPetMedicalHistoryPDF prints every medical record of a pet, oldest visit first,
+// with treatments, prescriptions and lab results
+func GetPetMedicalHistoryPDF(c *gin.Context, db *sql.DB) {
+    petId := c.Param("id")
+
+    pet, err := fetchPDFPet(db, petId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+        return
+    }
+
+    rows, err := db.Query(`SELECT `+medicalRecordColumns+`
+                        FROM "MedicalRecords" m
+                        WHERE m.pet_id=$1 AND m.active_status=1
+                        ORDER BY (SELECT a.appointment_datetime FROM "Appointments" a WHERE a.id = m.appointment_id) ASC`, petId)
+    if err != nil {
+        log.Println("Error fetching medical records for history:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medical records"})
+        return
+    }
+    records := []structs.MedicalRecord{}
+    for rows.Next() {
+        var r structs.MedicalRecord
+        if err := scanMedicalRecord(rows, &r); err != nil {
+            rows.Close()
+            log.Println("Error scanning medical record row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse medical records"})
+            return
+        }
+        records = append(records, r)
+    }
+    rows.Close()
+
+    pdf, err := newClinicPDF(db, "Medical History")
+    if err != nil {
+        log.Println("Error preparing medical history PDF:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+        return
+    }
+    writePetPDF(pdf, pet)
+    if len(records) == 0 {
+        pdf.Space(8)
+        pdf.Paragraph("No medical records.", 10)
+    }
+
+    for i := range records {
+        record := &records[i]
+        if err := loadSOAPDetails(db, record); err == nil {
+            record.Addenda, err = fetchAddenda(db, record.Id)
+        }
+        if err != nil {
+            log.Println("Error fetching medical record details for history:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch medical records"})
+            return
+        }
+        visit, _, err := fetchPDFVisit(db, record.AppointmentId.String())
+        if err != nil {
+            // the appointment was deactivated after the record was written
+            visit = pdfVisit{Datetime: record.CreatedAt}
+        }
+
+        pdf.Space(8)
+        pdf.Rule()
+        title := visit.Datetime.In(utils.ClinicLocation()).Format(pdfDateTime)
+        for _, part := range []string{visit.TypeName, visit.DoctorName} {
+            if part != "" {
+                title += "  -  " + part
+            }
+        }
+        pdf.Heading(title, 12)
+        writeMedicalRecordPDF(db, pdf, *record)
+
+        pdf.Heading("Treatments", 11)
+        if _, err := writeTreatmentsPDF(db, pdf, record.Id); err != nil {
+            log.Println("Error fetching treatments for history:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch treatments"})
+            return
+        }
+        if err := writePrescriptionsPDF(db, pdf, record.Id); err != nil {
+            log.Println("Error fetching prescriptions for history:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
+            return
+        }
+        if err := writeLabResultsPDF(db, pdf, record.AppointmentId); err != nil {
+            log.Println("Error fetching lab results for history:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lab results"})
+            return
+        }
+    }
+
+    sendPDF(c, pdfFileName("medical-history", pet.Name), pdf)
+}
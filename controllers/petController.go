@@ -44,9 +44,11 @@ func CreatePet(c *gin.Context, db *sql.DB) {
     newPet.ModifiedBy = createdBy
 
     query := `INSERT INTO "Pets"
-        (id, name, species, breed, gender, birth_date, owner_name, owner_phone,
+        (id, name, species, breed, gender, birth_date, owner_name, owner_phone, owner_id,
 		active_status, created_at, created_by, modified_at, modified_by)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,
+        (SELECT id FROM "Owners" WHERE phone=$8 AND active_status=1),
+        $9,$10,$11,$12,$13)`
 
     _, err := db.Exec(query,
         newPet.Id, newPet.Name, newPet.Species, newPet.Breed, newPet.Gender,
@@ -76,11 +78,11 @@ func FetchPetProfile(c *gin.Context, db *sql.DB) {
     petId := c.Param("id")
     var pet structs.Pet
 
-    query := `SELECT id, name, species, breed, gender, birth_date, owner_name, owner_phone, active_status, created_at, created_by, modified_at, modified_by 
+    query := `SELECT id, name, species, breed, gender, birth_date, owner_name, owner_phone, owner_id, active_status, created_at, created_by, modified_at, modified_by 
 			FROM "Pets" WHERE id=$1 AND active_status=1`
     err := db.QueryRow(query, petId).Scan(
         &pet.Id, &pet.Name, &pet.Species, &pet.Breed, &pet.Gender,
-        &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone,
+        &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone, &pet.OwnerId,
         &pet.ActiveStatus, &pet.CreatedAt, &pet.CreatedBy,
         &pet.ModifiedAt, &pet.ModifiedBy,
     )
@@ -90,6 +92,14 @@ func FetchPetProfile(c *gin.Context, db *sql.DB) {
         return
     }
 
+    noShows, _, err := ownerAttendance(db, pet.OwnerId, pet.Id)
+    if err != nil {
+        log.Println("Error counting owner no-shows:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pet profile"})
+        return
+    }
+    pet.OwnerNoShowCount = &noShows
+
     c.JSON(http.StatusOK, pet)
 }
 
@@ -99,7 +109,7 @@ func UpdatePet(c *gin.Context, db *sql.DB) {
     // 1. Fetch existing pet
     var existing structs.Pet
     fetchQuery := `SELECT id, name, species, breed, gender, birth_date,
-                        owner_name, owner_phone, active_status,
+                        owner_name, owner_phone, owner_id, active_status,
                         created_at, created_by, modified_at, modified_by
                     FROM "Pets"
                     WHERE id=$1 AND active_status=1`
@@ -107,7 +117,7 @@ func UpdatePet(c *gin.Context, db *sql.DB) {
     err := db.QueryRow(fetchQuery, petId).Scan(
         &existing.Id, &existing.Name, &existing.Species, &existing.Breed,
         &existing.Gender, &existing.BirthDate, &existing.OwnerName,
-        &existing.OwnerPhone, &existing.ActiveStatus,
+        &existing.OwnerPhone, &existing.OwnerId, &existing.ActiveStatus,
         &existing.CreatedAt, &existing.CreatedBy,
         &existing.ModifiedAt, &existing.ModifiedBy,
     )
@@ -159,6 +169,7 @@ func UpdatePet(c *gin.Context, db *sql.DB) {
     updateQuery := `UPDATE "Pets"
                     SET name=$1, species=$2, breed=$3, gender=$4, birth_date=$5,
                         owner_name=$6, owner_phone=$7,
+                        owner_id=(SELECT id FROM "Owners" WHERE phone=$7 AND active_status=1),
                         modified_at=$8, modified_by=$9
                     WHERE id=$10 AND active_status=1`
 
@@ -213,7 +224,7 @@ func FetchPetsByOwner(c *gin.Context, db *sql.DB) {
     ownerName := c.Param("owner_name")
     ownerPhone := c.Param("owner_phone")
 
-    query := `SELECT id, name, species, breed, gender, birth_date, owner_name, owner_phone, active_status, created_at, created_by, modified_at, modified_by
+    query := `SELECT id, name, species, breed, gender, birth_date, owner_name, owner_phone, owner_id, active_status, created_at, created_by, modified_at, modified_by
             FROM "Pets"
             WHERE owner_name=$1 AND owner_phone=$2 AND active_status=1`
 
@@ -230,7 +241,7 @@ func FetchPetsByOwner(c *gin.Context, db *sql.DB) {
         var pet structs.Pet
         if err := rows.Scan(
             &pet.Id, &pet.Name, &pet.Species, &pet.Breed, &pet.Gender,
-            &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone,
+            &pet.BirthDate, &pet.OwnerName, &pet.OwnerPhone, &pet.OwnerId,
             &pet.ActiveStatus, &pet.CreatedAt, &pet.CreatedBy,
             &pet.ModifiedAt, &pet.ModifiedBy,
         ); err != nil {
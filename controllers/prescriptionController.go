@@ -0,0 +1,420 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const prescriptionColumns = `id, medicalrecord_id, pet_id, doctor_id, drug_name, COALESCE(strength, ''), dose, route, frequency,
+                duration_days, quantity, COALESCE(unit, ''), COALESCE(instructions, ''), refills_allowed, refills_used, status,
+                prescribed_at, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanPrescription(row interface{ Scan(...interface{}) error }, p *structs.Prescription) error {
+    return row.Scan(
+        &p.Id, &p.MedicalRecordId, &p.PetId, &p.DoctorId, &p.DrugName, &p.Strength, &p.Dose, &p.Route, &p.Frequency,
+        &p.DurationDays, &p.Quantity, &p.Unit, &p.Instructions, &p.RefillsAllowed, &p.RefillsUsed, &p.Status,
+        &p.PrescribedAt, &p.ActiveStatus, &p.CreatedAt, &p.CreatedBy, &p.ModifiedAt, &p.ModifiedBy,
+    )
+}
+
+var prescriptionRoutes = map[string]bool{
+    "Oral": true, "Topical": true, "Otic": true, "Ophthalmic": true, "SC": true, "IM": true, "IV": true, "Other": true,
+}
+
+// checkPrescription returns an empty string when valid, otherwise the error message for the client
+func checkPrescription(p structs.Prescription) string {
+    if p.MedicalRecordId == uuid.Nil || p.DrugName == "" || p.Dose == "" || p.Frequency == "" {
+        return "MedicalRecordId, DrugName, Dose and Frequency are required"
+    }
+    if !prescriptionRoutes[p.Route] {
+        return "Route must be Oral, Topical, Otic, Ophthalmic, SC, IM, IV or Other"
+    }
+    if p.Quantity <= 0 {
+        return "Quantity must be positive"
+    }
+    if p.DurationDays != nil && *p.DurationDays <= 0 {
+        return "DurationDays must be positive"
+    }
+    if p.RefillsAllowed < 0 {
+        return "RefillsAllowed can't be negative"
+    }
+    return ""
+}
+
+func fetchDispenses(db *sql.DB, prescriptionId uuid.UUID) ([]structs.PrescriptionDispense, error) {
+    rows, err := db.Query(`SELECT id, prescription_id, quantity, is_refill, COALESCE(notes, ''), dispensed_at,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "PrescriptionDispenses"
+                        WHERE prescription_id=$1 AND active_status=1
+                        ORDER BY dispensed_at ASC`, prescriptionId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    dispenses := []structs.PrescriptionDispense{}
+    for rows.Next() {
+        var d structs.PrescriptionDispense
+        if err := rows.Scan(
+            &d.Id, &d.PrescriptionId, &d.Quantity, &d.IsRefill, &d.Notes, &d.DispensedAt,
+            &d.ActiveStatus, &d.CreatedAt, &d.CreatedBy, &d.ModifiedAt, &d.ModifiedBy,
+        ); err != nil {
+            return nil, err
+        }
+        dispenses = append(dispenses, d)
+    }
+    return dispenses, nil
+}
+
+func CreatePrescription(c *gin.Context, db *sql.DB) {
+    var prescription structs.Prescription
+    if err := c.ShouldBindJSON(&prescription); err != nil {
+        log.Println("Error binding JSON for new Prescription:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    // Doctors prescribe in their own name, Admin enters the prescribing doctor
+    if role, _ := c.Get("role"); role == "Doctor" {
+        prescription.DoctorId, _ = uuid.Parse(createdBy)
+    }
+    if prescription.DoctorId == uuid.Nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "DoctorId is required"})
+        return
+    }
+    if msg := checkPrescription(prescription); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    var doctorRole string
+    err := db.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, prescription.DoctorId).Scan(&doctorRole)
+    if err != nil || doctorRole != "Doctor" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The prescribing doctor must be an active Doctor"})
+        return
+    }
+
+    var signed bool
+    err = db.QueryRow(`SELECT pet_id, signed_at IS NOT NULL FROM "MedicalRecords" WHERE id=$1 AND active_status=1`,
+        prescription.MedicalRecordId).Scan(&prescription.PetId, &signed)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    }
+    if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, prescriptions can't be added"})
+        return
+    }
+
+    prescription.Id = uuid.New()
+    prescription.RefillsUsed = 0
+    prescription.Status = "Active"
+    prescription.ActiveStatus = 1
+    prescription.CreatedAt = time.Now()
+    prescription.PrescribedAt = prescription.CreatedAt
+    prescription.CreatedBy = createdBy
+    prescription.ModifiedAt = prescription.CreatedAt
+    prescription.ModifiedBy = createdBy
+
+    query := `INSERT INTO "Prescriptions"
+        (id, medicalrecord_id, pet_id, doctor_id, drug_name, strength, dose, route, frequency,
+        duration_days, quantity, unit, instructions, refills_allowed, refills_used, status,
+        prescribed_at, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22)`
+
+    _, err = db.Exec(query,
+        prescription.Id, prescription.MedicalRecordId, prescription.PetId, prescription.DoctorId,
+        prescription.DrugName, prescription.Strength, prescription.Dose, prescription.Route, prescription.Frequency,
+        prescription.DurationDays, prescription.Quantity, prescription.Unit, prescription.Instructions,
+        prescription.RefillsAllowed, prescription.RefillsUsed, prescription.Status, prescription.PrescribedAt,
+        prescription.ActiveStatus, prescription.CreatedAt, prescription.CreatedBy,
+        prescription.ModifiedAt, prescription.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting Prescription:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prescription"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, prescription)
+}
+
+func GetPrescription(c *gin.Context, db *sql.DB) {
+    var prescription structs.Prescription
+    err := scanPrescription(db.QueryRow(`SELECT `+prescriptionColumns+` FROM "Prescriptions" WHERE id=$1 AND active_status=1`,
+        c.Param("id")), &prescription)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+        return
+    }
+
+    prescription.Dispenses, err = fetchDispenses(db, prescription.Id)
+    if err != nil {
+        log.Println("Error fetching PrescriptionDispenses:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescription"})
+        return
+    }
+
+    c.JSON(http.StatusOK, prescription)
+}
+
+func listPrescriptions(c *gin.Context, db *sql.DB, where string, args ...interface{}) {
+    rows, err := db.Query(`SELECT `+prescriptionColumns+`
+                        FROM "Prescriptions"
+                        WHERE active_status=1 AND `+where+`
+                        ORDER BY prescribed_at DESC`, args...)
+    if err != nil {
+        log.Println("Error fetching prescriptions:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
+        return
+    }
+    defer rows.Close()
+
+    prescriptions := []structs.Prescription{}
+    for rows.Next() {
+        var p structs.Prescription
+        if err := scanPrescription(rows, &p); err != nil {
+            log.Println("Error scanning prescription row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse prescriptions"})
+            return
+        }
+        prescriptions = append(prescriptions, p)
+    }
+
+    c.JSON(http.StatusOK, prescriptions)
+}
+
+func GetPrescriptionsByMedicalRecordId(c *gin.Context, db *sql.DB) {
+    listPrescriptions(c, db, "medicalrecord_id=$1", c.Param("medicalrecord_id"))
+}
+
+// GetPetPrescriptions lists a pet's prescriptions, by default only the Active ones (?status=all for every status)
+func GetPetPrescriptions(c *gin.Context, db *sql.DB) {
+    status := c.DefaultQuery("status", "Active")
+    if status == "all" {
+        status = ""
+    }
+    listPrescriptions(c, db, "pet_id=$1 AND ($2='' OR status=$2)", c.Param("pet_id"), status)
+}
+
+// DispensePrescription records a fill. The first fill is the original, every later fill uses up a refill;
+// the prescription is Completed once the last allowed refill is dispensed.
+func DispensePrescription(c *gin.Context, db *sql.DB) {
+    prescriptionId := c.Param("id")
+
+    var req struct {
+        Quantity float64 `json:"quantity"` // defaults to the prescribed quantity
+        Notes    string  `json:"notes"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for DispensePrescription:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.Quantity < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity can't be negative"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for DispensePrescription:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispense prescription"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Lock the prescription so two counters can't use the same refill
+    var prescription structs.Prescription
+    err = scanPrescription(tx.QueryRow(`SELECT `+prescriptionColumns+` FROM "Prescriptions" WHERE id=$1 AND active_status=1 FOR UPDATE`,
+        prescriptionId), &prescription)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+        return
+    }
+    if prescription.Status != "Active" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Prescription is " + prescription.Status})
+        return
+    }
+
+    var fills int
+    if err := tx.QueryRow(`SELECT COUNT(*) FROM "PrescriptionDispenses" WHERE prescription_id=$1 AND active_status=1`,
+        prescription.Id).Scan(&fills); err != nil {
+        log.Println("Error counting PrescriptionDispenses:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispense prescription"})
+        return
+    }
+
+    dispense := structs.PrescriptionDispense{
+        Id:             uuid.New(),
+        PrescriptionId: prescription.Id,
+        Quantity:       req.Quantity,
+        IsRefill:       fills > 0,
+        Notes:          req.Notes,
+        ActiveStatus:   1,
+        CreatedAt:      time.Now(),
+        CreatedBy:      createdBy,
+    }
+    dispense.DispensedAt = dispense.CreatedAt
+    dispense.ModifiedAt = dispense.CreatedAt
+    dispense.ModifiedBy = createdBy
+    if dispense.Quantity == 0 {
+        dispense.Quantity = prescription.Quantity
+    }
+
+    if dispense.IsRefill {
+        if prescription.RefillsUsed >= prescription.RefillsAllowed {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No refills left on this prescription"})
+            return
+        }
+        prescription.RefillsUsed++
+    }
+    if prescription.RefillsUsed >= prescription.RefillsAllowed {
+        prescription.Status = "Completed"
+    }
+
+    _, err = tx.Exec(`INSERT INTO "PrescriptionDispenses"
+                    (id, prescription_id, quantity, is_refill, notes, dispensed_at,
+                    active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`,
+        dispense.Id, dispense.PrescriptionId, dispense.Quantity, dispense.IsRefill, dispense.Notes, dispense.DispensedAt,
+        dispense.ActiveStatus, dispense.CreatedAt, dispense.CreatedBy, dispense.ModifiedAt, dispense.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting PrescriptionDispense:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispense prescription"})
+        return
+    }
+
+    _, err = tx.Exec(`UPDATE "Prescriptions" SET refills_used=$1, status=$2, modified_at=$3, modified_by=$4 WHERE id=$5`,
+        prescription.RefillsUsed, prescription.Status, dispense.CreatedAt, createdBy, prescription.Id)
+    if err != nil {
+        log.Println("Error updating Prescription refills:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispense prescription"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing PrescriptionDispense:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dispense prescription"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "dispense":          dispense,
+        "refills_used":      prescription.RefillsUsed,
+        "refills_remaining": prescription.RefillsAllowed - prescription.RefillsUsed,
+        "status":            prescription.Status,
+    })
+}
+
+// CancelPrescription stops an Active prescription, no further fills can be dispensed
+func CancelPrescription(c *gin.Context, db *sql.DB) {
+    prescriptionId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "Prescriptions"
+                        SET status='Cancelled', modified_at=$1, modified_by=$2
+                        WHERE id=$3 AND status='Active' AND active_status=1`, time.Now(), modifiedBy, prescriptionId)
+    if err != nil {
+        log.Println("Error cancelling Prescription:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel prescription"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Only active prescriptions can be cancelled"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Prescription cancelled successfully"})
+}
+
+// UpdatePrescriptionActiveStatus removes a prescription entered by mistake (never dispensed, record not signed)
+func UpdatePrescriptionActiveStatus(c *gin.Context, db *sql.DB) {
+    prescriptionId := c.Param("id")
+
+    var signed, dispensed bool
+    err := db.QueryRow(`SELECT m.signed_at IS NOT NULL,
+                            EXISTS (SELECT 1 FROM "PrescriptionDispenses" d WHERE d.prescription_id = p.id AND d.active_status=1)
+                        FROM "Prescriptions" p
+                        JOIN "MedicalRecords" m ON m.id = p.medicalrecord_id
+                        WHERE p.id=$1 AND p.active_status=1`, prescriptionId).Scan(&signed, &dispensed)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+        return
+    }
+    if signed || dispensed {
+        c.JSON(http.StatusConflict, gin.H{"error": "The medical record is signed or the prescription was dispensed, cancel it instead"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "Prescriptions"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err = db.Exec(query, time.Now(), modifiedBy, prescriptionId)
+    if err != nil {
+        log.Println("Error soft deleting Prescription:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate prescription"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             prescriptionId,
+        "deactivated_by": modifiedBy,
+        "message":        "Prescription deactivated successfully",
+    })
+}
@@ -0,0 +1,501 @@
+package controllers
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"vetclinic-rest-api/middleware"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const queueSelect = `SELECT q.id, q.appointment_id, q.pet_id, COALESCE(p.name, q.visitor_name, ''), COALESCE(q.visitor_name, ''),
+                q.kind, q.triage_priority, COALESCE(q.triage_notes, ''), q.status,
+                q.doctor_id, COALESCE(u.name, ''), q.resource_id, COALESCE(r.name, ''),
+                q.checked_in_at, q.called_at, q.completed_at,
+                q.active_status, q.created_at, q.created_by, q.modified_at, q.modified_by
+            FROM "QueueEntries" q
+            LEFT JOIN "Pets" p ON p.id = q.pet_id
+            LEFT JOIN "Users" u ON u.id = q.doctor_id
+            LEFT JOIN "Resources" r ON r.id = q.resource_id`
+
+func scanQueueEntry(row interface{ Scan(...interface{}) error }, q *structs.QueueEntry) error {
+    return row.Scan(
+        &q.Id, &q.AppointmentId, &q.PetId, &q.PetName, &q.VisitorName,
+        &q.Kind, &q.TriagePriority, &q.TriageNotes, &q.Status,
+        &q.DoctorId, &q.DoctorName, &q.ResourceId, &q.RoomName,
+        &q.CheckedInAt, &q.CalledAt, &q.CompletedAt,
+        &q.ActiveStatus, &q.CreatedAt, &q.CreatedBy, &q.ModifiedAt, &q.ModifiedBy,
+    )
+}
+
+func fetchQueueEntry(db *sql.DB, entryId interface{}) (structs.QueueEntry, error) {
+    var entry structs.QueueEntry
+    err := scanQueueEntry(db.QueryRow(queueSelect+` WHERE q.id=$1 AND q.active_status=1`, entryId), &entry)
+    return entry, err
+}
+
+// fetchQueueBoard lists today's waiting room: patients in a room first, then by triage priority and arrival
+func fetchQueueBoard(db *sql.DB, includeFinished bool) ([]structs.QueueEntry, error) {
+    now := time.Now().In(utils.ClinicLocation())
+    dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+    rows, err := db.Query(queueSelect+`
+            WHERE q.active_status=1 AND q.checked_in_at >= $1 AND q.checked_in_at < $2
+            AND ($3 OR q.status IN ('Waiting', 'InRoom'))
+            ORDER BY (q.status='InRoom') DESC, (q.status='Waiting') DESC,
+                q.triage_priority DESC, q.checked_in_at ASC`,
+        dayStart, dayStart.AddDate(0, 0, 1), includeFinished)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    board := []structs.QueueEntry{}
+    for rows.Next() {
+        var entry structs.QueueEntry
+        if err := scanQueueEntry(rows, &entry); err != nil {
+            return nil, err
+        }
+        board = append(board, entry)
+    }
+    return board, nil
+}
+
+// checkRoom validates the room a patient is sent to; returns an empty string when valid, otherwise the error message
+func checkRoom(db *sql.DB, resourceId uuid.UUID, entryId uuid.UUID) (string, int) {
+    var kind string
+    err := db.QueryRow(`SELECT kind FROM "Resources" WHERE id=$1 AND active_status=1`, resourceId).Scan(&kind)
+    if err != nil || kind != "Room" {
+        return "Room not found", http.StatusBadRequest
+    }
+    var occupied bool
+    err = db.QueryRow(`SELECT EXISTS (
+                        SELECT 1 FROM "QueueEntries"
+                        WHERE resource_id=$1 AND status='InRoom' AND active_status=1 AND id <> $2
+                    )`, resourceId, entryId).Scan(&occupied)
+    if err != nil {
+        log.Println("Error checking room occupancy:", err)
+        return "Failed to check room", http.StatusInternalServerError
+    }
+    if occupied {
+        return "Room is occupied", http.StatusConflict
+    }
+    return "", 0
+}
+
+// CheckInAppointment puts a pet with an appointment today into the waiting room
+func CheckInAppointment(c *gin.Context, db *sql.DB) {
+    appointmentId := c.Param("appointment_id")
+
+    var req struct {
+        TriagePriority int    `json:"triage_priority"`
+        TriageNotes    string `json:"triage_notes"`
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Println("Error binding JSON for CheckInAppointment:", err)
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+            return
+        }
+    }
+
+    // 1. Appointment must be pending and today
+    var (
+        petId               uuid.UUID
+        doctorId            *uuid.UUID
+        status              string
+        appointmentDatetime time.Time
+    )
+    err := db.QueryRow(`SELECT pet_id, doctor_id, status, appointment_datetime
+                        FROM "Appointments" WHERE id=$1 AND active_status=1`, appointmentId).Scan(
+        &petId, &doctorId, &status, &appointmentDatetime,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment not found"})
+        return
+    }
+    if status != "Pending" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only pending appointments can be checked in"})
+        return
+    }
+    today := time.Now().In(utils.ClinicLocation()).Format("2006-01-02")
+    if appointmentDatetime.In(utils.ClinicLocation()).Format("2006-01-02") != today {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Only today's appointments can be checked in"})
+        return
+    }
+
+    // 2. Default room: the one reserved for the appointment, if any
+    var roomId *uuid.UUID
+    err = db.QueryRow(`SELECT ar.resource_id FROM "AppointmentResources" ar
+                    JOIN "Resources" r ON r.id = ar.resource_id
+                    WHERE ar.appointment_id=$1 AND ar.active_status=1 AND r.kind='Room'
+                    LIMIT 1`, appointmentId).Scan(&roomId)
+    if err != nil && err != sql.ErrNoRows {
+        log.Println("Error fetching reserved room:", err)
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    // 3. Insert; the unique index rejects a second check-in of the same appointment
+    entryId := uuid.New()
+    now := time.Now()
+    result, err := db.Exec(`INSERT INTO "QueueEntries"
+                    (id, appointment_id, pet_id, kind, triage_priority, triage_notes, status,
+                    doctor_id, resource_id, checked_in_at,
+                    active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,$3,'Appointment',$4,$5,'Waiting',$6,$7,$8,1,$8,$9,$8,$9)
+                    ON CONFLICT DO NOTHING`,
+        entryId, appointmentId, petId, req.TriagePriority, req.TriageNotes, doctorId, roomId, now, createdBy,
+    )
+    if err != nil {
+        log.Println("Error inserting QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in appointment"})
+        return
+    }
+    if affected, _ := result.RowsAffected(); affected == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Appointment is already checked in"})
+        return
+    }
+
+    entry, err := fetchQueueEntry(db, entryId)
+    if err != nil {
+        log.Println("Error fetching QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue entry"})
+        return
+    }
+    services.PublishQueueEvent("CheckedIn", entry)
+
+    c.JSON(http.StatusCreated, entry)
+}
+
+// CreateWalkIn adds a patient without appointment, either a registered pet or just a visitor name
+func CreateWalkIn(c *gin.Context, db *sql.DB) {
+    var req struct {
+        PetId          *uuid.UUID `json:"pet_id"`
+        VisitorName    string     `json:"visitor_name"`
+        DoctorId       *uuid.UUID `json:"doctor_id"`
+        TriagePriority int        `json:"triage_priority"`
+        TriageNotes    string     `json:"triage_notes"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for CreateWalkIn:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.PetId == nil && req.VisitorName == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "PetId or VisitorName is required"})
+        return
+    }
+    if req.PetId != nil {
+        var petExists bool
+        err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Pets" WHERE id=$1 AND active_status=1)`, *req.PetId).Scan(&petExists)
+        if err != nil || !petExists {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+            return
+        }
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    entryId := uuid.New()
+    now := time.Now()
+    _, err := db.Exec(`INSERT INTO "QueueEntries"
+                    (id, pet_id, visitor_name, kind, triage_priority, triage_notes, status,
+                    doctor_id, checked_in_at,
+                    active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,NULLIF($3, ''),'WalkIn',$4,$5,'Waiting',$6,$7,1,$7,$8,$7,$8)`,
+        entryId, req.PetId, req.VisitorName, req.TriagePriority, req.TriageNotes, req.DoctorId, now, createdBy,
+    )
+    if err != nil {
+        log.Println("Error inserting walk-in QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add walk-in"})
+        return
+    }
+
+    entry, err := fetchQueueEntry(db, entryId)
+    if err != nil {
+        log.Println("Error fetching QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue entry"})
+        return
+    }
+    services.PublishQueueEvent("WalkIn", entry)
+
+    c.JSON(http.StatusCreated, entry)
+}
+
+// GetQueue returns today's waiting-room board (?all=true also lists finished entries)
+func GetQueue(c *gin.Context, db *sql.DB) {
+    board, err := fetchQueueBoard(db, c.Query("all") == "true")
+    if err != nil {
+        log.Println("Error fetching queue:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue"})
+        return
+    }
+
+    c.JSON(http.StatusOK, board)
+}
+
+// TriageQueueEntry re-prioritizes a waiting patient
+func TriageQueueEntry(c *gin.Context, db *sql.DB) {
+    entryId := c.Param("id")
+
+    var req struct {
+        TriagePriority *int    `json:"triage_priority"`
+        TriageNotes    *string `json:"triage_notes"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for TriageQueueEntry:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 1. Fetch existing entry
+    entry, err := fetchQueueEntry(db, entryId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Queue entry not found"})
+        return
+    }
+
+    // 2. Merge fields
+    if req.TriagePriority != nil {
+        entry.TriagePriority = *req.TriagePriority
+    }
+    if req.TriageNotes != nil {
+        entry.TriageNotes = *req.TriageNotes
+    }
+
+    // 3. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 4. Update query
+    _, err = db.Exec(`UPDATE "QueueEntries"
+                    SET triage_priority=$1, triage_notes=$2, modified_at=$3, modified_by=$4
+                    WHERE id=$5 AND active_status=1`,
+        entry.TriagePriority, entry.TriageNotes, time.Now(), modifiedBy, entryId)
+    if err != nil {
+        log.Println("Error updating QueueEntry triage:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update triage"})
+        return
+    }
+
+    entry, err = fetchQueueEntry(db, entryId)
+    if err != nil {
+        log.Println("Error fetching QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue entry"})
+        return
+    }
+    services.PublishQueueEvent("Triaged", entry)
+
+    c.JSON(http.StatusOK, entry)
+}
+
+// UpdateQueueEntryStatus moves a patient through the waiting room: Waiting -> InRoom (with a room) -> Done, or Left
+func UpdateQueueEntryStatus(c *gin.Context, db *sql.DB) {
+    entryId := c.Param("id")
+
+    var req struct {
+        Status     string     `json:"status"`
+        ResourceId *uuid.UUID `json:"resource_id"`
+        DoctorId   *uuid.UUID `json:"doctor_id"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateQueueEntryStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if req.Status != "Waiting" && req.Status != "InRoom" && req.Status != "Done" && req.Status != "Left" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be Waiting, InRoom, Done or Left"})
+        return
+    }
+
+    entry, err := fetchQueueEntry(db, entryId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Queue entry not found"})
+        return
+    }
+    if entry.Status == "Done" || entry.Status == "Left" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Queue entry is already closed"})
+        return
+    }
+
+    if req.ResourceId != nil {
+        entry.ResourceId = req.ResourceId
+    }
+    if req.DoctorId != nil {
+        entry.DoctorId = req.DoctorId
+    }
+    if req.Status == "InRoom" {
+        if entry.ResourceId == nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "ResourceId (room) is required"})
+            return
+        }
+        if msg, status := checkRoom(db, *entry.ResourceId, entry.Id); msg != "" {
+            c.JSON(status, gin.H{"error": msg})
+            return
+        }
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    now := time.Now()
+    _, err = db.Exec(`UPDATE "QueueEntries"
+                    SET status=$1, resource_id=$2, doctor_id=$3,
+                        called_at = CASE WHEN $1='InRoom' THEN COALESCE(called_at, $4) ELSE called_at END,
+                        completed_at = CASE WHEN $1 IN ('Done', 'Left') THEN $4 ELSE NULL END,
+                        modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND active_status=1`,
+        req.Status, entry.ResourceId, entry.DoctorId, now, modifiedBy, entryId)
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "queueentries_room_occupied_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Room is occupied"})
+        return
+    }
+    if err != nil {
+        log.Println("Error updating QueueEntry status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update queue status"})
+        return
+    }
+
+    entry, err = fetchQueueEntry(db, entryId)
+    if err != nil {
+        log.Println("Error fetching QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue entry"})
+        return
+    }
+    services.PublishQueueEvent("StatusChanged", entry)
+
+    c.JSON(http.StatusOK, entry)
+}
+
+func UpdateQueueEntryActiveStatus(c *gin.Context, db *sql.DB) {
+    entryId := c.Param("id")
+
+    entry, err := fetchQueueEntry(db, entryId)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Queue entry not found"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "QueueEntries"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err = db.Exec(query, time.Now(), modifiedBy, entryId)
+    if err != nil {
+        log.Println("Error soft deleting QueueEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate queue entry"})
+        return
+    }
+    entry.ActiveStatus = 0
+    services.PublishQueueEvent("Removed", entry)
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             entryId,
+        "deactivated_by": modifiedBy,
+        "message":        "Queue entry deactivated successfully",
+    })
+}
+
+// CreateQueueStreamTicket returns a short-lived ticket for GET /api/queue/stream?ticket=, so browsers don't
+// have to put their JWT in the URL
+func CreateQueueStreamTicket(c *gin.Context) {
+    ticket, err := middleware.NewStreamTicket(c.GetString("role"), c.GetString("user_id"))
+    if err != nil {
+        log.Println("Error generating queue stream ticket:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate ticket"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "ticket":     ticket,
+        "expires_in": int(middleware.StreamTicketTTL.Seconds()),
+        "stream_url": "/api/queue/stream?ticket=" + ticket,
+    })
+}
+
+// StreamQueue pushes the board as a "snapshot" event, then every change as a "queue" event (server-sent events)
+func StreamQueue(c *gin.Context, db *sql.DB) {
+    // Subscribe before reading the snapshot so no change falls in between;
+    // an event already part of the snapshot is simply applied twice
+    events := services.SubscribeQueue()
+    defer services.UnsubscribeQueue(events)
+
+    board, err := fetchQueueBoard(db, false)
+    if err != nil {
+        log.Println("Error fetching queue for stream:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch queue"})
+        return
+    }
+
+    // keeps proxies from closing an idle connection
+    heartbeat := time.NewTicker(time.Duration(utils.GetEnvPositiveInt("QUEUE_SSE_HEARTBEAT_SECONDS", 25)) * time.Second)
+    defer heartbeat.Stop()
+
+    c.Header("Cache-Control", "no-cache")
+    c.Header("X-Accel-Buffering", "no")
+    c.SSEvent("snapshot", board)
+    c.Writer.Flush()
+
+    c.Stream(func(w io.Writer) bool {
+        select {
+        case event := <-events:
+            c.SSEvent("queue", event)
+            return true
+        case <-heartbeat.C:
+            c.SSEvent("ping", time.Now().Unix())
+            return true
+        case <-c.Request.Context().Done():
+            return false
+        }
+    })
+}
@@ -0,0 +1,988 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const referralColumns = `id, direction, pet_id, referring_doctor_id, COALESCE(referring_doctor_name, ''),
+                source_clinic, destination_clinic, COALESCE(destination_contact, ''), reason, urgency, status,
+                medical_record_ids, COALESCE(notes, ''), COALESCE(package_sha256, ''), source_referral_id, exported_at, imported_at,
+                active_status, created_at, created_by, modified_at, modified_by`
+
+func scanReferral(row interface{ Scan(...interface{}) error }, r *structs.Referral) error {
+    r.MedicalRecordIds = []uuid.UUID{}
+    return row.Scan(
+        &r.Id, &r.Direction, &r.PetId, &r.ReferringDoctorId, &r.ReferringDoctorName,
+        &r.SourceClinic, &r.DestinationClinic, &r.DestinationContact, &r.Reason, &r.Urgency, &r.Status,
+        pq.Array(&r.MedicalRecordIds), &r.Notes, &r.PackageSha256, &r.SourceReferralId, &r.ExportedAt, &r.ImportedAt,
+        &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+    )
+}
+
+// referralStatusTransitions lists the statuses that can be set by hand from each status.
+// Outgoing referrals start as Draft and become Sent when exported, imported ones start as Received.
+var referralStatusTransitions = map[string][]string{
+    "Draft":    {"Cancelled"},
+    "Sent":     {"Accepted", "Declined", "Cancelled"},
+    "Received": {"Accepted", "Declined"},
+    "Accepted": {"Completed", "Cancelled"},
+}
+
+var referralUrgencies = map[string]bool{"Routine": true, "Urgent": true, "Emergency": true}
+
+const (
+    referralPackageFormat  = "vetclinic-referral"
+    referralPackageVersion = 1
+)
+
+// Packages hold a few PDFs and some JSON; the limits keep a hostile zip from filling memory
+const (
+    maxReferralPackageBytes  = 20 << 20
+    maxReferralUnpackedBytes = 50 << 20
+)
+
+// checkReferral returns an empty string when valid, otherwise the error message for the client
+func checkReferral(r structs.Referral) string {
+    if strings.TrimSpace(r.DestinationClinic) == "" || strings.TrimSpace(r.Reason) == "" {
+        return "DestinationClinic and Reason are required"
+    }
+    if !referralUrgencies[r.Urgency] {
+        return "Urgency must be Routine, Urgent or Emergency"
+    }
+    return ""
+}
+
+// checkReferralRecords makes sure every selected medical record is an active record of the pet
+func checkReferralRecords(db *sql.DB, petId uuid.UUID, recordIds []uuid.UUID) (string, error) {
+    if len(recordIds) == 0 {
+        return "", nil
+    }
+    seen := map[uuid.UUID]bool{}
+    for _, id := range recordIds {
+        if seen[id] {
+            return "MedicalRecordIds has duplicates", nil
+        }
+        seen[id] = true
+    }
+    var found int
+    err := db.QueryRow(`SELECT COUNT(*) FROM "MedicalRecords"
+                        WHERE id = ANY($1) AND pet_id=$2 AND active_status=1`, pq.Array(recordIds), petId).Scan(&found)
+    if err != nil {
+        return "", err
+    }
+    if found != len(recordIds) {
+        return "Every medical record must be an active record of the pet", nil
+    }
+    return "", nil
+}
+
+func CreateReferral(c *gin.Context, db *sql.DB) {
+    var referral structs.Referral
+    if err := c.ShouldBindJSON(&referral); err != nil {
+        log.Println("Error binding JSON for new Referral:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    if referral.Urgency == "" {
+        referral.Urgency = "Routine"
+    }
+    if referral.PetId == uuid.Nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "PetId is required"})
+        return
+    }
+    if msg := checkReferral(referral); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    if referral.MedicalRecordIds == nil {
+        referral.MedicalRecordIds = []uuid.UUID{}
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    // Doctors refer their own patients, Admin names the referring doctor
+    if role, _ := c.Get("role"); role == "Doctor" {
+        doctorId, _ := uuid.Parse(createdBy)
+        referral.ReferringDoctorId = &doctorId
+    }
+    if referral.ReferringDoctorId == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "ReferringDoctorId is required"})
+        return
+    }
+    if respondRuleViolation(c, services.CheckTreatingDoctor(db, *referral.ReferringDoctorId), "Failed to create referral") {
+        return
+    }
+
+    var petExists bool
+    if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Pets" WHERE id=$1 AND active_status=1)`, referral.PetId).Scan(&petExists); err != nil {
+        log.Println("Error checking Pet for referral:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral"})
+        return
+    }
+    if !petExists {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Pet not found"})
+        return
+    }
+    msg, err := checkReferralRecords(db, referral.PetId, referral.MedicalRecordIds)
+    if err != nil {
+        log.Println("Error checking referral medical records:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral"})
+        return
+    }
+    if msg != "" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+        return
+    }
+    profile, err := fetchClinicProfile(db)
+    if err != nil {
+        log.Println("Error fetching ClinicProfile for referral:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral"})
+        return
+    }
+
+    referral.Id = uuid.New()
+    referral.Direction = "Outgoing"
+    referral.Status = "Draft"
+    referral.SourceClinic = profile.Name
+    referral.ReferringDoctorName = userName(db, referral.ReferringDoctorId.String())
+    referral.DestinationClinic = strings.TrimSpace(referral.DestinationClinic)
+    referral.ActiveStatus = 1
+    referral.CreatedAt = time.Now()
+    referral.CreatedBy = createdBy
+    referral.ModifiedAt = referral.CreatedAt
+    referral.ModifiedBy = createdBy
+
+    _, err = db.Exec(`INSERT INTO "Referrals"
+        (id, direction, pet_id, referring_doctor_id, referring_doctor_name, source_clinic, destination_clinic,
+        destination_contact, reason, urgency, status, medical_record_ids, notes,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,NULLIF($8, ''),$9,$10,$11,$12,NULLIF($13, ''),$14,$15,$16,$17,$18)`,
+        referral.Id, referral.Direction, referral.PetId, referral.ReferringDoctorId, referral.ReferringDoctorName,
+        referral.SourceClinic, referral.DestinationClinic, referral.DestinationContact, referral.Reason,
+        referral.Urgency, referral.Status, pq.Array(referral.MedicalRecordIds), referral.Notes,
+        referral.ActiveStatus, referral.CreatedAt, referral.CreatedBy, referral.ModifiedAt, referral.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting new Referral:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, referral)
+}
+
+// listReferrals answers with the active referrals matching where, newest first
+func listReferrals(c *gin.Context, db *sql.DB, where string, args ...interface{}) {
+    rows, err := db.Query(`SELECT `+referralColumns+` FROM "Referrals"
+                    WHERE active_status=1 AND `+where+`
+                    ORDER BY created_at DESC`, args...)
+    if err != nil {
+        log.Println("Error fetching referrals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrals"})
+        return
+    }
+    defer rows.Close()
+
+    referrals := []structs.Referral{}
+    for rows.Next() {
+        var r structs.Referral
+        if err := scanReferral(rows, &r); err != nil {
+            log.Println("Error scanning referral row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse referrals"})
+            return
+        }
+        referrals = append(referrals, r)
+    }
+
+    c.JSON(http.StatusOK, referrals)
+}
+
+// GetReferrals lists referrals, optionally filtered by ?status= and ?direction=
+func GetReferrals(c *gin.Context, db *sql.DB) {
+    listReferrals(c, db, "($1='' OR status=$1) AND ($2='' OR direction=$2)", c.Query("status"), c.Query("direction"))
+}
+
+func GetReferralsByPet(c *gin.Context, db *sql.DB) {
+    listReferrals(c, db, "pet_id=$1", c.Param("pet_id"))
+}
+
+func GetReferral(c *gin.Context, db *sql.DB) {
+    var referral structs.Referral
+    err := scanReferral(db.QueryRow(`SELECT `+referralColumns+` FROM "Referrals"
+                    WHERE id=$1 AND active_status=1`, c.Param("id")), &referral)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, referral)
+}
+
+// UpdateReferral edits an outgoing referral while it is still a Draft
+func UpdateReferral(c *gin.Context, db *sql.DB) {
+    referralId := c.Param("id")
+
+    // 1. Fetch existing referral
+    var existing structs.Referral
+    err := scanReferral(db.QueryRow(`SELECT `+referralColumns+` FROM "Referrals"
+                    WHERE id=$1 AND active_status=1`, referralId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+        return
+    }
+    if existing.Status != "Draft" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only Draft referrals can be edited"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.Referral
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateReferral:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if req.DestinationClinic != "" {
+        existing.DestinationClinic = strings.TrimSpace(req.DestinationClinic)
+    }
+    if req.DestinationContact != "" {
+        existing.DestinationContact = req.DestinationContact
+    }
+    if req.Reason != "" {
+        existing.Reason = req.Reason
+    }
+    if req.Urgency != "" {
+        existing.Urgency = req.Urgency
+    }
+    if req.Notes != "" {
+        existing.Notes = req.Notes
+    }
+    if req.MedicalRecordIds != nil {
+        existing.MedicalRecordIds = req.MedicalRecordIds
+    }
+    if msg := checkReferral(existing); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+    msg, err := checkReferralRecords(db, existing.PetId, existing.MedicalRecordIds)
+    if err != nil {
+        log.Println("Error checking referral medical records:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update referral"})
+        return
+    }
+    if msg != "" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+        return
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "Referrals"
+                    SET destination_clinic=$1, destination_contact=NULLIF($2, ''), reason=$3, urgency=$4,
+                        notes=NULLIF($5, ''), medical_record_ids=$6, modified_at=$7, modified_by=$8
+                    WHERE id=$9 AND status='Draft' AND active_status=1`
+
+    result, err := db.Exec(updateQuery,
+        existing.DestinationClinic, existing.DestinationContact, existing.Reason, existing.Urgency,
+        existing.Notes, pq.Array(existing.MedicalRecordIds), time.Now(), modifiedBy, referralId,
+    )
+    if err != nil {
+        log.Println("Error updating Referral:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update referral"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Only Draft referrals can be edited"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Referral updated successfully"})
+}
+
+func UpdateReferralStatus(c *gin.Context, db *sql.DB) {
+    referralId := c.Param("id")
+
+    var req struct {
+        Status string `json:"status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateReferralStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    var current string
+    if err := db.QueryRow(`SELECT status FROM "Referrals" WHERE id=$1 AND active_status=1`, referralId).Scan(&current); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+        return
+    }
+    allowed := false
+    for _, next := range referralStatusTransitions[current] {
+        if next == req.Status {
+            allowed = true
+        }
+    }
+    if !allowed {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A " + current + " referral can't be set to " + req.Status})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "Referrals" SET status=$1, modified_at=$2, modified_by=$3
+                    WHERE id=$4 AND status=$5 AND active_status=1`, req.Status, time.Now(), modifiedBy, referralId, current)
+    if err != nil {
+        log.Println("Error updating Referral status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update referral status"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusConflict, gin.H{"error": "Referral status changed, reload and try again"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Referral status updated successfully"})
+}
+
+func UpdateReferralActiveStatus(c *gin.Context, db *sql.DB) {
+    referralId := c.Param("id")
+
+    var req struct {
+        ActiveStatus int `json:"active_status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateReferralActiveStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "Referrals" SET active_status=$1, modified_at=$2, modified_by=$3 WHERE id=$4`,
+        req.ActiveStatus, time.Now(), modifiedBy, referralId)
+    if err != nil {
+        log.Println("Error updating Referral active_status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Active status updated successfully"})
+}
+
+// fetchReferralTreatments lists the active treatments of a medical record, oldest first
+func fetchReferralTreatments(db *sql.DB, recordId uuid.UUID) ([]structs.Treatment, error) {
+    rows, err := db.Query(`SELECT id, medicalrecord_id, doctor_id, description, cost,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "Treatments"
+                        WHERE medicalrecord_id=$1 AND active_status=1
+                        ORDER BY created_at ASC`, recordId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    treatments := []structs.Treatment{}
+    for rows.Next() {
+        var t structs.Treatment
+        if err := rows.Scan(
+            &t.Id, &t.MedicalRecordId, &t.DoctorId, &t.Description, &t.Cost,
+            &t.ActiveStatus, &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
+        ); err != nil {
+            return nil, err
+        }
+        treatments = append(treatments, t)
+    }
+    return treatments, rows.Err()
+}
+
+// buildReferralLetterPDF is the cover letter of a package: who is referred, where to and why
+func buildReferralLetterPDF(db *sql.DB, referral structs.Referral, pet structs.Pet, records []structs.ReferralPackageRecord) (*utils.PDF, error) {
+    pdf, err := newClinicPDF(db, "Referral")
+    if err != nil {
+        return nil, err
+    }
+    writePetPDF(pdf, pet)
+
+    pdf.Heading("Referral", 12)
+    pdf.Field("To", referral.DestinationClinic)
+    if referral.DestinationContact != "" {
+        pdf.Field("Contact", referral.DestinationContact)
+    }
+    pdf.Field("Referring doctor", referral.ReferringDoctorName)
+    pdf.Field("Urgency", referral.Urgency)
+    pdf.Field("Reason", referral.Reason)
+    if referral.Notes != "" {
+        pdf.Field("Notes", referral.Notes)
+    }
+
+    pdf.Heading("Enclosed visits", 12)
+    if len(records) == 0 {
+        pdf.Paragraph("No medical records enclosed.", 10)
+        return pdf, nil
+    }
+    rows := make([][]string, 0, len(records))
+    for _, r := range records {
+        rows = append(rows, []string{
+            r.AppointmentDatetime.In(utils.ClinicLocation()).Format(pdfDateTime), r.AppointmentType,
+            r.MedicalRecord.Diagnosis, r.SummaryFile,
+        })
+    }
+    pdf.Table([]utils.PDFColumn{
+        {Title: "Date", Width: 100}, {Title: "Type", Width: 90}, {Title: "Diagnosis", Width: 150}, {Title: "File", Width: pdf.ContentWidth() - 340},
+    }, rows)
+    return pdf, nil
+}
+
+// buildReferralPackage writes the zip of an outgoing referral:
+// manifest.json (every other file with its SHA-256), manifest.sig (HMAC of manifest.json),
+// referral.json (referral, pet, records with SOAP, vitals, diagnoses, addenda and treatments),
+// referral-letter.pdf and one visit summary PDF per record
+func buildReferralPackage(db *sql.DB, referral structs.Referral) ([]byte, error) {
+    pet, err := fetchPDFPet(db, referral.PetId.String())
+    if err != nil {
+        return nil, err
+    }
+
+    type packageFile struct {
+        name    string
+        content []byte
+    }
+    files := []packageFile{}
+    records := []structs.ReferralPackageRecord{}
+    for i, recordId := range referral.MedicalRecordIds {
+        var record structs.MedicalRecord
+        err := scanMedicalRecord(db.QueryRow(`SELECT `+medicalRecordColumns+` FROM "MedicalRecords"
+                        WHERE id=$1 AND active_status=1`, recordId), &record)
+        if err == sql.ErrNoRows {
+            // deactivated after it was selected
+            continue
+        }
+        if err == nil {
+            err = loadSOAPDetails(db, &record)
+        }
+        if err == nil {
+            record.Addenda, err = fetchAddenda(db, record.Id)
+        }
+        if err != nil {
+            return nil, err
+        }
+        visit, _, err := fetchPDFVisit(db, record.AppointmentId.String())
+        if err != nil {
+            visit = pdfVisit{Datetime: record.CreatedAt}
+        }
+        treatments, err := fetchReferralTreatments(db, record.Id)
+        if err != nil {
+            return nil, err
+        }
+
+        summary, err := buildVisitSummaryPDF(db, visit, pet, &record)
+        if err != nil {
+            return nil, err
+        }
+        name := fmt.Sprintf("records/%02d-%s.pdf", i+1, visit.Datetime.In(utils.ClinicLocation()).Format("2006-01-02"))
+        files = append(files, packageFile{name, summary.Bytes()})
+        records = append(records, structs.ReferralPackageRecord{
+            AppointmentDatetime: visit.Datetime,
+            AppointmentType:     visit.TypeName,
+            DoctorName:          visit.DoctorName,
+            MedicalRecord:       record,
+            Treatments:          treatments,
+            SummaryFile:         name,
+        })
+    }
+
+    letter, err := buildReferralLetterPDF(db, referral, pet, records)
+    if err != nil {
+        return nil, err
+    }
+    data, err := json.MarshalIndent(structs.ReferralPackage{Referral: referral, Pet: pet, Records: records}, "", "  ")
+    if err != nil {
+        return nil, err
+    }
+    files = append([]packageFile{{"referral.json", data}, {"referral-letter.pdf", letter.Bytes()}}, files...)
+
+    manifest := structs.ReferralManifest{
+        Format:       referralPackageFormat,
+        Version:      referralPackageVersion,
+        ReferralId:   referral.Id,
+        SourceClinic: referral.SourceClinic,
+        CreatedAt:    time.Now(),
+        Files:        make([]structs.ReferralManifestFile, 0, len(files)),
+    }
+    for _, f := range files {
+        manifest.Files = append(manifest.Files, structs.ReferralManifestFile{Name: f.name, Sha256: utils.Sha256Hex(f.content), Size: len(f.content)})
+    }
+    manifestData, err := json.MarshalIndent(manifest, "", "  ")
+    if err != nil {
+        return nil, err
+    }
+    signature, err := utils.SignReferralPackage(manifestData)
+    if err != nil {
+        return nil, err
+    }
+    files = append([]packageFile{{"manifest.json", manifestData}, {"manifest.sig", []byte(signature)}}, files...)
+
+    var buf bytes.Buffer
+    zw := zip.NewWriter(&buf)
+    for _, f := range files {
+        w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: manifest.CreatedAt})
+        if err != nil {
+            return nil, err
+        }
+        if _, err := w.Write(f.content); err != nil {
+            return nil, err
+        }
+    }
+    if err := zw.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// ExportReferral downloads the signed package of an outgoing referral. The first export of a Draft sends it.
+func ExportReferral(c *gin.Context, db *sql.DB) {
+    referralId := c.Param("id")
+
+    var referral structs.Referral
+    err := scanReferral(db.QueryRow(`SELECT `+referralColumns+` FROM "Referrals"
+                    WHERE id=$1 AND active_status=1`, referralId), &referral)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+        return
+    }
+    if referral.Direction != "Outgoing" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Only outgoing referrals can be exported"})
+        return
+    }
+    if referral.Status != "Draft" && referral.Status != "Sent" {
+        c.JSON(http.StatusConflict, gin.H{"error": "A " + referral.Status + " referral can't be exported"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    pkg, err := buildReferralPackage(db, referral)
+    if err == utils.ErrNoSigningKey {
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Referral signing key is not configured"})
+        return
+    }
+    if err != nil {
+        log.Println("Error building referral package:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export referral"})
+        return
+    }
+
+    now := time.Now()
+    _, err = db.Exec(`UPDATE "Referrals"
+                    SET status='Sent', package_sha256=$1, exported_at=$2, modified_at=$2, modified_by=$3
+                    WHERE id=$4 AND status IN ('Draft', 'Sent') AND active_status=1`, utils.Sha256Hex(pkg), now, modifiedBy, referral.Id)
+    if err != nil {
+        log.Println("Error updating Referral after export:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export referral"})
+        return
+    }
+
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`,
+        strings.TrimSuffix(pdfFileName("referral", referral.DestinationClinic, now.In(utils.ClinicLocation()).Format("2006-01-02")), ".pdf")+".zip"))
+    c.Data(http.StatusOK, "application/zip", pkg)
+}
+
+// readReferralPackage unzips an uploaded package and checks the signature and every file hash.
+// It returns the manifest and the files listed in it, or the reason the package is rejected.
+func readReferralPackage(data []byte) (structs.ReferralManifest, map[string][]byte, string, error) {
+    var manifest structs.ReferralManifest
+    zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        return manifest, nil, "File is not a zip archive", nil
+    }
+
+    files := map[string][]byte{}
+    unpacked := 0
+    for _, f := range zr.File {
+        if f.FileInfo().IsDir() {
+            continue
+        }
+        if path.Clean(f.Name) != f.Name || strings.HasPrefix(f.Name, "/") || strings.HasPrefix(f.Name, "..") {
+            return manifest, nil, "Invalid file name in package: " + f.Name, nil
+        }
+        if _, dup := files[f.Name]; dup {
+            return manifest, nil, "Duplicate file in package: " + f.Name, nil
+        }
+        rc, err := f.Open()
+        if err != nil {
+            return manifest, nil, "Failed to read " + f.Name, nil
+        }
+        content, err := io.ReadAll(io.LimitReader(rc, int64(maxReferralUnpackedBytes-unpacked+1)))
+        rc.Close()
+        if err != nil {
+            return manifest, nil, "Failed to read " + f.Name, nil
+        }
+        unpacked += len(content)
+        if unpacked > maxReferralUnpackedBytes {
+            return manifest, nil, "Package is too large once unpacked", nil
+        }
+        files[f.Name] = content
+    }
+
+    manifestData, ok := files["manifest.json"]
+    if !ok {
+        return manifest, nil, "Package has no manifest.json", nil
+    }
+    signature, ok := files["manifest.sig"]
+    if !ok {
+        return manifest, nil, "Package is not signed", nil
+    }
+    valid, err := utils.VerifyReferralPackage(manifestData, strings.TrimSpace(string(signature)))
+    if err != nil {
+        return manifest, nil, "", err
+    }
+    if !valid {
+        return manifest, nil, "Package signature doesn't match, it was changed or signed with another key", nil
+    }
+    if err := json.Unmarshal(manifestData, &manifest); err != nil {
+        return manifest, nil, "Invalid manifest.json", nil
+    }
+    if manifest.Format != referralPackageFormat || manifest.Version != referralPackageVersion {
+        return manifest, nil, fmt.Sprintf("Unsupported package %s version %d", manifest.Format, manifest.Version), nil
+    }
+
+    listed := map[string][]byte{}
+    for _, entry := range manifest.Files {
+        if _, dup := listed[entry.Name]; dup {
+            return manifest, nil, "Duplicate file in the manifest: " + entry.Name, nil
+        }
+        content, ok := files[entry.Name]
+        if !ok {
+            return manifest, nil, "File listed in the manifest is missing: " + entry.Name, nil
+        }
+        if utils.Sha256Hex(content) != entry.Sha256 {
+            return manifest, nil, "File doesn't match the manifest: " + entry.Name, nil
+        }
+        listed[entry.Name] = content
+    }
+    if len(listed)+2 != len(files) {
+        return manifest, nil, "Package has files that aren't in the manifest", nil
+    }
+    if _, ok := listed["referral.json"]; !ok {
+        return manifest, nil, "Package has no referral.json", nil
+    }
+    return manifest, listed, "", nil
+}
+
+// ImportReferral: POST /api/referrals/import?pet_id= (multipart "file")
+// Ingests a package exported by another clinic as an Incoming referral in Received status. The pet is
+// ?pet_id= when given, otherwise the active pet with the same name, species and owner phone, otherwise a new pet.
+func ImportReferral(c *gin.Context, db *sql.DB) {
+    fileHeader, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "A referral package is required in the file field"})
+        return
+    }
+    if fileHeader.Size > maxReferralPackageBytes {
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Package must be 20 MB or smaller"})
+        return
+    }
+    file, err := fileHeader.Open()
+    if err != nil {
+        log.Println("Error opening referral package upload:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+    defer file.Close()
+    data, err := io.ReadAll(io.LimitReader(file, maxReferralPackageBytes+1))
+    if err != nil || len(data) > maxReferralPackageBytes {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+        return
+    }
+
+    manifest, files, reason, err := readReferralPackage(data)
+    if err == utils.ErrNoSigningKey {
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Referral signing key is not configured"})
+        return
+    }
+    if err != nil {
+        log.Println("Error reading referral package:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+        return
+    }
+    if reason != "" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": reason})
+        return
+    }
+    var pkg structs.ReferralPackage
+    if err := json.Unmarshal(files["referral.json"], &pkg); err != nil {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid referral.json"})
+        return
+    }
+    if strings.TrimSpace(pkg.Pet.Name) == "" || strings.TrimSpace(pkg.Referral.Reason) == "" {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Package has no pet or referral reason"})
+        return
+    }
+    if !referralUrgencies[pkg.Referral.Urgency] {
+        pkg.Referral.Urgency = "Routine"
+    }
+
+    if manifest.ReferralId == uuid.Nil {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Package manifest has no referral_id"})
+        return
+    }
+    sourceClinic := manifest.SourceClinic
+    if sourceClinic == "" {
+        sourceClinic = pkg.Referral.SourceClinic
+    }
+
+    // The same referral exported twice is a different zip, so it is recognized by the sending clinic's id
+    var imported bool
+    err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Referrals"
+                        WHERE direction='Incoming' AND lower(source_clinic)=lower($1) AND source_referral_id=$2 AND active_status=1)`,
+        sourceClinic, manifest.ReferralId).Scan(&imported)
+    if err != nil {
+        log.Println("Error checking imported Referrals:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+        return
+    }
+    if imported {
+        c.JSON(http.StatusConflict, gin.H{"error": "This referral was already imported"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for referral import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+        return
+    }
+    defer tx.Rollback()
+
+    now := time.Now()
+    var petId uuid.UUID
+    petCreated := false
+    if raw := c.Query("pet_id"); raw != "" {
+        err = tx.QueryRow(`SELECT id FROM "Pets" WHERE id::text=$1 AND active_status=1`, raw).Scan(&petId)
+        if err != nil {
+            c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Pet not found"})
+            return
+        }
+    } else {
+        err = tx.QueryRow(`SELECT id FROM "Pets"
+                        WHERE lower(name)=lower($1) AND lower(COALESCE(species, ''))=lower($2)
+                        AND owner_phone=$3 AND $3<>'' AND active_status=1
+                        ORDER BY created_at ASC LIMIT 1`, strings.TrimSpace(pkg.Pet.Name), pkg.Pet.Species, pkg.Pet.OwnerPhone).Scan(&petId)
+        if err == sql.ErrNoRows {
+            petId = uuid.New()
+            petCreated = true
+            _, err = tx.Exec(`INSERT INTO "Pets"
+                (id, name, species, breed, gender, birth_date, owner_name, owner_phone, owner_id,
+                active_status, created_at, created_by, modified_at, modified_by)
+                VALUES ($1,$2,$3,$4,$5,NULLIF($6, '')::date,$7,$8,
+                (SELECT id FROM "Owners" WHERE phone=$8 AND active_status=1),
+                1,$9,$10,$9,$10)`,
+                petId, strings.TrimSpace(pkg.Pet.Name), pkg.Pet.Species, pkg.Pet.Breed, pkg.Pet.Gender,
+                pkg.Pet.BirthDate, pkg.Pet.OwnerName, pkg.Pet.OwnerPhone, now, createdBy,
+            )
+        }
+        if err != nil {
+            log.Println("Error matching Pet for referral import:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+            return
+        }
+    }
+
+    referralId := uuid.New()
+    _, err = tx.Exec(`INSERT INTO "Referrals"
+        (id, direction, pet_id, referring_doctor_name, source_clinic, destination_clinic, destination_contact,
+        reason, urgency, status, notes, package_sha256, source_referral_id, imported_at,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,'Incoming',$2,NULLIF($3, ''),$4,$5,NULLIF($6, ''),$7,$8,'Received',NULLIF($9, ''),$10,$11,$12,1,$12,$13,$12,$13)`,
+        referralId, petId, pkg.Referral.ReferringDoctorName, sourceClinic, pkg.Referral.DestinationClinic,
+        pkg.Referral.DestinationContact, pkg.Referral.Reason, pkg.Referral.Urgency, pkg.Referral.Notes,
+        utils.Sha256Hex(data), manifest.ReferralId, now, createdBy,
+    )
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "referrals_incoming_source_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "This referral was already imported"})
+        return
+    }
+    if err != nil {
+        log.Println("Error inserting imported Referral:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+        return
+    }
+
+    for _, entry := range manifest.Files {
+        content := files[entry.Name]
+        mime := http.DetectContentType(content)
+        switch path.Ext(entry.Name) {
+        case ".json":
+            mime = "application/json"
+        case ".pdf":
+            mime = "application/pdf"
+        }
+        _, err = tx.Exec(`INSERT INTO "ReferralDocuments"
+            (id, referral_id, name, mime, content, sha256, active_status, created_at, created_by, modified_at, modified_by)
+            VALUES ($1,$2,$3,$4,$5,$6,1,$7,$8,$7,$8)`,
+            uuid.New(), referralId, entry.Name, mime, content, entry.Sha256, now, createdBy,
+        )
+        if err != nil {
+            log.Println("Error inserting ReferralDocument:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+            return
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing referral import:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import referral"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "id":            referralId,
+        "pet_id":        petId,
+        "pet_created":   petCreated,
+        "source_clinic": sourceClinic,
+        "records":       len(pkg.Records),
+        "documents":     len(manifest.Files),
+    })
+}
+
+func GetReferralDocuments(c *gin.Context, db *sql.DB) {
+    rows, err := db.Query(`SELECT id, referral_id, name, mime, octet_length(content), sha256,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "ReferralDocuments"
+                        WHERE referral_id=$1 AND active_status=1
+                        ORDER BY name ASC`, c.Param("id"))
+    if err != nil {
+        log.Println("Error fetching referral documents:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+        return
+    }
+    defer rows.Close()
+
+    documents := []structs.ReferralDocument{}
+    for rows.Next() {
+        var d structs.ReferralDocument
+        if err := rows.Scan(
+            &d.Id, &d.ReferralId, &d.Name, &d.Mime, &d.Size, &d.Sha256,
+            &d.ActiveStatus, &d.CreatedAt, &d.CreatedBy, &d.ModifiedAt, &d.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning referral document row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse documents"})
+            return
+        }
+        documents = append(documents, d)
+    }
+
+    c.JSON(http.StatusOK, documents)
+}
+
+// referralDocumentMimes are the document types served with their own Content-Type
+var referralDocumentMimes = map[string]bool{
+    "application/pdf":  true,
+    "application/json": true,
+    "image/png":        true,
+    "image/jpeg":       true,
+    "image/gif":        true,
+    "image/webp":       true,
+}
+
+func GetReferralDocument(c *gin.Context, db *sql.DB) {
+    var name, mime string
+    var content []byte
+    err := db.QueryRow(`SELECT name, mime, content FROM "ReferralDocuments"
+                        WHERE id=$1 AND referral_id=$2 AND active_status=1`, c.Param("document_id"), c.Param("id")).Scan(&name, &mime, &content)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+        return
+    }
+
+    // Documents come from another clinic: only known-safe types keep their type, everything is a download
+    // and browsers must not sniff it into something renderable (e.g. an HTML or SVG file with scripts)
+    if !referralDocumentMimes[mime] {
+        mime = "application/octet-stream"
+    }
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, strings.ReplaceAll(path.Base(name), `"`, "")))
+    c.Header("X-Content-Type-Options", "nosniff")
+    c.Data(http.StatusOK, mime, content)
+}
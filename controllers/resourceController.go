@@ -0,0 +1,445 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// allocateAppointmentResources reserves rooms/equipment inside the booking transaction.
+// Writes the error response and returns false when the appointment can't get its resources.
+func allocateAppointmentResources(c *gin.Context, tx *sql.Tx, appointmentId uuid.UUID, typeId *uuid.UUID, start time.Time, createdBy string) bool {
+    duration, err := services.AppointmentDuration(tx, typeId)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Appointment type not found"})
+        return false
+    }
+    err = services.AllocateResources(tx, appointmentId, typeId, start, duration, createdBy)
+    var unavailable *services.ResourceUnavailableError
+    if errors.As(err, &unavailable) {
+        c.JSON(http.StatusConflict, gin.H{"error": unavailable.Error()})
+        return false
+    }
+    if err != nil {
+        log.Println("Error allocating Appointment resources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reserve appointment resources"})
+        return false
+    }
+    return true
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+    if a == nil || b == nil {
+        return a == nil && b == nil
+    }
+    return *a == *b
+}
+
+func isValidResourceKind(kind string) bool {
+    return kind == "Room" || kind == "Equipment"
+}
+
+func CreateResource(c *gin.Context, db *sql.DB) {
+    var newResource structs.Resource
+    if err := c.ShouldBindJSON(&newResource); err != nil {
+        log.Println("Error binding JSON for new Resource:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    if newResource.Name == "" || newResource.Category == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Name and Category are required"})
+        return
+    }
+    if !isValidResourceKind(newResource.Kind) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Kind must be Room or Equipment"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    newResource.Id = uuid.New()
+    newResource.ActiveStatus = 1
+    newResource.CreatedAt = time.Now()
+    newResource.CreatedBy = createdBy
+    newResource.ModifiedAt = newResource.CreatedAt
+    newResource.ModifiedBy = createdBy
+
+    query := `INSERT INTO "Resources"
+        (id, name, kind, category, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`
+
+    _, err := db.Exec(query,
+        newResource.Id, newResource.Name, newResource.Kind, newResource.Category,
+        newResource.ActiveStatus, newResource.CreatedAt, newResource.CreatedBy, newResource.ModifiedAt, newResource.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting Resource:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create resource"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, newResource)
+}
+
+// GetResources lists the active resources, optionally filtered by ?category=
+func GetResources(c *gin.Context, db *sql.DB) {
+    category := c.Query("category")
+
+    query := `SELECT id, name, kind, category, active_status, created_at, created_by, modified_at, modified_by
+            FROM "Resources"
+            WHERE active_status=1 AND ($1='' OR category=$1)
+            ORDER BY category ASC, name ASC`
+
+    rows, err := db.Query(query, category)
+    if err != nil {
+        log.Println("Error fetching resources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch resources"})
+        return
+    }
+    defer rows.Close()
+
+    var resources []structs.Resource
+    for rows.Next() {
+        var r structs.Resource
+        if err := rows.Scan(
+            &r.Id, &r.Name, &r.Kind, &r.Category,
+            &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning resource row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse resources"})
+            return
+        }
+        resources = append(resources, r)
+    }
+
+    c.JSON(http.StatusOK, resources)
+}
+
+func UpdateResource(c *gin.Context, db *sql.DB) {
+    resourceId := c.Param("id")
+
+    // 1. Fetch existing resource
+    var existing structs.Resource
+    fetchQuery := `SELECT id, name, kind, category, active_status, created_at, created_by, modified_at, modified_by
+                    FROM "Resources"
+                    WHERE id=$1 AND active_status=1`
+
+    err := db.QueryRow(fetchQuery, resourceId).Scan(
+        &existing.Id, &existing.Name, &existing.Kind, &existing.Category,
+        &existing.ActiveStatus, &existing.CreatedAt, &existing.CreatedBy,
+        &existing.ModifiedAt, &existing.ModifiedBy,
+    )
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.Resource
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateResource:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields
+    if req.Name != "" {
+        existing.Name = req.Name
+    }
+    if req.Kind != "" {
+        if !isValidResourceKind(req.Kind) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Kind must be Room or Equipment"})
+            return
+        }
+        existing.Kind = req.Kind
+    }
+    if req.Category != "" {
+        existing.Category = req.Category
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "Resources"
+                    SET name=$1, kind=$2, category=$3, modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND active_status=1`
+
+    _, err = db.Exec(updateQuery,
+        existing.Name, existing.Kind, existing.Category, time.Now(), modifiedBy, resourceId,
+    )
+    if err != nil {
+        log.Println("Error updating Resource:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Resource updated successfully"})
+}
+
+func UpdateResourceActiveStatus(c *gin.Context, db *sql.DB) {
+    resourceId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "Resources"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, resourceId)
+    if err != nil {
+        log.Println("Error soft deleting Resource:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate resource"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             resourceId,
+        "deactivated_by": modifiedBy,
+        "message":        "Resource deactivated successfully",
+    })
+}
+
+func scanAppointmentResources(rows *sql.Rows) ([]structs.AppointmentResource, error) {
+    defer rows.Close()
+    allocations := []structs.AppointmentResource{}
+    for rows.Next() {
+        var ar structs.AppointmentResource
+        if err := rows.Scan(
+            &ar.Id, &ar.AppointmentId, &ar.ResourceId, &ar.ResourceName, &ar.Category,
+            &ar.StartsAt, &ar.EndsAt, &ar.ActiveStatus, &ar.CreatedAt, &ar.CreatedBy,
+        ); err != nil {
+            return nil, err
+        }
+        allocations = append(allocations, ar)
+    }
+    return allocations, nil
+}
+
+// GetResourceBookings shows the schedule of one room/machine for a clinic day (?date=YYYY-MM-DD, default today)
+func GetResourceBookings(c *gin.Context, db *sql.DB) {
+    resourceId := c.Param("id")
+
+    day := time.Now().In(utils.ClinicLocation())
+    if raw := c.Query("date"); raw != "" {
+        parsed, err := utils.ParseClinicDate(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, expected YYYY-MM-DD"})
+            return
+        }
+        day = parsed
+    }
+    dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, utils.ClinicLocation())
+    dayEnd := dayStart.AddDate(0, 0, 1)
+
+    rows, err := db.Query(`SELECT ar.id, ar.appointment_id, ar.resource_id, r.name, r.category,
+                            ar.starts_at, ar.ends_at, ar.active_status, ar.created_at, ar.created_by
+                        FROM "AppointmentResources" ar
+                        JOIN "Resources" r ON r.id = ar.resource_id
+                        JOIN "Appointments" a ON a.id = ar.appointment_id
+                        WHERE ar.resource_id=$1 AND ar.active_status=1
+                        AND a.active_status=1 AND a.status <> 'Cancelled'
+                        AND ar.starts_at < $3 AND ar.ends_at > $2
+                        ORDER BY ar.starts_at ASC`, resourceId, dayStart, dayEnd)
+    if err != nil {
+        log.Println("Error fetching resource bookings:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch resource bookings"})
+        return
+    }
+    bookings, err := scanAppointmentResources(rows)
+    if err != nil {
+        log.Println("Error scanning resource booking row:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse resource bookings"})
+        return
+    }
+
+    c.JSON(http.StatusOK, bookings)
+}
+
+// GetAppointmentResources lists the rooms/equipment reserved for an appointment
+func GetAppointmentResources(c *gin.Context, db *sql.DB) {
+    appointmentId := c.Param("id")
+
+    rows, err := db.Query(`SELECT ar.id, ar.appointment_id, ar.resource_id, r.name, r.category,
+                            ar.starts_at, ar.ends_at, ar.active_status, ar.created_at, ar.created_by
+                        FROM "AppointmentResources" ar
+                        JOIN "Resources" r ON r.id = ar.resource_id
+                        WHERE ar.appointment_id=$1 AND ar.active_status=1
+                        ORDER BY r.category ASC, r.name ASC`, appointmentId)
+    if err != nil {
+        log.Println("Error fetching appointment resources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch appointment resources"})
+        return
+    }
+    allocations, err := scanAppointmentResources(rows)
+    if err != nil {
+        log.Println("Error scanning appointment resource row:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse appointment resources"})
+        return
+    }
+
+    c.JSON(http.StatusOK, allocations)
+}
+
+// GetAppointmentTypeResources lists the resource requirements of an appointment type
+func GetAppointmentTypeResources(c *gin.Context, db *sql.DB) {
+    typeId := c.Param("id")
+
+    rows, err := db.Query(`SELECT id, appointment_type_id, category, quantity,
+                            active_status, created_at, created_by, modified_at, modified_by
+                        FROM "AppointmentTypeResources"
+                        WHERE appointment_type_id=$1 AND active_status=1
+                        ORDER BY category ASC`, typeId)
+    if err != nil {
+        log.Println("Error fetching appointment type resources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch resource requirements"})
+        return
+    }
+    defer rows.Close()
+
+    requirements := []structs.AppointmentTypeResource{}
+    for rows.Next() {
+        var r structs.AppointmentTypeResource
+        if err := rows.Scan(
+            &r.Id, &r.AppointmentTypeId, &r.Category, &r.Quantity,
+            &r.ActiveStatus, &r.CreatedAt, &r.CreatedBy, &r.ModifiedAt, &r.ModifiedBy,
+        ); err != nil {
+            log.Println("Error scanning appointment type resource row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse resource requirements"})
+            return
+        }
+        requirements = append(requirements, r)
+    }
+
+    c.JSON(http.StatusOK, requirements)
+}
+
+// SetAppointmentTypeResources replaces the resource requirements of an appointment type
+func SetAppointmentTypeResources(c *gin.Context, db *sql.DB) {
+    typeId := c.Param("id")
+
+    var req []structs.AppointmentTypeResource
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for SetAppointmentTypeResources:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+    seen := map[string]bool{}
+    for i := range req {
+        if req[i].Category == "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Category is required"})
+            return
+        }
+        if req[i].Quantity == 0 {
+            req[i].Quantity = 1
+        }
+        if req[i].Quantity < 0 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
+            return
+        }
+        if seen[req[i].Category] {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate category " + req[i].Category})
+            return
+        }
+        seen[req[i].Category] = true
+    }
+
+    var typeExists bool
+    err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "AppointmentTypes" WHERE id=$1 AND active_status=1)`, typeId).Scan(&typeExists)
+    if err != nil || !typeExists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Appointment type not found"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for SetAppointmentTypeResources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource requirements"})
+        return
+    }
+    defer tx.Rollback()
+
+    now := time.Now()
+    _, err = tx.Exec(`UPDATE "AppointmentTypeResources"
+                    SET active_status=0, modified_at=$1, modified_by=$2
+                    WHERE appointment_type_id=$3 AND active_status=1`, now, modifiedBy, typeId)
+    if err != nil {
+        log.Println("Error clearing AppointmentTypeResources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource requirements"})
+        return
+    }
+
+    parsedTypeId, _ := uuid.Parse(typeId)
+    for i := range req {
+        req[i].Id = uuid.New()
+        req[i].AppointmentTypeId = parsedTypeId
+        req[i].ActiveStatus = 1
+        req[i].CreatedAt = now
+        req[i].CreatedBy = modifiedBy
+        req[i].ModifiedAt = now
+        req[i].ModifiedBy = modifiedBy
+
+        _, err = tx.Exec(`INSERT INTO "AppointmentTypeResources"
+                        (id, appointment_type_id, category, quantity,
+                        active_status, created_at, created_by, modified_at, modified_by)
+                        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+            req[i].Id, req[i].AppointmentTypeId, req[i].Category, req[i].Quantity,
+            req[i].ActiveStatus, req[i].CreatedAt, req[i].CreatedBy, req[i].ModifiedAt, req[i].ModifiedBy,
+        )
+        if err != nil {
+            log.Println("Error inserting AppointmentTypeResource:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource requirements"})
+            return
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing SetAppointmentTypeResources:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update resource requirements"})
+        return
+    }
+
+    c.JSON(http.StatusOK, req)
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"database/sql"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// searchSources holds one SELECT per kind of clinical text, all with the same columns
+// (source, reference_id, medicalrecord_id, appointment_id, rank, document) and matched against the
+// "search" CTE. The document is only turned into a highlighted snippet for the rows of the page.
+var searchSources = map[string]string{
+    "diagnosis": `SELECT 'diagnosis', d.id, m.id, m.appointment_id, ts_rank(d.description_search, s.q), d.description
+                    FROM "MedicalRecordDiagnoses" d
+                    JOIN "MedicalRecords" m ON m.id = d.medicalrecord_id AND m.active_status=1, search s
+                    WHERE d.active_status=1 AND d.description_search @@ s.q
+                UNION ALL
+                SELECT 'diagnosis', m.id, m.id, m.appointment_id, ts_rank(m.diagnosis_search, s.q), m.diagnosis
+                    FROM "MedicalRecords" m, search s
+                    WHERE m.active_status=1 AND m.diagnosis_search @@ s.q
+                    AND NOT EXISTS (SELECT 1 FROM "MedicalRecordDiagnoses" d WHERE d.medicalrecord_id = m.id AND d.active_status=1)`,
+    "note": `SELECT 'note', m.id, m.id, m.appointment_id, ts_rank(m.notes_search, s.q),
+                    concat_ws(E'\n', m.subjective, m.objective, m.assessment, m.plan, m.notes)
+                    FROM "MedicalRecords" m, search s
+                    WHERE m.active_status=1 AND m.notes_search @@ s.q`,
+    "addendum": `SELECT 'addendum', ad.id, m.id, m.appointment_id, ts_rank(ad.content_search, s.q), ad.content
+                    FROM "MedicalRecordAddenda" ad
+                    JOIN "MedicalRecords" m ON m.id = ad.medicalrecord_id AND m.active_status=1, search s
+                    WHERE ad.active_status=1 AND ad.content_search @@ s.q`,
+    "treatment": `SELECT 'treatment', tr.id, m.id, m.appointment_id, ts_rank(tr.description_search, s.q), tr.description
+                    FROM "Treatments" tr
+                    JOIN "MedicalRecords" m ON m.id = tr.medicalrecord_id AND m.active_status=1, search s
+                    WHERE tr.active_status=1 AND tr.description_search @@ s.q`,
+}
+
+// searchOrder keeps the generated query stable
+var searchOrder = []string{"diagnosis", "note", "addendum", "treatment"}
+
+// Staff work the front desk and billing: they can find diagnoses and treatments, not the clinical notes
+var searchSourcesByRole = map[string][]string{
+    "Doctor": searchOrder,
+    "Admin":  searchOrder,
+    "Staff":  {"diagnosis", "treatment"},
+}
+
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" ... "`
+
+// SearchClinicalNotes: GET /api/search/clinical?q=&sources=&species=&doctor_id=&pet_id=&from=&to=&page=&page_size=
+// q uses web search syntax ("heart murmur", -puppy, murmur or arrhythmia). Best matches first, newest first on ties.
+func SearchClinicalNotes(c *gin.Context, db *sql.DB) {
+    q := strings.TrimSpace(c.Query("q"))
+    if len(q) < 2 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "q must be at least 2 characters"})
+        return
+    }
+
+    role, _ := c.Get("role")
+    roleName, _ := role.(string)
+    allowed := map[string]bool{}
+    for _, s := range searchSourcesByRole[roleName] {
+        allowed[s] = true
+    }
+
+    sources := searchSourcesByRole[roleName]
+    if raw := c.Query("sources"); raw != "" {
+        sources = nil
+        seen := map[string]bool{}
+        for _, s := range strings.Split(raw, ",") {
+            s = strings.TrimSpace(s)
+            if _, ok := searchSources[s]; !ok {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown source: " + s})
+                return
+            }
+            if !allowed[s] {
+                c.JSON(http.StatusForbidden, gin.H{"error": "Your role can't search " + s + " text"})
+                return
+            }
+            if !seen[s] {
+                seen[s] = true
+                sources = append(sources, s)
+            }
+        }
+    }
+    if len(sources) == 0 {
+        c.JSON(http.StatusForbidden, gin.H{"error": "Your role can't search clinical text"})
+        return
+    }
+
+    page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+    if err != nil || page < 1 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+        return
+    }
+    pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+    if err != nil || pageSize < 1 || pageSize > 200 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be between 1 and 200"})
+        return
+    }
+
+    selects := make([]string, 0, len(sources))
+    for _, s := range sources {
+        selects = append(selects, searchSources[s])
+    }
+    query := `WITH search AS (SELECT websearch_to_tsquery('english', $1) AS q)
+            SELECT h.source, h.reference_id, h.medicalrecord_id, h.appointment_id, a.pet_id, p.name, COALESCE(p.species, ''),
+                a.doctor_id, COALESCE(u.name, ''), a.appointment_datetime, h.rank,
+                ts_headline('english', h.document, (SELECT q FROM search), '` + searchHeadlineOptions + `'),
+                COUNT(*) OVER()
+            FROM (` + strings.Join(selects, "\n UNION ALL \n") + `) AS h (source, reference_id, medicalrecord_id, appointment_id, rank, document)
+            JOIN "Appointments" a ON a.id = h.appointment_id AND a.active_status=1
+            JOIN "Pets" p ON p.id = a.pet_id AND p.active_status=1
+            LEFT JOIN "Users" u ON u.id = a.doctor_id
+            WHERE 1=1`
+    args := []interface{}{q}
+
+    if species := strings.TrimSpace(c.Query("species")); species != "" {
+        args = append(args, species)
+        query += " AND lower(p.species) = lower($" + strconv.Itoa(len(args)) + ")"
+    }
+    for _, filter := range []struct{ param, column string }{{"doctor_id", "a.doctor_id"}, {"pet_id", "a.pet_id"}} {
+        raw := c.Query(filter.param)
+        if raw == "" {
+            continue
+        }
+        id, err := uuid.Parse(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter.param})
+            return
+        }
+        args = append(args, id)
+        query += " AND " + filter.column + " = $" + strconv.Itoa(len(args))
+    }
+    if raw := c.Query("from"); raw != "" {
+        from, _, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        args = append(args, from)
+        query += " AND a.appointment_datetime >= $" + strconv.Itoa(len(args))
+    }
+    if raw := c.Query("to"); raw != "" {
+        to, dateOnly, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        if dateOnly {
+            to = to.AddDate(0, 0, 1)
+        }
+        args = append(args, to)
+        query += " AND a.appointment_datetime < $" + strconv.Itoa(len(args))
+    }
+
+    args = append(args, pageSize, (page-1)*pageSize)
+    query += " ORDER BY h.rank DESC, a.appointment_datetime DESC, h.reference_id ASC LIMIT $" + strconv.Itoa(len(args)-1) +
+        " OFFSET $" + strconv.Itoa(len(args))
+
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        log.Println("Error searching clinical notes:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+        return
+    }
+    defer rows.Close()
+
+    total := 0
+    hits := []structs.ClinicalSearchHit{}
+    for rows.Next() {
+        var h structs.ClinicalSearchHit
+        if err := rows.Scan(
+            &h.Source, &h.ReferenceId, &h.MedicalRecordId, &h.AppointmentId, &h.PetId, &h.PetName, &h.Species,
+            &h.DoctorId, &h.DoctorName, &h.AppointmentDatetime, &h.Rank, &h.Headline, &total,
+        ); err != nil {
+            log.Println("Error scanning search row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse search results"})
+            return
+        }
+        // Notes are free text: escape them, keeping only the highlight tags
+        h.Headline = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>").Replace(html.EscapeString(h.Headline))
+        hits = append(hits, h)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "hits":      hits,
+        "sources":   sources,
+        "page":      page,
+        "page_size": pageSize,
+        "total":     total,
+    })
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const taxRateColumns = `id, category, name, rate, active_status, created_at, created_by, modified_at, modified_by`
+
+func scanTaxRate(row interface{ Scan(...interface{}) error }, t *structs.TaxRate) error {
+    return row.Scan(
+        &t.Id, &t.Category, &t.Name, &t.Rate, &t.ActiveStatus, &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
+    )
+}
+
+// checkTaxRate returns an empty string when valid, otherwise the error message for the client
+func checkTaxRate(t structs.TaxRate) string {
+    if t.Category == "" || t.Name == "" || t.Rate == nil {
+        return "Category, Name and Rate are required"
+    }
+    if *t.Rate < 0 || *t.Rate > 100 {
+        return "Rate must be a percent between 0 and 100"
+    }
+    return ""
+}
+
+// checkBillingCategory returns an empty string when the category has an active tax rate, otherwise the error message
+func checkBillingCategory(db *sql.DB, category string) string {
+    var exists bool
+    err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "TaxRates" WHERE lower(category)=lower($1) AND active_status=1)`, category).Scan(&exists)
+    if err != nil || !exists {
+        return "Billing category " + category + " has no tax rate, see /api/tax-rates"
+    }
+    return ""
+}
+
+func CreateTaxRate(c *gin.Context, db *sql.DB) {
+    var rate structs.TaxRate
+    if err := c.ShouldBindJSON(&rate); err != nil {
+        log.Println("Error binding JSON for new TaxRate:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    rate.Category = strings.TrimSpace(rate.Category)
+    rate.Name = strings.TrimSpace(rate.Name)
+    if msg := checkTaxRate(rate); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    rate.Id = uuid.New()
+    rate.ActiveStatus = 1
+    rate.CreatedAt = time.Now()
+    rate.CreatedBy = createdBy
+    rate.ModifiedAt = rate.CreatedAt
+    rate.ModifiedBy = createdBy
+
+    query := `INSERT INTO "TaxRates"
+        (id, category, name, rate, active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`
+
+    _, err := db.Exec(query,
+        rate.Id, rate.Category, rate.Name, rate.Rate,
+        rate.ActiveStatus, rate.CreatedAt, rate.CreatedBy, rate.ModifiedAt, rate.ModifiedBy,
+    )
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "taxrates_category_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Category " + rate.Category + " already has a tax rate"})
+        return
+    }
+    if err != nil {
+        log.Println("Error inserting TaxRate:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, rate)
+}
+
+func GetTaxRates(c *gin.Context, db *sql.DB) {
+    rows, err := db.Query(`SELECT ` + taxRateColumns + ` FROM "TaxRates"
+                    WHERE active_status=1
+                    ORDER BY category ASC`)
+    if err != nil {
+        log.Println("Error fetching tax rates:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tax rates"})
+        return
+    }
+    defer rows.Close()
+
+    rates := []structs.TaxRate{}
+    for rows.Next() {
+        var t structs.TaxRate
+        if err := scanTaxRate(rows, &t); err != nil {
+            log.Println("Error scanning tax rate row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse tax rates"})
+            return
+        }
+        rates = append(rates, t)
+    }
+
+    c.JSON(http.StatusOK, rates)
+}
+
+// UpdateTaxRate changes the name or rate of a category. Draft invoices take the new rate the next time
+// their totals are worked out, issued invoices keep the rate they were issued with.
+func UpdateTaxRate(c *gin.Context, db *sql.DB) {
+    rateId := c.Param("id")
+
+    // 1. Fetch existing rate
+    var existing structs.TaxRate
+    err := scanTaxRate(db.QueryRow(`SELECT `+taxRateColumns+` FROM "TaxRates" WHERE id=$1 AND active_status=1`, rateId), &existing)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+        return
+    }
+
+    // 2. Bind incoming JSON
+    var req structs.TaxRate
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateTaxRate:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // 3. Merge fields (the category is the key lines are matched on and doesn't change)
+    if name := strings.TrimSpace(req.Name); name != "" {
+        existing.Name = name
+    }
+    if req.Rate != nil {
+        existing.Rate = req.Rate
+    }
+    if msg := checkTaxRate(existing); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // 4. Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy := userIdVal.(string)
+
+    // 5. Update query
+    updateQuery := `UPDATE "TaxRates"
+                    SET name=$1, rate=$2, modified_at=$3, modified_by=$4
+                    WHERE id=$5 AND active_status=1`
+
+    _, err = db.Exec(updateQuery, existing.Name, existing.Rate, time.Now(), modifiedBy, existing.Id)
+    if err != nil {
+        log.Println("Error updating TaxRate:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tax rate"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Tax rate updated successfully"})
+}
+
+// UpdateTaxRateActiveStatus removes (or restores) a category; lines of a removed category are not taxed
+func UpdateTaxRateActiveStatus(c *gin.Context, db *sql.DB) {
+    rateId := c.Param("id")
+
+    var req struct {
+        ActiveStatus int `json:"active_status"`
+    }
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateTaxRateActiveStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    result, err := db.Exec(`UPDATE "TaxRates" SET active_status=$1, modified_at=$2, modified_by=$3
+                    WHERE id=$4`, req.ActiveStatus, time.Now(), modifiedBy, rateId)
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "taxrates_category_key" {
+        c.JSON(http.StatusConflict, gin.H{"error": "Category already has another tax rate"})
+        return
+    }
+    if err != nil {
+        log.Println("Error updating TaxRate active_status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update active status"})
+        return
+    }
+    if n, _ := result.RowsAffected(); n == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Tax rate not found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Active status updated successfully"})
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timelineSources holds one SELECT per event type, all with the same columns
+// (type, occurred_at, reference_id, appointment_id, medicalrecord_id, details) and filtered by pet ($1).
+// Every source joins straight from the pet's appointments (or referrals), so the whole feed is one query.
+// Audit timestamps are clinic wall-clock times and are read in the clinic timezone (clinic CTE, $2).
+var timelineSources = map[string]string{
+    "appointment": `SELECT 'appointment', a.appointment_datetime::timestamptz, a.id, a.id, m.id,
+                        jsonb_build_object('status', a.status, 'doctor_id', a.doctor_id, 'doctor_name', u.name,
+                            'appointment_type', t.name, 'notes', a.notes)
+                    FROM "Appointments" a
+                    LEFT JOIN "Users" u ON u.id = a.doctor_id
+                    LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                    LEFT JOIN "MedicalRecords" m ON m.appointment_id = a.id AND m.active_status=1
+                    WHERE a.pet_id=$1 AND a.active_status=1`,
+    "medical_record": `SELECT 'medical_record', m.created_at AT TIME ZONE (SELECT timezone FROM clinic), m.id, a.id, m.id,
+                        jsonb_build_object('diagnosis', m.diagnosis, 'doctor_id', a.doctor_id,
+                            'assessment', m.assessment, 'plan', m.plan, 'signed_at', m.signed_at)
+                    FROM "MedicalRecords" m
+                    JOIN "Appointments" a ON a.id = m.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND m.active_status=1`,
+    "vitals": `SELECT 'vitals', v.recorded_at, v.id, a.id, m.id,
+                        jsonb_build_object('weight_kg', v.weight_kg, 'temperature_c', v.temperature_c,
+                            'heart_rate', v.heart_rate, 'respiratory_rate', v.respiratory_rate,
+                            'capillary_refill_seconds', v.capillary_refill_seconds, 'mucous_membranes', v.mucous_membranes,
+                            'body_condition_score', v.body_condition_score, 'pain_score', v.pain_score)
+                    FROM "Vitals" v
+                    JOIN "MedicalRecords" m ON m.id = v.medicalrecord_id
+                    JOIN "Appointments" a ON a.id = m.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND m.active_status=1 AND v.active_status=1`,
+    "treatment": `SELECT 'treatment', tr.created_at AT TIME ZONE (SELECT timezone FROM clinic), tr.id, a.id, m.id,
+                        jsonb_build_object('description', tr.description, 'cost', tr.cost, 'doctor_id', tr.doctor_id)
+                    FROM "Treatments" tr
+                    JOIN "MedicalRecords" m ON m.id = tr.medicalrecord_id
+                    JOIN "Appointments" a ON a.id = m.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND m.active_status=1 AND tr.active_status=1`,
+    "prescription": `SELECT 'prescription', p.prescribed_at, p.id, a.id, m.id,
+                        jsonb_build_object('drug_name', p.drug_name, 'strength', p.strength, 'dose', p.dose, 'route', p.route,
+                            'frequency', p.frequency, 'status', p.status, 'refills_allowed', p.refills_allowed, 'refills_used', p.refills_used)
+                    FROM "Prescriptions" p
+                    JOIN "MedicalRecords" m ON m.id = p.medicalrecord_id
+                    JOIN "Appointments" a ON a.id = m.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND m.active_status=1 AND p.active_status=1`,
+    "lab_order": `SELECT 'lab_order', l.ordered_at, l.id, a.id, l.medicalrecord_id,
+                        jsonb_build_object('accession_number', l.accession_number, 'panel', l.panel, 'sample_type', l.sample_type,
+                            'status', l.status, 'resulted_at', l.resulted_at)
+                    FROM "LabOrders" l
+                    JOIN "Appointments" a ON a.id = l.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND l.active_status=1`,
+    "addendum": `SELECT 'addendum', ad.signed_at, ad.id, a.id, m.id,
+                        jsonb_build_object('content', ad.content, 'signed_by', ad.signed_by)
+                    FROM "MedicalRecordAddenda" ad
+                    JOIN "MedicalRecords" m ON m.id = ad.medicalrecord_id
+                    JOIN "Appointments" a ON a.id = m.appointment_id
+                    WHERE a.pet_id=$1 AND a.active_status=1 AND m.active_status=1 AND ad.active_status=1`,
+    "attachment": `SELECT 'attachment', d.created_at AT TIME ZONE (SELECT timezone FROM clinic), d.id, NULL::uuid, NULL::uuid,
+                        jsonb_build_object('name', d.name, 'mime', d.mime, 'referral_id', r.id,
+                            'source_clinic', r.source_clinic, 'direction', r.direction)
+                    FROM "ReferralDocuments" d
+                    JOIN "Referrals" r ON r.id = d.referral_id
+                    WHERE r.pet_id=$1 AND r.active_status=1 AND d.active_status=1`,
+}
+
+// timelineOrder keeps the generated query stable
+var timelineOrder = []string{"appointment", "medical_record", "vitals", "treatment", "prescription", "lab_order", "addendum", "attachment"}
+
+// attachments are referral documents, which only Doctors and Admins may open
+func timelineTypeVisible(role, eventType string) bool {
+    return eventType != "attachment" || role != "Staff"
+}
+
+// GetPetTimeline: GET /api/pets/:id/timeline?types=&from=&to=&page=&page_size=
+// Newest first. types is a comma separated list of event types, from/to are RFC3339 or YYYY-MM-DD (a date-only "to" is inclusive).
+func GetPetTimeline(c *gin.Context, db *sql.DB) {
+    petId := c.Param("id")
+
+    var exists bool
+    if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM "Pets" WHERE id=$1 AND active_status=1)`, petId).Scan(&exists); err != nil || !exists {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+        return
+    }
+
+    role := c.GetString("role")
+    var types []string
+    for _, t := range timelineOrder {
+        if timelineTypeVisible(role, t) {
+            types = append(types, t)
+        }
+    }
+    if raw := c.Query("types"); raw != "" {
+        types = nil
+        seen := map[string]bool{}
+        for _, t := range strings.Split(raw, ",") {
+            t = strings.TrimSpace(t)
+            if _, ok := timelineSources[t]; !ok {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + t})
+                return
+            }
+            if !timelineTypeVisible(role, t) {
+                c.JSON(http.StatusForbidden, gin.H{"error": "Attachments are only visible to Doctors and Admins"})
+                return
+            }
+            if !seen[t] {
+                seen[t] = true
+                types = append(types, t)
+            }
+        }
+    }
+
+    page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+    if err != nil || page < 1 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+        return
+    }
+    pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+    if err != nil || pageSize < 1 || pageSize > 200 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be between 1 and 200"})
+        return
+    }
+
+    sources := make([]string, 0, len(types))
+    for _, t := range types {
+        sources = append(sources, timelineSources[t])
+    }
+    query := `WITH clinic (timezone) AS (SELECT $2::text)
+            SELECT type, occurred_at, reference_id, appointment_id, medicalrecord_id, details, COUNT(*) OVER()
+            FROM (` + strings.Join(sources, "\n UNION ALL \n") + `) AS events (type, occurred_at, reference_id, appointment_id, medicalrecord_id, details)
+            WHERE 1=1`
+    args := []interface{}{petId, utils.ClinicTimezone()}
+
+    if raw := c.Query("from"); raw != "" {
+        from, _, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        args = append(args, from)
+        query += " AND occurred_at >= $" + strconv.Itoa(len(args))
+    }
+    if raw := c.Query("to"); raw != "" {
+        to, dateOnly, err := parseRangeBound(raw)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+            return
+        }
+        if dateOnly {
+            to = to.AddDate(0, 0, 1)
+        }
+        args = append(args, to)
+        query += " AND occurred_at < $" + strconv.Itoa(len(args))
+    }
+
+    args = append(args, pageSize, (page-1)*pageSize)
+    query += " ORDER BY occurred_at DESC, type ASC LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        log.Println("Error fetching pet timeline:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeline"})
+        return
+    }
+    defer rows.Close()
+
+    total := 0
+    events := []structs.TimelineEvent{}
+    for rows.Next() {
+        var e structs.TimelineEvent
+        var details []byte
+        if err := rows.Scan(&e.Type, &e.OccurredAt, &e.ReferenceId, &e.AppointmentId, &e.MedicalRecordId, &details, &total); err != nil {
+            log.Println("Error scanning timeline row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse timeline"})
+            return
+        }
+        e.Details = details
+        events = append(events, e)
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "events":    events,
+        "page":      page,
+        "page_size": pageSize,
+        "total":     total,
+    })
+}
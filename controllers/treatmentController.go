@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"log"
 	"net/http"
+	"strings"
 	"time"
+	"vetclinic-rest-api/services"
 	"vetclinic-rest-api/structs"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +25,30 @@ func CreateTreatment(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "MedicalRecordId, Description, and Cost are required"})
         return
     }
+    treatment.BillingCategory = strings.TrimSpace(treatment.BillingCategory)
+    if treatment.BillingCategory == "" {
+        treatment.BillingCategory = "Treatment"
+    }
+    if msg := checkBillingCategory(db, treatment.BillingCategory); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    // Doctors record their own treatments by default, Admin names the treating doctor
+    if role, _ := c.Get("role"); role == "Doctor" && treatment.DoctorId == uuid.Nil {
+        userIdVal, _ := c.Get("user_id")
+        treatment.DoctorId, _ = uuid.Parse(userIdVal.(string))
+    }
+    if respondRuleViolation(c, services.CheckTreatingDoctor(db, treatment.DoctorId), "Failed to create treatment") {
+        return
+    }
+    if signed, err := medicalRecordSigned(db, treatment.MedicalRecordId); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    } else if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, treatments can't be changed"})
+        return
+    }
 
     // Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
@@ -44,13 +70,26 @@ func CreateTreatment(c *gin.Context, db *sql.DB) {
     treatment.ModifiedBy = createdBy
 
     query := `INSERT INTO "Treatments"
-        (id, medicalrecord_id, doctor_id, description, cost,
+        (id, medicalrecord_id, doctor_id, description, cost, billing_category,
         active_status, created_at, created_by, modified_at, modified_by)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for new Treatment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create treatment"})
+        return
+    }
+    defer tx.Rollback()
+
+    // Lock the record so it can't be signed while the treatment is added
+    if !lockUnsignedMedicalRecord(c, tx, treatment.MedicalRecordId, "Medical record is signed, treatments can't be changed") {
+        return
+    }
 
-    _, err := db.Exec(query,
+    _, err = tx.Exec(query,
         treatment.Id, treatment.MedicalRecordId, treatment.DoctorId,
-        treatment.Description, treatment.Cost,
+        treatment.Description, treatment.Cost, treatment.BillingCategory,
         treatment.ActiveStatus, treatment.CreatedAt, treatment.CreatedBy,
         treatment.ModifiedAt, treatment.ModifiedBy,
     )
@@ -59,6 +98,17 @@ func CreateTreatment(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create treatment"})
         return
     }
+    if err := recordTreatmentVersion(tx, treatment.Id, createdBy, treatment.CreatedAt); err != nil {
+        log.Println("Error inserting TreatmentVersion:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create treatment"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing new Treatment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create treatment"})
+        return
+    }
 
     c.JSON(http.StatusCreated, treatment)
 }
@@ -66,7 +116,7 @@ func CreateTreatment(c *gin.Context, db *sql.DB) {
 func GetTreatmentsByMedicalRecordId(c *gin.Context, db *sql.DB) {
     recordId := c.Param("medicalrecord_id")
 
-    query := `SELECT id, medicalrecord_id, doctor_id, description, cost,
+    query := `SELECT id, medicalrecord_id, doctor_id, description, cost, billing_category,
             active_status, created_at, created_by, modified_at, modified_by
             FROM "Treatments"
             WHERE medicalrecord_id=$1 AND active_status=1
@@ -84,7 +134,7 @@ func GetTreatmentsByMedicalRecordId(c *gin.Context, db *sql.DB) {
     for rows.Next() {
         var t structs.Treatment
         if err := rows.Scan(
-            &t.Id, &t.MedicalRecordId, &t.DoctorId, &t.Description, &t.Cost,
+            &t.Id, &t.MedicalRecordId, &t.DoctorId, &t.Description, &t.Cost, &t.BillingCategory,
             &t.ActiveStatus, &t.CreatedAt, &t.CreatedBy, &t.ModifiedAt, &t.ModifiedBy,
         ); err != nil {
             log.Println("Error scanning treatment row:", err)
@@ -102,14 +152,14 @@ func UpdateTreatment(c *gin.Context, db *sql.DB) {
 
     // 1. Fetch existing treatment
     var existing structs.Treatment
-    fetchQuery := `SELECT id, medicalrecord_id, doctor_id, description, cost,
+    fetchQuery := `SELECT id, medicalrecord_id, doctor_id, description, cost, billing_category,
                     active_status, created_at, created_by, modified_at, modified_by
                     FROM "Treatments"
                     WHERE id=$1 AND active_status=1`
 
     err := db.QueryRow(fetchQuery, treatmentId).Scan(
         &existing.Id, &existing.MedicalRecordId, &existing.DoctorId,
-        &existing.Description, &existing.Cost, &existing.ActiveStatus,
+        &existing.Description, &existing.Cost, &existing.BillingCategory, &existing.ActiveStatus,
         &existing.CreatedAt, &existing.CreatedBy,
         &existing.ModifiedAt, &existing.ModifiedBy,
     )
@@ -117,6 +167,13 @@ func UpdateTreatment(c *gin.Context, db *sql.DB) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Treatment not found"})
         return
     }
+    if signed, err := medicalRecordSigned(db, existing.MedicalRecordId); err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Medical record not found"})
+        return
+    } else if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, treatments can't be changed"})
+        return
+    }
 
     // 2. Bind incoming JSON
     var req structs.Treatment
@@ -133,6 +190,13 @@ func UpdateTreatment(c *gin.Context, db *sql.DB) {
     if req.Cost != 0 {
         existing.Cost = req.Cost
     }
+    if category := strings.TrimSpace(req.BillingCategory); category != "" {
+        if msg := checkBillingCategory(db, category); msg != "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+            return
+        }
+        existing.BillingCategory = category
+    }
 
     // 4. Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
@@ -144,11 +208,24 @@ func UpdateTreatment(c *gin.Context, db *sql.DB) {
 
     // 5. Update query
     updateQuery := `UPDATE "Treatments"
-                    SET description=$1, cost=$2, modified_at=$3, modified_by=$4
-                    WHERE id=$5 AND active_status=1`
+                    SET description=$1, cost=$2, billing_category=$3, modified_at=$4, modified_by=$5
+                    WHERE id=$6 AND active_status=1`
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateTreatment:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update treatment"})
+        return
+    }
+    defer tx.Rollback()
 
-    _, err = db.Exec(updateQuery,
-        existing.Description, existing.Cost, time.Now(), modifiedBy, treatmentId,
+    if !lockUnsignedMedicalRecord(c, tx, existing.MedicalRecordId, "Medical record is signed, treatments can't be changed") {
+        return
+    }
+
+    now := time.Now()
+    _, err = tx.Exec(updateQuery,
+        existing.Description, existing.Cost, existing.BillingCategory, now, modifiedBy, treatmentId,
     )
     if err != nil {
         log.Println("Error updating Treatment:", err)
@@ -156,12 +233,40 @@ func UpdateTreatment(c *gin.Context, db *sql.DB) {
         return
     }
 
+    // 6. Every update is kept as a new version
+    if err := recordTreatmentVersion(tx, existing.Id, modifiedBy, now); err != nil {
+        log.Println("Error inserting TreatmentVersion:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update treatment"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing Treatment update:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update treatment"})
+        return
+    }
+
     c.JSON(http.StatusOK, gin.H{"message": "Treatment updated successfully"})
 }
 
 func UpdateTreatmentActiveStatus(c *gin.Context, db *sql.DB) {
     treatmentId := c.Param("id")
 
+    var recordId uuid.UUID
+    var signed bool
+    err := db.QueryRow(`SELECT m.id, m.signed_at IS NOT NULL
+                        FROM "Treatments" t
+                        JOIN "MedicalRecords" m ON m.id = t.medicalrecord_id
+                        WHERE t.id=$1`, treatmentId).Scan(&recordId, &signed)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Treatment not found"})
+        return
+    }
+    if signed {
+        c.JSON(http.StatusConflict, gin.H{"error": "Medical record is signed, treatments can't be changed"})
+        return
+    }
+
     // Get user_id from JWT
     userIdVal, exists := c.Get("user_id")
     if !exists {
@@ -178,12 +283,28 @@ func UpdateTreatmentActiveStatus(c *gin.Context, db *sql.DB) {
             SET active_status=0, modified_at=$1, modified_by=$2
             WHERE id=$3`
 
-    _, err := db.Exec(query, time.Now(), modifiedBy, treatmentId)
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for UpdateTreatmentActiveStatus:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate treatment"})
+        return
+    }
+    defer tx.Rollback()
+
+    if !lockUnsignedMedicalRecord(c, tx, recordId, "Medical record is signed, treatments can't be changed") {
+        return
+    }
+    _, err = tx.Exec(query, time.Now(), modifiedBy, treatmentId)
     if err != nil {
         log.Println("Error soft deleting Treatment:", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate treatment"})
         return
     }
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing Treatment deactivation:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate treatment"})
+        return
+    }
 
     c.JSON(http.StatusOK, gin.H{
         "id":             treatmentId,
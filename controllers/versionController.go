@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Snapshots keep the clinically relevant fields only; the shape must match the backfill in 13_versions.sql
+// and 32_treatment_version_billing_category.sql (uncoded diagnoses have no code key, so older versions compare cleanly)
+const medicalRecordSnapshot = `jsonb_build_object(
+        'diagnosis', m.diagnosis,
+        'notes', COALESCE(m.notes, ''),
+        'subjective', COALESCE(m.subjective, ''),
+        'objective', COALESCE(m.objective, ''),
+        'assessment', COALESCE(m.assessment, ''),
+        'plan', COALESCE(m.plan, ''),
+        'diagnoses', COALESCE((
+            SELECT jsonb_agg(jsonb_strip_nulls(jsonb_build_object('description', d.description, 'certainty', d.certainty,
+                    'is_primary', d.is_primary, 'code', c.code))
+                ORDER BY d.is_primary DESC, d.created_at ASC)
+            FROM "MedicalRecordDiagnoses" d
+            LEFT JOIN "DiagnosisCatalog" c ON c.id = d.diagnosis_catalog_id
+            WHERE d.medicalrecord_id = m.id AND d.active_status = 1
+        ), '[]'::jsonb)
+    )`
+
+const treatmentSnapshot = `jsonb_build_object('doctor_id', t.doctor_id, 'description', t.description, 'cost', t.cost,
+        'billing_category', t.billing_category)`
+
+// recordMedicalRecordVersion stores the current state of a record as its next version.
+// Runs in the transaction that changed the record, whose row lock keeps version numbers sequential.
+func recordMedicalRecordVersion(tx *sql.Tx, recordId uuid.UUID, createdBy string, now time.Time) error {
+    _, err := tx.Exec(`INSERT INTO "MedicalRecordVersions"
+                    (id, medicalrecord_id, version, snapshot, active_status, created_at, created_by, modified_at, modified_by)
+                    SELECT $1, m.id,
+                        COALESCE((SELECT MAX(v.version) FROM "MedicalRecordVersions" v WHERE v.medicalrecord_id = m.id), 0) + 1,
+                        `+medicalRecordSnapshot+`, 1, $3, $4, $3, $4
+                    FROM "MedicalRecords" m
+                    WHERE m.id=$2`, uuid.New(), recordId, now, createdBy)
+    return err
+}
+
+// recordTreatmentVersion stores the current state of a treatment as its next version
+func recordTreatmentVersion(tx *sql.Tx, treatmentId uuid.UUID, createdBy string, now time.Time) error {
+    _, err := tx.Exec(`INSERT INTO "TreatmentVersions"
+                    (id, treatment_id, version, snapshot, active_status, created_at, created_by, modified_at, modified_by)
+                    SELECT $1, t.id,
+                        COALESCE((SELECT MAX(v.version) FROM "TreatmentVersions" v WHERE v.treatment_id = t.id), 0) + 1,
+                        `+treatmentSnapshot+`, 1, $3, $4, $3, $4
+                    FROM "Treatments" t
+                    WHERE t.id=$2`, uuid.New(), treatmentId, now, createdBy)
+    return err
+}
+
+// versionSources maps the versioned entity to its table and foreign key column (never user input)
+var versionSources = map[string][2]string{
+    "medicalrecord": {`"MedicalRecordVersions"`, "medicalrecord_id"},
+    "treatment":     {`"TreatmentVersions"`, "treatment_id"},
+}
+
+func fetchVersions(db *sql.DB, entity string, entityId string) ([]structs.Version, error) {
+    source := versionSources[entity]
+    query := `SELECT v.id, v.` + source[1] + `, v.version, v.snapshot, COALESCE(u.name, v.created_by), v.created_at, v.created_by
+            FROM ` + source[0] + ` v
+            LEFT JOIN "Users" u ON u.id::text = v.created_by
+            WHERE v.` + source[1] + `=$1 AND v.active_status=1
+            ORDER BY v.version ASC`
+
+    rows, err := db.Query(query, entityId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    versions := []structs.Version{}
+    for rows.Next() {
+        var v structs.Version
+        var snapshot []byte
+        if err := rows.Scan(&v.Id, &v.EntityId, &v.Version, &snapshot, &v.AuthorName, &v.CreatedAt, &v.CreatedBy); err != nil {
+            return nil, err
+        }
+        v.Snapshot = snapshot
+        versions = append(versions, v)
+    }
+    return versions, nil
+}
+
+func getVersions(c *gin.Context, db *sql.DB, entity string) {
+    versions, err := fetchVersions(db, entity, c.Param("id"))
+    if err != nil {
+        log.Println("Error fetching versions:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+        return
+    }
+    if len(versions) == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No versions found"})
+        return
+    }
+
+    c.JSON(http.StatusOK, versions)
+}
+
+// getVersionDiff compares ?from= and ?to= version numbers (default: previous and latest)
+func getVersionDiff(c *gin.Context, db *sql.DB, entity string) {
+    entityId := c.Param("id")
+
+    all, err := fetchVersions(db, entity, entityId)
+    if err != nil {
+        log.Println("Error fetching versions:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+        return
+    }
+    if len(all) == 0 {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No versions found"})
+        return
+    }
+
+    latest := all[len(all)-1].Version
+    to := latest
+    from := latest - 1
+    if from < 1 {
+        from = 1
+    }
+    if raw := c.Query("from"); raw != "" {
+        if from, err = strconv.Atoi(raw); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from version"})
+            return
+        }
+    }
+    if raw := c.Query("to"); raw != "" {
+        if to, err = strconv.Atoi(raw); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to version"})
+            return
+        }
+    }
+
+    var fromVersion, toVersion *structs.Version
+    for i := range all {
+        if all[i].Version == from {
+            fromVersion = &all[i]
+        }
+        if all[i].Version == to {
+            toVersion = &all[i]
+        }
+    }
+    if fromVersion == nil || toVersion == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+        return
+    }
+
+    changes, err := utils.DiffSnapshots(fromVersion.Snapshot, toVersion.Snapshot)
+    if err != nil {
+        log.Println("Error diffing versions:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare versions"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "from":    fromVersion,
+        "to":      toVersion,
+        "changes": changes,
+    })
+}
+
+func GetMedicalRecordVersions(c *gin.Context, db *sql.DB) {
+    getVersions(c, db, "medicalrecord")
+}
+
+func GetMedicalRecordVersionDiff(c *gin.Context, db *sql.DB) {
+    getVersionDiff(c, db, "medicalrecord")
+}
+
+func GetTreatmentVersions(c *gin.Context, db *sql.DB) {
+    getVersions(c, db, "treatment")
+}
+
+func GetTreatmentVersionDiff(c *gin.Context, db *sql.DB) {
+    getVersionDiff(c, db, "treatment")
+}
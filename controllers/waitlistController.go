@@ -0,0 +1,376 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const waitlistColumns = `id, pet_id, preferred_doctor_id, to_char(date_from, 'YYYY-MM-DD'), to_char(date_to, 'YYYY-MM-DD'),
+            priority, COALESCE(notes, ''), status, offered_datetime, offered_doctor_id, offered_at, appointment_id,
+            active_status, created_at, created_by, modified_at, modified_by`
+
+func scanWaitlistEntry(row interface{ Scan(...interface{}) error }, w *structs.WaitlistEntry) error {
+    return row.Scan(
+        &w.Id, &w.PetId, &w.PreferredDoctorId, &w.DateFrom, &w.DateTo,
+        &w.Priority, &w.Notes, &w.Status, &w.OfferedDatetime, &w.OfferedDoctorId, &w.OfferedAt, &w.AppointmentId,
+        &w.ActiveStatus, &w.CreatedAt, &w.CreatedBy, &w.ModifiedAt, &w.ModifiedBy,
+    )
+}
+
+func CreateWaitlistEntry(c *gin.Context, db *sql.DB) {
+    var entry structs.WaitlistEntry
+    if err := c.ShouldBindJSON(&entry); err != nil {
+        log.Println("Error binding JSON for new Waitlist entry:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    if entry.PetId == uuid.Nil || entry.DateFrom == "" || entry.DateTo == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "PetId, DateFrom and DateTo are required"})
+        return
+    }
+    dateFrom, errFrom := time.Parse("2006-01-02", entry.DateFrom)
+    dateTo, errTo := time.Parse("2006-01-02", entry.DateTo)
+    if errFrom != nil || errTo != nil || dateTo.Before(dateFrom) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "DateFrom and DateTo must be YYYY-MM-DD and DateTo cannot be before DateFrom"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    entry.Id = uuid.New()
+    entry.Status = "Waiting"
+    entry.OfferedDatetime = nil
+    entry.OfferedDoctorId = nil
+    entry.OfferedAt = nil
+    entry.AppointmentId = nil
+    entry.ActiveStatus = 1
+    entry.CreatedAt = time.Now()
+    entry.CreatedBy = createdBy
+    entry.ModifiedAt = entry.CreatedAt
+    entry.ModifiedBy = createdBy
+
+    query := `INSERT INTO "Waitlist"
+        (id, pet_id, preferred_doctor_id, date_from, date_to, priority, notes, status,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`
+
+    _, err := db.Exec(query,
+        entry.Id, entry.PetId, entry.PreferredDoctorId, entry.DateFrom, entry.DateTo,
+        entry.Priority, entry.Notes, entry.Status,
+        entry.ActiveStatus, entry.CreatedAt, entry.CreatedBy, entry.ModifiedAt, entry.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting Waitlist entry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create waitlist entry"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, entry)
+}
+
+func GetWaitlist(c *gin.Context, db *sql.DB) {
+    status := c.DefaultQuery("status", "Waiting")
+
+    query := `SELECT ` + waitlistColumns + `
+            FROM "Waitlist"
+            WHERE status=$1 AND active_status=1
+            ORDER BY priority DESC, created_at ASC`
+
+    rows, err := db.Query(query, status)
+    if err != nil {
+        log.Println("Error fetching Waitlist:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+        return
+    }
+    defer rows.Close()
+
+    var entries []structs.WaitlistEntry
+    for rows.Next() {
+        var w structs.WaitlistEntry
+        if err := scanWaitlistEntry(rows, &w); err != nil {
+            log.Println("Error scanning Waitlist row:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse waitlist"})
+            return
+        }
+        entries = append(entries, w)
+    }
+
+    c.JSON(http.StatusOK, entries)
+}
+
+func UpdateWaitlistStatus(c *gin.Context, db *sql.DB) {
+    waitlistId := c.Param("id")
+    var req struct {
+        Status string `json:"status"`
+    }
+
+    if err := c.ShouldBindJSON(&req); err != nil {
+        log.Println("Error binding JSON for UpdateWaitlistStatus:", err)
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+        return
+    }
+
+    // Booked/Offered are set by the matcher and the convert action only
+    if req.Status != "Waiting" && req.Status != "Cancelled" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be Waiting or Cancelled"})
+        return
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    // Declining an offer puts the entry back in the queue and clears the offered slot, which goes to the next entry
+    query := `WITH previous AS (
+                SELECT id, offered_datetime, offered_appointment_id FROM "Waitlist"
+                WHERE id=$4 AND status IN ('Waiting', 'Offered') AND active_status=1
+                FOR UPDATE
+            )
+            UPDATE "Waitlist" w
+            SET status=$1, offer_expired_datetime=COALESCE(p.offered_datetime, w.offer_expired_datetime),
+                offered_datetime=NULL, offered_doctor_id=NULL, offered_at=NULL, offered_appointment_id=NULL,
+                modified_at=$2, modified_by=$3
+            FROM previous p
+            WHERE w.id = p.id
+            RETURNING p.offered_appointment_id`
+
+    var offeredAppointmentId *uuid.UUID
+    err := db.QueryRow(query, req.Status, time.Now(), modifiedBy, waitlistId).Scan(&offeredAppointmentId)
+    if err == sql.ErrNoRows {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Open waitlist entry not found"})
+        return
+    }
+    if err != nil {
+        log.Println("Error updating Waitlist status:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist status"})
+        return
+    }
+    if offeredAppointmentId != nil {
+        services.SlotFreed(offeredAppointmentId.String())
+    }
+
+    c.JSON(http.StatusOK, gin.H{"message": "Waitlist status updated successfully"})
+}
+
+// ConvertWaitlistEntry books the offered slot (or an explicit one) as a real appointment
+func ConvertWaitlistEntry(c *gin.Context, db *sql.DB) {
+    waitlistId := c.Param("id")
+
+    var req struct {
+        DoctorId            *uuid.UUID `json:"doctor_id"`
+        AppointmentTypeId   *uuid.UUID `json:"appointment_type_id"`
+        AppointmentDatetime *time.Time `json:"appointment_datetime"`
+        Notes               string     `json:"notes"`
+    }
+    if c.Request.ContentLength > 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            log.Println("Error binding JSON for ConvertWaitlistEntry:", err)
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON data"})
+            return
+        }
+    }
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    createdBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        log.Println("Error starting transaction for ConvertWaitlistEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert waitlist entry"})
+        return
+    }
+    defer tx.Rollback()
+
+    // 1. Lock the entry
+    var entry structs.WaitlistEntry
+    err = scanWaitlistEntry(tx.QueryRow(`SELECT `+waitlistColumns+`
+                    FROM "Waitlist"
+                    WHERE id=$1 AND status IN ('Waiting', 'Offered') AND active_status=1
+                    FOR UPDATE`, waitlistId), &entry)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Open waitlist entry not found"})
+        return
+    }
+
+    // 2. Resolve slot: request body wins, otherwise the offered slot
+    slotDatetime := entry.OfferedDatetime
+    if req.AppointmentDatetime != nil {
+        slotDatetime = req.AppointmentDatetime
+    }
+    doctorId := entry.OfferedDoctorId
+    if req.DoctorId != nil {
+        doctorId = req.DoctorId
+    }
+    if doctorId == nil {
+        doctorId = entry.PreferredDoctorId
+    }
+    if slotDatetime == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "No slot offered yet, AppointmentDatetime is required"})
+        return
+    }
+    if !slotDatetime.After(time.Now()) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "The slot has already passed"})
+        return
+    }
+    if doctorId == nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "No doctor offered or preferred, DoctorId is required"})
+        return
+    }
+
+    // The booking takes the type of the freed appointment, so it gets the same duration and resources
+    typeId := req.AppointmentTypeId
+    if typeId == nil {
+        err = tx.QueryRow(`SELECT a.appointment_type_id
+                        FROM "Waitlist" w
+                        JOIN "Appointments" a ON a.id = w.offered_appointment_id
+                        WHERE w.id=$1`, waitlistId).Scan(&typeId)
+        if err != nil && err != sql.ErrNoRows {
+            log.Println("Error fetching offered Appointment type:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert waitlist entry"})
+            return
+        }
+    }
+    if msg := checkAppointmentType(db, typeId, *doctorId); msg != "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+        return
+    }
+
+    notes := req.Notes
+    if notes == "" {
+        notes = entry.Notes
+    }
+
+    // Serialize bookings of the same doctor, as new bookings do
+    if _, err := tx.Exec(`SELECT id FROM "Users" WHERE id=$1 FOR UPDATE`, *doctorId); err != nil {
+        log.Println("Error locking doctor for ConvertWaitlistEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert waitlist entry"})
+        return
+    }
+    if !doctorAvailable(c, tx, *doctorId, typeId, *slotDatetime, nil) {
+        return
+    }
+
+    // 3. Create the appointment
+    now := time.Now()
+    appointment := structs.Appointment{
+        Id:                  uuid.New(),
+        PetId:               entry.PetId,
+        DoctorId:            *doctorId,
+        AppointmentTypeId:   typeId,
+        Status:              "Pending",
+        AppointmentDatetime: *slotDatetime,
+        Notes:               notes,
+        ActiveStatus:        1,
+        CreatedAt:           now,
+        CreatedBy:           createdBy,
+        ModifiedAt:          now,
+        ModifiedBy:          createdBy,
+    }
+
+    _, err = tx.Exec(`INSERT INTO "Appointments"
+        (id, pet_id, doctor_id, appointment_type_id, status, appointment_datetime, notes,
+        active_status, created_at, created_by, modified_at, modified_by)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
+        appointment.Id, appointment.PetId, appointment.DoctorId, appointment.AppointmentTypeId, appointment.Status,
+        appointment.AppointmentDatetime, appointment.Notes, appointment.ActiveStatus,
+        appointment.CreatedAt, appointment.CreatedBy, appointment.ModifiedAt, appointment.ModifiedBy,
+    )
+    if err != nil {
+        log.Println("Error inserting Appointment from Waitlist:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create appointment"})
+        return
+    }
+    if !allocateAppointmentResources(c, tx, appointment.Id, appointment.AppointmentTypeId, appointment.AppointmentDatetime, createdBy) {
+        return
+    }
+
+    // 4. Close the entry
+    _, err = tx.Exec(`UPDATE "Waitlist"
+                    SET status='Booked', appointment_id=$1, offered_appointment_id=NULL, modified_at=$2, modified_by=$3
+                    WHERE id=$4`, appointment.Id, now, createdBy, waitlistId)
+    if err != nil {
+        log.Println("Error updating Waitlist entry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert waitlist entry"})
+        return
+    }
+
+    if err := tx.Commit(); err != nil {
+        log.Println("Error committing ConvertWaitlistEntry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert waitlist entry"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "waitlist_id": entry.Id,
+        "appointment": appointment,
+        "message":     "Waitlist entry converted to appointment successfully",
+    })
+}
+
+func UpdateWaitlistActiveStatus(c *gin.Context, db *sql.DB) {
+    waitlistId := c.Param("id")
+
+    // Get user_id from JWT
+    userIdVal, exists := c.Get("user_id")
+    if !exists {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+        return
+    }
+    modifiedBy, ok := userIdVal.(string)
+    if !ok {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID type"})
+        return
+    }
+
+    query := `UPDATE "Waitlist"
+            SET active_status=0, modified_at=$1, modified_by=$2
+            WHERE id=$3`
+
+    _, err := db.Exec(query, time.Now(), modifiedBy, waitlistId)
+    if err != nil {
+        log.Println("Error soft deleting Waitlist entry:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate waitlist entry"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "id":             waitlistId,
+        "deactivated_by": modifiedBy,
+        "message":        "Waitlist entry deactivated successfully",
+    })
+}
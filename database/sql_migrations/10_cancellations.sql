@@ -0,0 +1,51 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- CANCELLATION REASONS (structured catalog)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "CancellationReasons"
+(
+    id uuid NOT NULL,
+    name character varying(100) NOT NULL,
+    party character varying(10) NOT NULL, -- Owner, Clinic, Any
+    requires_note boolean NOT NULL DEFAULT false,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "CancellationReasons_pkey" PRIMARY KEY (id)
+);
+
+INSERT INTO "CancellationReasons" (id, name, party, requires_note, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000001', 'Pet recovered', 'Owner', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000002', 'Schedule conflict', 'Owner', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000003', 'Cost', 'Owner', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000004', 'Going to another clinic', 'Owner', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000005', 'Pet passed away', 'Owner', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000006', 'Doctor unavailable', 'Clinic', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000007', 'Clinic closed', 'Clinic', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000008', 'Room or equipment unavailable', 'Clinic', false, 1, NOW(), 'system', NOW(), 'system'),
+    ('9d4a3f5b-4a6e-4f8d-8b34-000000000009', 'Other', 'Any', true, 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
+
+---------------------------------------------------------
+-- APPOINTMENTS: cancellation details and no-shows
+---------------------------------------------------------
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS cancellation_reason_id uuid;
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS cancellation_note text;
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS cancelled_by_party character varying(10); -- Owner, Clinic
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS cancelled_at timestamp(0) with time zone;
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS late_cancellation boolean NOT NULL DEFAULT false;
+ALTER TABLE "Appointments" ADD COLUMN IF NOT EXISTS no_show_at timestamp(0) with time zone;
+
+ALTER TABLE "Appointments"
+    ADD CONSTRAINT appointments_cancellation_reason_id_to_cancellationreasons_id FOREIGN KEY (cancellation_reason_id)
+        REFERENCES "CancellationReasons" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS appointments_pet_id_status_idx
+    ON "Appointments" (pet_id, status)
+    WHERE active_status = 1;
@@ -0,0 +1,65 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- MEDICAL RECORDS: SOAP sections
+-- diagnosis/notes stay as they are, so existing records keep reading the same.
+-- For structured records diagnosis holds the primary diagnosis.
+---------------------------------------------------------
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS subjective text;
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS objective text;
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS assessment text;
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS plan text;
+
+---------------------------------------------------------
+-- VITALS (Objective, many measurements per medical record)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Vitals"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    weight_kg numeric(6,2),
+    temperature_c numeric(4,1),
+    heart_rate integer, -- beats per minute
+    respiratory_rate integer, -- breaths per minute
+    capillary_refill_seconds numeric(3,1),
+    mucous_membranes character varying(50),
+    body_condition_score integer, -- 1-9
+    pain_score integer, -- 0-10
+    recorded_at timestamp(0) with time zone NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Vitals_pkey" PRIMARY KEY (id),
+    CONSTRAINT vitals_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS vitals_medicalrecord_id_idx ON "Vitals" (medicalrecord_id);
+
+---------------------------------------------------------
+-- MEDICAL RECORD DIAGNOSES (Assessment, one or more per medical record)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "MedicalRecordDiagnoses"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    description text NOT NULL,
+    certainty character varying(20) NOT NULL DEFAULT 'Confirmed', -- Tentative, Confirmed, RuledOut
+    is_primary boolean NOT NULL DEFAULT false,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "MedicalRecordDiagnoses_pkey" PRIMARY KEY (id),
+    CONSTRAINT medicalrecorddiagnoses_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS medicalrecorddiagnoses_medicalrecord_id_idx ON "MedicalRecordDiagnoses" (medicalrecord_id);
@@ -0,0 +1,55 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- MEDICAL RECORDS: sign-off by the attending doctor, immutable afterwards
+---------------------------------------------------------
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS signed_at timestamp(0) with time zone;
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS signed_by character varying(50);
+
+---------------------------------------------------------
+-- MEDICAL RECORD ADDENDA (append-only, signed by their author)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "MedicalRecordAddenda"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    content text NOT NULL,
+    signed_at timestamp(0) with time zone NOT NULL,
+    signed_by character varying(50) NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "MedicalRecordAddenda_pkey" PRIMARY KEY (id),
+    CONSTRAINT medicalrecordaddenda_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS medicalrecordaddenda_medicalrecord_id_idx ON "MedicalRecordAddenda" (medicalrecord_id);
+
+---------------------------------------------------------
+-- MEDICAL RECORD CORRECTIONS (audit of Admin corrections on signed records)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "MedicalRecordCorrections"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    action character varying(20) NOT NULL, -- Deactivate
+    reason text NOT NULL,
+    snapshot jsonb NOT NULL, -- the record as it was before the correction
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "MedicalRecordCorrections_pkey" PRIMARY KEY (id),
+    CONSTRAINT medicalrecordcorrections_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS medicalrecordcorrections_medicalrecord_id_idx ON "MedicalRecordCorrections" (medicalrecord_id);
@@ -0,0 +1,78 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- MEDICAL RECORD VERSIONS (immutable, one per create/update)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "MedicalRecordVersions"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    version integer NOT NULL,
+    snapshot jsonb NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "MedicalRecordVersions_pkey" PRIMARY KEY (id),
+    CONSTRAINT medicalrecordversions_medicalrecord_id_version_key UNIQUE (medicalrecord_id, version),
+    CONSTRAINT medicalrecordversions_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+---------------------------------------------------------
+-- TREATMENT VERSIONS (immutable, one per create/update)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "TreatmentVersions"
+(
+    id uuid NOT NULL,
+    treatment_id uuid NOT NULL,
+    version integer NOT NULL,
+    snapshot jsonb NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "TreatmentVersions_pkey" PRIMARY KEY (id),
+    CONSTRAINT treatmentversions_treatment_id_version_key UNIQUE (treatment_id, version),
+    CONSTRAINT treatmentversions_treatment_id_to_treatments_id FOREIGN KEY (treatment_id)
+        REFERENCES "Treatments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+---------------------------------------------------------
+-- Backfill: the current state of existing rows is version 1
+-- (snapshot shape must match the one built in controllers/versionController.go)
+---------------------------------------------------------
+INSERT INTO "MedicalRecordVersions" (id, medicalrecord_id, version, snapshot, active_status, created_at, created_by, modified_at, modified_by)
+SELECT md5('medicalrecordversion:' || m.id::text)::uuid, m.id, 1,
+    jsonb_build_object(
+        'diagnosis', m.diagnosis,
+        'notes', COALESCE(m.notes, ''),
+        'subjective', COALESCE(m.subjective, ''),
+        'objective', COALESCE(m.objective, ''),
+        'assessment', COALESCE(m.assessment, ''),
+        'plan', COALESCE(m.plan, ''),
+        'diagnoses', COALESCE((
+            SELECT jsonb_agg(jsonb_build_object('description', d.description, 'certainty', d.certainty, 'is_primary', d.is_primary)
+                ORDER BY d.is_primary DESC, d.created_at ASC)
+            FROM "MedicalRecordDiagnoses" d
+            WHERE d.medicalrecord_id = m.id AND d.active_status = 1
+        ), '[]'::jsonb)
+    ),
+    1, COALESCE(m.modified_at, m.created_at), COALESCE(m.modified_by, m.created_by),
+    COALESCE(m.modified_at, m.created_at), COALESCE(m.modified_by, m.created_by)
+FROM "MedicalRecords" m
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "TreatmentVersions" (id, treatment_id, version, snapshot, active_status, created_at, created_by, modified_at, modified_by)
+SELECT md5('treatmentversion:' || t.id::text)::uuid, t.id, 1,
+    jsonb_build_object('doctor_id', t.doctor_id, 'description', t.description, 'cost', t.cost),
+    1, COALESCE(t.modified_at, t.created_at), COALESCE(t.modified_by, t.created_by),
+    COALESCE(t.modified_at, t.created_at), COALESCE(t.modified_by, t.created_by)
+FROM "Treatments" t
+ON CONFLICT DO NOTHING;
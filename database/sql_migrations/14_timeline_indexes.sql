@@ -0,0 +1,8 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- Indexes used by the pet timeline (pet -> appointments -> records -> treatments/vitals/addenda),
+-- appointments by pet are covered by appointments_pet_id_datetime_idx (6_appointment_timestamptz.sql)
+---------------------------------------------------------
+CREATE INDEX IF NOT EXISTS medicalrecords_appointment_id_idx ON "MedicalRecords" (appointment_id);
+CREATE INDEX IF NOT EXISTS treatments_medicalrecord_id_idx ON "Treatments" (medicalrecord_id);
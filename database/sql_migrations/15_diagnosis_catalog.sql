@@ -0,0 +1,35 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- DIAGNOSIS CATALOG (coded terminology)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "DiagnosisCatalog"
+(
+    id uuid NOT NULL,
+    code character varying(50) NOT NULL,
+    term character varying(255) NOT NULL,
+    synonyms text[] NOT NULL DEFAULT '{}',
+    species text[] NOT NULL DEFAULT '{}', -- empty = applies to every species
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "DiagnosisCatalog_pkey" PRIMARY KEY (id),
+    CONSTRAINT diagnosiscatalog_code_key UNIQUE (code)
+);
+
+CREATE INDEX IF NOT EXISTS diagnosiscatalog_term_idx ON "DiagnosisCatalog" (lower(term) text_pattern_ops);
+
+---------------------------------------------------------
+-- Medical record diagnoses can reference a catalog code next to the free text
+---------------------------------------------------------
+ALTER TABLE "MedicalRecordDiagnoses" ADD COLUMN IF NOT EXISTS diagnosis_catalog_id uuid;
+
+ALTER TABLE "MedicalRecordDiagnoses"
+    ADD CONSTRAINT medicalrecorddiagnoses_diagnosis_catalog_id_to_diagnosiscatalog_id FOREIGN KEY (diagnosis_catalog_id)
+        REFERENCES "DiagnosisCatalog" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS medicalrecorddiagnoses_diagnosis_catalog_id_idx ON "MedicalRecordDiagnoses" (diagnosis_catalog_id);
@@ -0,0 +1,72 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- PRESCRIPTIONS (many per medical record)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Prescriptions"
+(
+    id uuid NOT NULL,
+    medicalrecord_id uuid NOT NULL,
+    pet_id uuid NOT NULL,
+    doctor_id uuid NOT NULL, -- prescribing doctor
+    drug_name character varying(150) NOT NULL,
+    strength character varying(50), -- e.g. 250 mg
+    dose character varying(100) NOT NULL, -- e.g. 1 tablet
+    route character varying(30) NOT NULL, -- Oral, Topical, Otic, Ophthalmic, SC, IM, IV, Other
+    frequency character varying(100) NOT NULL, -- e.g. every 12 hours
+    duration_days integer,
+    quantity numeric(10,2) NOT NULL, -- per fill
+    unit character varying(30), -- tablets, ml, ...
+    instructions text,
+    refills_allowed integer NOT NULL DEFAULT 0,
+    refills_used integer NOT NULL DEFAULT 0,
+    status character varying(20) NOT NULL DEFAULT 'Active', -- Active, Completed, Cancelled
+    prescribed_at timestamp(0) with time zone NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Prescriptions_pkey" PRIMARY KEY (id),
+    CONSTRAINT prescriptions_refills_check CHECK (refills_used >= 0 AND refills_used <= refills_allowed),
+    CONSTRAINT prescriptions_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT prescriptions_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT prescriptions_doctor_id_to_users_id FOREIGN KEY (doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS prescriptions_pet_id_status_idx ON "Prescriptions" (pet_id, status);
+CREATE INDEX IF NOT EXISTS prescriptions_medicalrecord_id_idx ON "Prescriptions" (medicalrecord_id);
+
+---------------------------------------------------------
+-- PRESCRIPTION DISPENSES (first fill and refills)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "PrescriptionDispenses"
+(
+    id uuid NOT NULL,
+    prescription_id uuid NOT NULL,
+    quantity numeric(10,2) NOT NULL,
+    is_refill boolean NOT NULL DEFAULT false,
+    notes text,
+    dispensed_at timestamp(0) with time zone NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "PrescriptionDispenses_pkey" PRIMARY KEY (id),
+    CONSTRAINT prescriptiondispenses_prescription_id_to_prescriptions_id FOREIGN KEY (prescription_id)
+        REFERENCES "Prescriptions" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS prescriptiondispenses_prescription_id_idx ON "PrescriptionDispenses" (prescription_id);
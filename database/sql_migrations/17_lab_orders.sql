@@ -0,0 +1,77 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- LAB ORDERS (many per appointment)
+---------------------------------------------------------
+CREATE SEQUENCE IF NOT EXISTS lab_accession_seq;
+
+CREATE TABLE IF NOT EXISTS "LabOrders"
+(
+    id uuid NOT NULL,
+    accession_number character varying(20) NOT NULL DEFAULT ('L' || lpad(nextval('lab_accession_seq')::text, 7, '0')), -- printed on the sample, matched on import
+    appointment_id uuid NOT NULL,
+    medicalrecord_id uuid,
+    pet_id uuid NOT NULL,
+    doctor_id uuid NOT NULL, -- ordering doctor
+    panel character varying(100) NOT NULL, -- e.g. CBC, Chemistry 10, Urinalysis
+    sample_type character varying(50) NOT NULL, -- e.g. Whole blood (EDTA), Serum, Urine
+    status character varying(20) NOT NULL DEFAULT 'Ordered', -- Ordered, Collected, InProgress, Resulted, Cancelled
+    notes text,
+    ordered_at timestamp(0) with time zone NOT NULL,
+    collected_at timestamp(0) with time zone,
+    resulted_at timestamp(0) with time zone,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "LabOrders_pkey" PRIMARY KEY (id),
+    CONSTRAINT laborders_accession_number_key UNIQUE (accession_number),
+    CONSTRAINT laborders_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT laborders_medicalrecord_id_to_medicalrecords_id FOREIGN KEY (medicalrecord_id)
+        REFERENCES "MedicalRecords" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT laborders_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT laborders_doctor_id_to_users_id FOREIGN KEY (doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS laborders_pet_id_status_idx ON "LabOrders" (pet_id, status);
+CREATE INDEX IF NOT EXISTS laborders_appointment_id_idx ON "LabOrders" (appointment_id);
+
+---------------------------------------------------------
+-- LAB RESULTS (one row per analyte)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "LabResults"
+(
+    id uuid NOT NULL,
+    lab_order_id uuid NOT NULL,
+    analyte_code character varying(50) NOT NULL,
+    analyte character varying(100) NOT NULL,
+    value character varying(100) NOT NULL,
+    numeric_value numeric(14,4), -- filled when value is a number
+    unit character varying(30),
+    source character varying(10) NOT NULL DEFAULT 'Manual', -- Manual, CSV, HL7
+    observed_at timestamp(0) with time zone NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "LabResults_pkey" PRIMARY KEY (id),
+    CONSTRAINT labresults_lab_order_id_to_laborders_id FOREIGN KEY (lab_order_id)
+        REFERENCES "LabOrders" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS labresults_lab_order_id_idx ON "LabResults" (lab_order_id);
@@ -0,0 +1,34 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- LAB REFERENCE RANGES (by analyte, species and age band)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "LabReferenceRanges"
+(
+    id uuid NOT NULL,
+    analyte_code character varying(50) NOT NULL,
+    species character varying(50) NOT NULL,
+    age_min_months integer NOT NULL DEFAULT 0,
+    age_max_months integer, -- exclusive, NULL = no upper bound
+    unit character varying(30), -- NULL = any unit
+    low numeric(14,4),
+    high numeric(14,4),
+    critical_low numeric(14,4),
+    critical_high numeric(14,4),
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "LabReferenceRanges_pkey" PRIMARY KEY (id),
+    CONSTRAINT labreferenceranges_age_check CHECK (age_min_months >= 0 AND (age_max_months IS NULL OR age_max_months > age_min_months))
+);
+
+CREATE INDEX IF NOT EXISTS labreferenceranges_analyte_species_idx ON "LabReferenceRanges" (lower(analyte_code), lower(species));
+
+---------------------------------------------------------
+-- Results keep the range they were flagged against
+---------------------------------------------------------
+ALTER TABLE "LabResults" ADD COLUMN IF NOT EXISTS reference_low numeric(14,4);
+ALTER TABLE "LabResults" ADD COLUMN IF NOT EXISTS reference_high numeric(14,4);
+ALTER TABLE "LabResults" ADD COLUMN IF NOT EXISTS flag character varying(10); -- Normal, Low, High, Critical (NULL = no range)
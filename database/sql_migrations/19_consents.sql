@@ -0,0 +1,88 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- Appointment types that need a signed owner consent before they start
+---------------------------------------------------------
+ALTER TABLE "AppointmentTypes" ADD COLUMN IF NOT EXISTS requires_consent boolean NOT NULL DEFAULT false;
+
+-- Surgery and Dental (anesthesia)
+UPDATE "AppointmentTypes" SET requires_consent = true
+WHERE id IN ('6a1f0c2e-1d3b-4c5a-9e01-000000000003', '6a1f0c2e-1d3b-4c5a-9e01-000000000004');
+
+---------------------------------------------------------
+-- CONSENT TEMPLATES (body with {{placeholders}})
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "ConsentTemplates"
+(
+    id uuid NOT NULL,
+    name character varying(100) NOT NULL,
+    appointment_type_id uuid, -- default template for this type
+    body text NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "ConsentTemplates_pkey" PRIMARY KEY (id),
+    CONSTRAINT consenttemplates_appointment_type_id_to_appointmenttypes_id FOREIGN KEY (appointment_type_id)
+        REFERENCES "AppointmentTypes" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+---------------------------------------------------------
+-- CONSENTS (one rendered form per appointment, signed by the owner)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Consents"
+(
+    id uuid NOT NULL,
+    appointment_id uuid NOT NULL,
+    consent_template_id uuid NOT NULL,
+    procedure character varying(200) NOT NULL,
+    estimate integer NOT NULL DEFAULT 0,
+    body text NOT NULL, -- rendered, never changes after generation
+    body_sha256 character varying(64) NOT NULL,
+    status character varying(20) NOT NULL DEFAULT 'Pending', -- Pending, Signed, Revoked
+    signer_name character varying(100),
+    signature_image bytea,
+    signature_mime character varying(20), -- image/png, image/jpeg
+    signed_at timestamp(0) with time zone,
+    signer_ip character varying(45),
+    revoked_at timestamp(0) with time zone,
+    revoked_reason text,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Consents_pkey" PRIMARY KEY (id),
+    CONSTRAINT consents_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT consents_consent_template_id_to_consenttemplates_id FOREIGN KEY (consent_template_id)
+        REFERENCES "ConsentTemplates" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS consents_appointment_id_idx ON "Consents" (appointment_id);
+
+INSERT INTO "ConsentTemplates"
+    (id, name, appointment_type_id, body, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('3c7e5b1a-8f2d-4e6c-a1b0-000000000001', 'Surgery and anesthesia consent', '6a1f0c2e-1d3b-4c5a-9e01-000000000003',
+     E'I, {{owner_name}}, am the owner (or authorized agent) of {{pet_name}} ({{species}}, {{breed}}).\n\n'
+     || E'I authorize {{doctor_name}} and the clinic staff to perform: {{procedure}} on {{date}}, including the anesthesia, '
+     || E'medication and procedures that are necessary in their professional judgement.\n\n'
+     || E'I understand that anesthesia and surgery carry risks, including death, and that no result can be guaranteed.\n\n'
+     || E'The estimated cost is {{estimate}}. I will be contacted before costs exceed this estimate, except in an emergency.',
+     1, NOW(), 'system', NOW(), 'system'),
+    ('3c7e5b1a-8f2d-4e6c-a1b0-000000000002', 'Dental procedure and anesthesia consent', '6a1f0c2e-1d3b-4c5a-9e01-000000000004',
+     E'I, {{owner_name}}, am the owner (or authorized agent) of {{pet_name}} ({{species}}, {{breed}}).\n\n'
+     || E'I authorize {{doctor_name}} and the clinic staff to perform: {{procedure}} on {{date}} under general anesthesia, '
+     || E'including extractions found necessary during the procedure.\n\n'
+     || E'I understand that anesthesia carries risks, including death.\n\n'
+     || E'The estimated cost is {{estimate}}. I will be contacted before costs exceed this estimate, except in an emergency.',
+     1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,30 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- CLINIC PROFILE (single row, branding of printed documents)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "ClinicProfile"
+(
+    id uuid NOT NULL,
+    name character varying(150) NOT NULL,
+    address text,
+    phone character varying(30),
+    email character varying(100),
+    website character varying(150),
+    footer_text character varying(300), -- printed at the bottom of every page
+    accent_color character varying(7) NOT NULL DEFAULT '#4A90D9',
+    logo bytea,
+    logo_mime character varying(20), -- image/png, image/jpeg
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "ClinicProfile_pkey" PRIMARY KEY (id)
+);
+
+INSERT INTO "ClinicProfile"
+    (id, name, accent_color, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('9b2d4f60-7c1e-4a3b-8d5f-000000000001', 'VetClinic', '#4A90D9', 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,33 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- Full-text search over clinical text (english stemming, kept up to date by Postgres)
+---------------------------------------------------------
+
+-- Diagnoses: the primary diagnosis column of records (the only one for records written before SOAP)
+-- and the coded/free-text diagnoses of SOAP records
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS diagnosis_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', COALESCE(diagnosis, ''))) STORED;
+
+ALTER TABLE "MedicalRecordDiagnoses" ADD COLUMN IF NOT EXISTS description_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', COALESCE(description, ''))) STORED;
+
+-- Clinical notes: assessment ranks above history/exam, which rank above plan and free notes
+ALTER TABLE "MedicalRecords" ADD COLUMN IF NOT EXISTS notes_search tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(assessment, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(subjective, '') || ' ' || COALESCE(objective, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(plan, '') || ' ' || COALESCE(notes, '')), 'C')
+    ) STORED;
+
+ALTER TABLE "MedicalRecordAddenda" ADD COLUMN IF NOT EXISTS content_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', COALESCE(content, ''))) STORED;
+
+ALTER TABLE "Treatments" ADD COLUMN IF NOT EXISTS description_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', COALESCE(description, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS medicalrecords_diagnosis_search_idx ON "MedicalRecords" USING GIN (diagnosis_search);
+CREATE INDEX IF NOT EXISTS medicalrecorddiagnoses_description_search_idx ON "MedicalRecordDiagnoses" USING GIN (description_search);
+CREATE INDEX IF NOT EXISTS medicalrecords_notes_search_idx ON "MedicalRecords" USING GIN (notes_search);
+CREATE INDEX IF NOT EXISTS medicalrecordaddenda_content_search_idx ON "MedicalRecordAddenda" USING GIN (content_search);
+CREATE INDEX IF NOT EXISTS treatments_description_search_idx ON "Treatments" USING GIN (description_search);
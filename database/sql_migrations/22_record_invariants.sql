@@ -0,0 +1,49 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- Medical records belong to the pet of their appointment
+---------------------------------------------------------
+UPDATE "MedicalRecords" m
+SET pet_id = a.pet_id, modified_at = NOW(), modified_by = 'system'
+FROM "Appointments" a
+WHERE a.id = m.appointment_id AND m.pet_id <> a.pet_id;
+
+---------------------------------------------------------
+-- One active medical record per appointment
+-- Duplicates are deactivated (keeping the signed one, otherwise the latest edited) with an audit entry
+---------------------------------------------------------
+INSERT INTO "MedicalRecordCorrections"
+    (id, medicalrecord_id, action, reason, snapshot, active_status, created_at, created_by, modified_at, modified_by)
+SELECT md5(m.id::text || ':duplicate')::uuid, m.id, 'Deactivate',
+    'Duplicate medical record for the appointment, deactivated when one active record per appointment was enforced',
+    jsonb_build_object('id', m.id, 'appointment_id', m.appointment_id, 'pet_id', m.pet_id, 'diagnosis', m.diagnosis,
+        'notes', m.notes, 'signed_at', m.signed_at, 'signed_by', m.signed_by, 'active_status', m.active_status,
+        'created_at', m.created_at, 'created_by', m.created_by, 'modified_at', m.modified_at, 'modified_by', m.modified_by),
+    1, NOW(), 'system', NOW(), 'system'
+FROM (
+    SELECT *, ROW_NUMBER() OVER (
+        PARTITION BY appointment_id
+        ORDER BY (signed_at IS NOT NULL) DESC, modified_at DESC NULLS LAST, created_at DESC, id
+    ) AS position
+    FROM "MedicalRecords"
+    WHERE active_status = 1
+) m
+WHERE m.position > 1
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE "MedicalRecords"
+SET active_status = 0, modified_at = NOW(), modified_by = 'system'
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY appointment_id
+            ORDER BY (signed_at IS NOT NULL) DESC, modified_at DESC NULLS LAST, created_at DESC, id
+        ) AS position
+        FROM "MedicalRecords"
+        WHERE active_status = 1
+    ) ranked
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS medicalrecords_appointment_id_active_key ON "MedicalRecords" (appointment_id)
+    WHERE active_status = 1;
@@ -0,0 +1,70 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- REFERRALS (outgoing to a specialist clinic, or imported from another clinic)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Referrals"
+(
+    id uuid NOT NULL,
+    direction character varying(10) NOT NULL, -- Outgoing, Incoming
+    pet_id uuid NOT NULL,
+    referring_doctor_id uuid, -- our doctor for outgoing referrals
+    referring_doctor_name character varying(100), -- as written in the package for incoming referrals
+    source_clinic character varying(150) NOT NULL,
+    destination_clinic character varying(150) NOT NULL,
+    destination_contact character varying(150),
+    reason text NOT NULL,
+    urgency character varying(10) NOT NULL DEFAULT 'Routine', -- Routine, Urgent, Emergency
+    status character varying(20) NOT NULL, -- Draft, Sent, Received, Accepted, Declined, Completed, Cancelled
+    medical_record_ids uuid[] NOT NULL DEFAULT '{}', -- records included in the package
+    notes text,
+    package_sha256 character varying(64), -- last exported or the imported package
+    exported_at timestamp(0) with time zone,
+    imported_at timestamp(0) with time zone,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Referrals_pkey" PRIMARY KEY (id),
+    CONSTRAINT referrals_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT referrals_referring_doctor_id_to_users_id FOREIGN KEY (referring_doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS referrals_pet_id_idx ON "Referrals" (pet_id);
+CREATE INDEX IF NOT EXISTS referrals_status_idx ON "Referrals" (status) WHERE active_status = 1;
+
+-- a package can only be imported once
+CREATE UNIQUE INDEX IF NOT EXISTS referrals_incoming_package_key ON "Referrals" (package_sha256)
+    WHERE direction = 'Incoming' AND active_status = 1;
+
+---------------------------------------------------------
+-- REFERRAL DOCUMENTS (files of an imported package, kept as received)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "ReferralDocuments"
+(
+    id uuid NOT NULL,
+    referral_id uuid NOT NULL,
+    name character varying(200) NOT NULL, -- path inside the package
+    mime character varying(50) NOT NULL,
+    content bytea NOT NULL,
+    sha256 character varying(64) NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "ReferralDocuments_pkey" PRIMARY KEY (id),
+    CONSTRAINT referraldocuments_referral_id_to_referrals_id FOREIGN KEY (referral_id)
+        REFERENCES "Referrals" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS referraldocuments_referral_id_idx ON "ReferralDocuments" (referral_id);
@@ -0,0 +1,87 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- INVOICE NUMBERING (per clinic, gapless: a number is taken when an invoice is issued)
+---------------------------------------------------------
+ALTER TABLE "ClinicProfile"
+    ADD COLUMN IF NOT EXISTS invoice_prefix character varying(10) NOT NULL DEFAULT 'INV-',
+    ADD COLUMN IF NOT EXISTS next_invoice_number integer NOT NULL DEFAULT 1;
+
+---------------------------------------------------------
+-- INVOICES (one per appointment that isn't void)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Invoices"
+(
+    id uuid NOT NULL,
+    clinic_profile_id uuid NOT NULL,
+    invoice_number character varying(30), -- NULL while Draft
+    appointment_id uuid NOT NULL,
+    pet_id uuid NOT NULL,
+    owner_id uuid,
+    bill_to_name character varying(100) NOT NULL, -- owner as written when the invoice was made
+    bill_to_phone character varying(20),
+    status character varying(10) NOT NULL DEFAULT 'Draft', -- Draft, Issued, Void
+    subtotal integer NOT NULL DEFAULT 0,
+    total integer NOT NULL DEFAULT 0,
+    notes text,
+    issued_at timestamp(0) with time zone,
+    issued_by character varying(50),
+    voided_at timestamp(0) with time zone,
+    voided_by character varying(50),
+    void_reason text,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Invoices_pkey" PRIMARY KEY (id),
+    CONSTRAINT invoices_clinic_profile_id_to_clinicprofile_id FOREIGN KEY (clinic_profile_id)
+        REFERENCES "ClinicProfile" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT invoices_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT invoices_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT invoices_owner_id_to_owners_id FOREIGN KEY (owner_id)
+        REFERENCES "Owners" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS invoices_number_key ON "Invoices" (clinic_profile_id, invoice_number) WHERE invoice_number IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS invoices_appointment_id_open_key ON "Invoices" (appointment_id) WHERE status <> 'Void' AND active_status = 1;
+CREATE INDEX IF NOT EXISTS invoices_owner_id_idx ON "Invoices" (owner_id);
+CREATE INDEX IF NOT EXISTS invoices_pet_id_idx ON "Invoices" (pet_id);
+
+---------------------------------------------------------
+-- INVOICE LINES (copied from the appointment type and treatments, frozen once the invoice is issued)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "InvoiceLines"
+(
+    id uuid NOT NULL,
+    invoice_id uuid NOT NULL,
+    source character varying(20) NOT NULL, -- AppointmentType, Treatment, Manual
+    source_id uuid, -- appointment type or treatment the line was copied from
+    description text NOT NULL,
+    quantity integer NOT NULL DEFAULT 1,
+    unit_price integer NOT NULL,
+    amount integer NOT NULL, -- quantity * unit_price
+    sort_order integer NOT NULL DEFAULT 0,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "InvoiceLines_pkey" PRIMARY KEY (id),
+    CONSTRAINT invoicelines_invoice_id_to_invoices_id FOREIGN KEY (invoice_id)
+        REFERENCES "Invoices" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS invoicelines_invoice_id_idx ON "InvoiceLines" (invoice_id);
@@ -0,0 +1,82 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- RECEIPT NUMBERING (per clinic, like invoices)
+---------------------------------------------------------
+ALTER TABLE "ClinicProfile"
+    ADD COLUMN IF NOT EXISTS receipt_prefix character varying(10) NOT NULL DEFAULT 'RCP-',
+    ADD COLUMN IF NOT EXISTS next_receipt_number integer NOT NULL DEFAULT 1;
+
+---------------------------------------------------------
+-- PAYMENTS (money received, split over one or more invoices)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Payments"
+(
+    id uuid NOT NULL,
+    clinic_profile_id uuid NOT NULL,
+    receipt_number character varying(30) NOT NULL,
+    owner_id uuid,
+    payer_name character varying(100) NOT NULL,
+    method character varying(10) NOT NULL, -- Cash, Card, Transfer
+    amount integer NOT NULL,
+    reference character varying(100), -- card slip or transfer reference
+    received_by uuid NOT NULL,
+    received_at timestamp(0) with time zone NOT NULL,
+    notes text,
+    status character varying(10) NOT NULL DEFAULT 'Recorded', -- Recorded, Void
+    voided_at timestamp(0) with time zone,
+    voided_by character varying(50),
+    void_reason text,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Payments_pkey" PRIMARY KEY (id),
+    CONSTRAINT payments_amount_positive CHECK (amount > 0),
+    CONSTRAINT payments_clinic_profile_id_to_clinicprofile_id FOREIGN KEY (clinic_profile_id)
+        REFERENCES "ClinicProfile" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT payments_owner_id_to_owners_id FOREIGN KEY (owner_id)
+        REFERENCES "Owners" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT payments_received_by_to_users_id FOREIGN KEY (received_by)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_receipt_number_key ON "Payments" (clinic_profile_id, receipt_number);
+CREATE INDEX IF NOT EXISTS payments_owner_id_idx ON "Payments" (owner_id);
+CREATE INDEX IF NOT EXISTS payments_received_at_idx ON "Payments" (received_at);
+
+---------------------------------------------------------
+-- PAYMENT ALLOCATIONS (how much of a payment settles which invoice)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "PaymentAllocations"
+(
+    id uuid NOT NULL,
+    payment_id uuid NOT NULL,
+    invoice_id uuid NOT NULL,
+    amount integer NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "PaymentAllocations_pkey" PRIMARY KEY (id),
+    CONSTRAINT paymentallocations_amount_positive CHECK (amount > 0),
+    CONSTRAINT paymentallocations_payment_id_to_payments_id FOREIGN KEY (payment_id)
+        REFERENCES "Payments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT paymentallocations_invoice_id_to_invoices_id FOREIGN KEY (invoice_id)
+        REFERENCES "Invoices" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS paymentallocations_payment_id_idx ON "PaymentAllocations" (payment_id);
+CREATE INDEX IF NOT EXISTS paymentallocations_invoice_id_idx ON "PaymentAllocations" (invoice_id);
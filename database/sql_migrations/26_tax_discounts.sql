@@ -0,0 +1,80 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- TAX RATES (one per billing category, a line is taxed at the rate of its category)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "TaxRates"
+(
+    id uuid NOT NULL,
+    category character varying(30) NOT NULL,
+    name character varying(50) NOT NULL, -- as printed on invoices, e.g. "VAT 11%"
+    rate numeric(5,2) NOT NULL DEFAULT 0, -- percent
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "TaxRates_pkey" PRIMARY KEY (id),
+    CONSTRAINT taxrates_rate_check CHECK (rate >= 0 AND rate <= 100)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS taxrates_category_key ON "TaxRates" (lower(category)) WHERE active_status = 1;
+
+INSERT INTO "TaxRates" (id, category, name, rate, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('5e8c1a2d-3b4f-4c6e-9a7d-000000000001', 'Service', 'No tax', 0, 1, NOW(), 'system', NOW(), 'system'),
+    ('5e8c1a2d-3b4f-4c6e-9a7d-000000000002', 'Treatment', 'No tax', 0, 1, NOW(), 'system', NOW(), 'system'),
+    ('5e8c1a2d-3b4f-4c6e-9a7d-000000000003', 'Medication', 'No tax', 0, 1, NOW(), 'system', NOW(), 'system'),
+    ('5e8c1a2d-3b4f-4c6e-9a7d-000000000004', 'Product', 'No tax', 0, 1, NOW(), 'system', NOW(), 'system'),
+    ('5e8c1a2d-3b4f-4c6e-9a7d-000000000005', 'Other', 'No tax', 0, 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
+
+---------------------------------------------------------
+-- BILLING CATEGORIES of the catalog, copied to the invoice lines
+---------------------------------------------------------
+ALTER TABLE "AppointmentTypes"
+    ADD COLUMN IF NOT EXISTS billing_category character varying(30) NOT NULL DEFAULT 'Service';
+
+ALTER TABLE "Treatments"
+    ADD COLUMN IF NOT EXISTS billing_category character varying(30) NOT NULL DEFAULT 'Treatment';
+
+---------------------------------------------------------
+-- ROUNDING AND DISCOUNT SETTINGS
+---------------------------------------------------------
+ALTER TABLE "ClinicProfile"
+    ADD COLUMN IF NOT EXISTS rounding_unit integer NOT NULL DEFAULT 1, -- grand totals are rounded half up to a multiple of it
+    ADD COLUMN IF NOT EXISTS staff_discount_limit numeric(5,2) NOT NULL DEFAULT 0, -- percent Staff may give, larger discounts need Admin
+    ADD CONSTRAINT clinicprofile_rounding_unit_check CHECK (rounding_unit >= 1);
+
+---------------------------------------------------------
+-- LINE DISCOUNTS AND TAX (amounts are worked out by services.CalculateInvoiceTotals)
+---------------------------------------------------------
+ALTER TABLE "InvoiceLines"
+    ADD COLUMN IF NOT EXISTS category character varying(30) NOT NULL DEFAULT 'Other',
+    ADD COLUMN IF NOT EXISTS discount_type character varying(10), -- Percent, Fixed
+    ADD COLUMN IF NOT EXISTS discount_value numeric(12,2),
+    ADD COLUMN IF NOT EXISTS discount_reason text,
+    ADD COLUMN IF NOT EXISTS discount_approved_by character varying(50),
+    ADD COLUMN IF NOT EXISTS discount_amount integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS invoice_discount_share integer NOT NULL DEFAULT 0, -- part of the invoice discount taken off this line
+    ADD COLUMN IF NOT EXISTS tax_rate numeric(5,2) NOT NULL DEFAULT 0, -- rate of the category, frozen once the invoice is issued
+    ADD COLUMN IF NOT EXISTS tax_amount integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS total integer NOT NULL DEFAULT 0;
+
+UPDATE "InvoiceLines" SET total = amount WHERE total = 0;
+
+UPDATE "InvoiceLines" SET category = CASE source WHEN 'AppointmentType' THEN 'Service' ELSE 'Treatment' END
+WHERE source <> 'Manual';
+
+---------------------------------------------------------
+-- INVOICE DISCOUNT AND TOTALS
+---------------------------------------------------------
+ALTER TABLE "Invoices"
+    ADD COLUMN IF NOT EXISTS discount_type character varying(10), -- Percent, Fixed
+    ADD COLUMN IF NOT EXISTS discount_value numeric(12,2),
+    ADD COLUMN IF NOT EXISTS discount_reason text,
+    ADD COLUMN IF NOT EXISTS discount_approved_by character varying(50),
+    ADD COLUMN IF NOT EXISTS discount_amount integer NOT NULL DEFAULT 0, -- invoice discount only
+    ADD COLUMN IF NOT EXISTS discount_total integer NOT NULL DEFAULT 0, -- line and invoice discounts
+    ADD COLUMN IF NOT EXISTS tax_total integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rounding_adjustment integer NOT NULL DEFAULT 0;
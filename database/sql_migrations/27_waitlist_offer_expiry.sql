@@ -0,0 +1,10 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- WAITLIST OFFERS expire after WAITLIST_OFFER_TTL_MINUTES, the slot then goes to the next entry
+---------------------------------------------------------
+ALTER TABLE "Waitlist"
+    ADD COLUMN IF NOT EXISTS offered_appointment_id uuid, -- freed appointment the offered slot came from
+    ADD COLUMN IF NOT EXISTS offer_expired_datetime timestamp(0) without time zone; -- slot of the last offer left to expire, not offered again
+
+CREATE INDEX IF NOT EXISTS waitlist_offered_at_idx ON "Waitlist" (offered_at) WHERE status = 'Offered';
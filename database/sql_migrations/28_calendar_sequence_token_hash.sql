@@ -0,0 +1,39 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- CALENDAR SEQUENCE (iCalendar SEQUENCE, bumped whenever a field shown in the feed changes)
+---------------------------------------------------------
+ALTER TABLE "Appointments"
+    ADD COLUMN IF NOT EXISTS calendar_sequence integer NOT NULL DEFAULT 0;
+
+-- +migrate StatementBegin
+CREATE OR REPLACE FUNCTION appointments_bump_calendar_sequence() RETURNS trigger AS $$
+BEGIN
+    IF (NEW.status, NEW.appointment_datetime, NEW.notes, NEW.active_status, NEW.appointment_type_id, NEW.pet_id, NEW.doctor_id)
+        IS DISTINCT FROM
+       (OLD.status, OLD.appointment_datetime, OLD.notes, OLD.active_status, OLD.appointment_type_id, OLD.pet_id, OLD.doctor_id) THEN
+        NEW.calendar_sequence := OLD.calendar_sequence + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +migrate StatementEnd
+
+DROP TRIGGER IF EXISTS appointments_calendar_sequence ON "Appointments";
+CREATE TRIGGER appointments_calendar_sequence
+    BEFORE UPDATE ON "Appointments"
+    FOR EACH ROW EXECUTE FUNCTION appointments_bump_calendar_sequence();
+
+---------------------------------------------------------
+-- CALENDAR TOKENS are kept as sha256 hashes, the plain token is only shown once on creation
+---------------------------------------------------------
+ALTER TABLE "CalendarTokens"
+    ADD COLUMN IF NOT EXISTS token_hash character varying(64);
+
+UPDATE "CalendarTokens" SET token_hash = encode(sha256(token::bytea), 'hex') WHERE token_hash IS NULL;
+
+ALTER TABLE "CalendarTokens"
+    ALTER COLUMN token_hash SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS calendartokens_token_key,
+    DROP COLUMN IF EXISTS token,
+    ADD CONSTRAINT calendartokens_token_hash_key UNIQUE (token_hash);
@@ -0,0 +1,6 @@
+-- +migrate Up
+
+-- a room holds one patient at a time; checkRoom gives the friendly error, this catches two calls racing
+CREATE UNIQUE INDEX IF NOT EXISTS queueentries_room_occupied_key
+    ON "QueueEntries" (resource_id)
+    WHERE status = 'InRoom' AND active_status = 1;
@@ -0,0 +1,78 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- NOTIFICATIONS (transactional outbox)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Notifications"
+(
+    id uuid NOT NULL,
+    appointment_id uuid,
+    waitlist_id uuid,
+    kind character varying(30) NOT NULL, -- WaitlistOffer
+    channel character varying(20) NOT NULL, -- SMS, Email
+    recipient character varying(100) NOT NULL,
+    subject character varying(200),
+    message text NOT NULL,
+    status character varying(20) NOT NULL, -- Pending, Sent, Failed
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Notifications_pkey" PRIMARY KEY (id),
+    CONSTRAINT notifications_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS notifications_status_idx ON "Notifications" (status);
+
+---------------------------------------------------------
+-- WAITLIST
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Waitlist"
+(
+    id uuid NOT NULL,
+    pet_id uuid NOT NULL,
+    preferred_doctor_id uuid,
+    date_from date NOT NULL,
+    date_to date NOT NULL,
+    priority integer NOT NULL DEFAULT 0, -- higher = more urgent
+    notes text,
+    status character varying(20) NOT NULL, -- Waiting, Offered, Booked, Cancelled
+    offered_datetime timestamp(0) without time zone,
+    offered_doctor_id uuid,
+    offered_at timestamp(0) without time zone,
+    appointment_id uuid,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Waitlist_pkey" PRIMARY KEY (id),
+    CONSTRAINT waitlist_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT waitlist_preferred_doctor_id_to_users_id FOREIGN KEY (preferred_doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT waitlist_offered_doctor_id_to_users_id FOREIGN KEY (offered_doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT waitlist_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_status_window_idx ON "Waitlist" (status, date_from, date_to);
+
+ALTER TABLE "Notifications"
+    ADD CONSTRAINT notifications_waitlist_id_to_waitlist_id FOREIGN KEY (waitlist_id)
+        REFERENCES "Waitlist" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
@@ -0,0 +1,14 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- IMPORTED REFERRALS are identified by the sending clinic's referral id, not by the zip bytes
+-- (a re-export of the same referral is a different zip)
+---------------------------------------------------------
+ALTER TABLE "Referrals"
+    ADD COLUMN IF NOT EXISTS source_referral_id uuid; -- referral_id of the manifest, incoming referrals only
+
+DROP INDEX IF EXISTS referrals_incoming_package_key;
+
+-- a referral of a clinic can only be imported once
+CREATE UNIQUE INDEX IF NOT EXISTS referrals_incoming_source_key ON "Referrals" (lower(source_clinic), source_referral_id)
+    WHERE direction = 'Incoming' AND active_status = 1;
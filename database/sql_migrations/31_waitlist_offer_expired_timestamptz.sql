@@ -0,0 +1,10 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- WAITLIST: offer_expired_datetime is compared with the timezone-aware
+-- appointment_datetime/offered_datetime, so it is timezone-aware too.
+-- Existing values are clinic wall-clock times, see 6_appointment_timestamptz.sql.
+---------------------------------------------------------
+ALTER TABLE "Waitlist"
+    ALTER COLUMN offer_expired_datetime TYPE timestamp(0) with time zone
+    USING offer_expired_datetime AT TIME ZONE current_setting('TimeZone');
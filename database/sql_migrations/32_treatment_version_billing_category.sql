@@ -0,0 +1,10 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- TREATMENT VERSIONS: snapshots include the billing category (see controllers/versionController.go).
+-- Category changes weren't versioned before, so older versions get the current category.
+---------------------------------------------------------
+UPDATE "TreatmentVersions" v
+SET snapshot = v.snapshot || jsonb_build_object('billing_category', t.billing_category)
+FROM "Treatments" t
+WHERE t.id = v.treatment_id AND v.snapshot -> 'billing_category' IS NULL;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- CALENDAR TOKENS (per-doctor .ics subscription feeds)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "CalendarTokens"
+(
+    id uuid NOT NULL,
+    doctor_id uuid NOT NULL,
+    token character varying(64) NOT NULL,
+    revoked_at timestamp(0) without time zone,
+    revoked_by character varying(50),
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "CalendarTokens_pkey" PRIMARY KEY (id),
+    CONSTRAINT calendartokens_token_key UNIQUE (token),
+    CONSTRAINT calendartokens_doctor_id_to_users_id FOREIGN KEY (doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- NOTIFICATIONS: delivery tracking + appointment reminders
+---------------------------------------------------------
+ALTER TABLE "Notifications"
+    ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamp(0) without time zone,
+    ADD COLUMN IF NOT EXISTS last_error text,
+    ADD COLUMN IF NOT EXISTS sent_at timestamp(0) without time zone;
+
+UPDATE "Notifications" SET next_attempt_at = created_at WHERE status = 'Pending' AND next_attempt_at IS NULL;
+
+-- kind: WaitlistOffer, Reminder48h, Reminder2h, ...
+-- one reminder of each kind per appointment
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_appointment_reminder_key
+    ON "Notifications" (appointment_id, kind)
+    WHERE kind LIKE 'Reminder%';
+
+CREATE INDEX IF NOT EXISTS notifications_pending_idx
+    ON "Notifications" (next_attempt_at)
+    WHERE status = 'Pending' AND active_status = 1;
+
+CREATE INDEX IF NOT EXISTS notifications_appointment_id_idx ON "Notifications" (appointment_id);
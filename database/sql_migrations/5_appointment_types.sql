@@ -0,0 +1,39 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- APPOINTMENT TYPES (service catalog)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "AppointmentTypes"
+(
+    id uuid NOT NULL,
+    name character varying(100) NOT NULL,
+    default_duration_minutes integer NOT NULL DEFAULT 30,
+    default_price integer NOT NULL DEFAULT 0,
+    required_role character varying(20) NOT NULL DEFAULT 'Doctor', -- Doctor, Staff
+    color character varying(7), -- #RRGGBB
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "AppointmentTypes_pkey" PRIMARY KEY (id)
+);
+
+INSERT INTO "AppointmentTypes"
+    (id, name, default_duration_minutes, default_price, required_role, color, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('6a1f0c2e-1d3b-4c5a-9e01-000000000001', 'Consultation', 30, 150000, 'Doctor', '#4A90D9', 1, NOW(), 'system', NOW(), 'system'),
+    ('6a1f0c2e-1d3b-4c5a-9e01-000000000002', 'Vaccination', 15, 200000, 'Doctor', '#7ED321', 1, NOW(), 'system', NOW(), 'system'),
+    ('6a1f0c2e-1d3b-4c5a-9e01-000000000003', 'Surgery', 120, 1500000, 'Doctor', '#D0021B', 1, NOW(), 'system', NOW(), 'system'),
+    ('6a1f0c2e-1d3b-4c5a-9e01-000000000004', 'Dental', 60, 500000, 'Doctor', '#F5A623', 1, NOW(), 'system', NOW(), 'system'),
+    ('6a1f0c2e-1d3b-4c5a-9e01-000000000005', 'Grooming', 60, 100000, 'Staff', '#9013FE', 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE "Appointments"
+    ADD COLUMN IF NOT EXISTS appointment_type_id uuid,
+    ADD CONSTRAINT appointments_appointment_type_id_to_appointmenttypes_id FOREIGN KEY (appointment_type_id)
+        REFERENCES "AppointmentTypes" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS appointments_appointment_type_id_idx ON "Appointments" (appointment_type_id);
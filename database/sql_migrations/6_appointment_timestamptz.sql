@@ -0,0 +1,30 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- APPOINTMENTS: timezone-aware datetime
+-- Existing values are clinic wall-clock times. The connection
+-- TimeZone is set to CLINIC_TIMEZONE (see main.go), so they are
+-- interpreted in the clinic timezone during the conversion.
+---------------------------------------------------------
+ALTER TABLE "Appointments"
+    ALTER COLUMN appointment_datetime TYPE timestamp(0) with time zone
+    USING appointment_datetime AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE "Waitlist"
+    ALTER COLUMN offered_datetime TYPE timestamp(0) with time zone
+    USING offered_datetime AT TIME ZONE current_setting('TimeZone');
+
+---------------------------------------------------------
+-- APPOINTMENTS: range query indexes
+---------------------------------------------------------
+CREATE INDEX IF NOT EXISTS appointments_datetime_idx
+    ON "Appointments" (appointment_datetime)
+    WHERE active_status = 1;
+
+CREATE INDEX IF NOT EXISTS appointments_doctor_id_datetime_idx
+    ON "Appointments" (doctor_id, appointment_datetime)
+    WHERE active_status = 1;
+
+CREATE INDEX IF NOT EXISTS appointments_pet_id_datetime_idx
+    ON "Appointments" (pet_id, appointment_datetime)
+    WHERE active_status = 1;
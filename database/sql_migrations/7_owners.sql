@@ -0,0 +1,46 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- OWNERS (self-service principals, separate from Users)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Owners"
+(
+    id uuid NOT NULL,
+    name character varying(100) NOT NULL,
+    email character varying(100),
+    phone character varying(20) NOT NULL,
+    password_hash text, -- NULL = no login yet
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Owners_pkey" PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS owners_email_key ON "Owners" (lower(email)) WHERE email IS NOT NULL AND active_status = 1;
+CREATE UNIQUE INDEX IF NOT EXISTS owners_phone_key ON "Owners" (phone) WHERE active_status = 1;
+
+ALTER TABLE "Pets"
+    ADD COLUMN IF NOT EXISTS owner_id uuid,
+    ADD CONSTRAINT pets_owner_id_to_owners_id FOREIGN KEY (owner_id)
+        REFERENCES "Owners" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION;
+
+CREATE INDEX IF NOT EXISTS pets_owner_id_idx ON "Pets" (owner_id);
+
+-- Backfill one owner per distinct phone number already stored on pets (deterministic ids)
+INSERT INTO "Owners" (id, name, phone, active_status, created_at, created_by, modified_at, modified_by)
+SELECT DISTINCT ON (owner_phone)
+    md5('owner:' || owner_phone)::uuid, COALESCE(NULLIF(owner_name, ''), 'Unknown'), owner_phone,
+    1, NOW(), 'system', NOW(), 'system'
+FROM "Pets"
+WHERE COALESCE(owner_phone, '') <> ''
+ORDER BY owner_phone, created_at DESC
+ON CONFLICT DO NOTHING;
+
+UPDATE "Pets" p
+SET owner_id = o.id
+FROM "Owners" o
+WHERE o.phone = p.owner_phone AND p.owner_id IS NULL;
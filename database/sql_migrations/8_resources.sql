@@ -0,0 +1,94 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- RESOURCES (rooms, equipment)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "Resources"
+(
+    id uuid NOT NULL,
+    name character varying(100) NOT NULL,
+    kind character varying(20) NOT NULL, -- Room, Equipment
+    category character varying(50) NOT NULL, -- ExamRoom, OperatingTheatre, AnesthesiaMachine, ...
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "Resources_pkey" PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS resources_category_idx ON "Resources" (category) WHERE active_status = 1;
+
+---------------------------------------------------------
+-- APPOINTMENT TYPE RESOURCES (requirements per type)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "AppointmentTypeResources"
+(
+    id uuid NOT NULL,
+    appointment_type_id uuid NOT NULL,
+    category character varying(50) NOT NULL,
+    quantity integer NOT NULL DEFAULT 1,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "AppointmentTypeResources_pkey" PRIMARY KEY (id),
+    CONSTRAINT appointmenttyperesources_appointment_type_id_to_appointmenttypes_id FOREIGN KEY (appointment_type_id)
+        REFERENCES "AppointmentTypes" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+---------------------------------------------------------
+-- APPOINTMENT RESOURCES (allocations)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "AppointmentResources"
+(
+    id uuid NOT NULL,
+    appointment_id uuid NOT NULL,
+    resource_id uuid NOT NULL,
+    starts_at timestamp(0) with time zone NOT NULL,
+    ends_at timestamp(0) with time zone NOT NULL,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "AppointmentResources_pkey" PRIMARY KEY (id),
+    CONSTRAINT appointmentresources_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT appointmentresources_resource_id_to_resources_id FOREIGN KEY (resource_id)
+        REFERENCES "Resources" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+CREATE INDEX IF NOT EXISTS appointmentresources_resource_id_starts_at_idx
+    ON "AppointmentResources" (resource_id, starts_at)
+    WHERE active_status = 1;
+CREATE INDEX IF NOT EXISTS appointmentresources_appointment_id_idx ON "AppointmentResources" (appointment_id);
+
+---------------------------------------------------------
+-- Seed: clinic rooms/equipment and requirements of the default catalog
+---------------------------------------------------------
+INSERT INTO "Resources" (id, name, kind, category, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('7b2e1d3f-2e4c-4d6b-8f12-000000000001', 'Exam Room 1', 'Room', 'ExamRoom', 1, NOW(), 'system', NOW(), 'system'),
+    ('7b2e1d3f-2e4c-4d6b-8f12-000000000002', 'Exam Room 2', 'Room', 'ExamRoom', 1, NOW(), 'system', NOW(), 'system'),
+    ('7b2e1d3f-2e4c-4d6b-8f12-000000000003', 'Exam Room 3', 'Room', 'ExamRoom', 1, NOW(), 'system', NOW(), 'system'),
+    ('7b2e1d3f-2e4c-4d6b-8f12-000000000004', 'Operating Theatre', 'Room', 'OperatingTheatre', 1, NOW(), 'system', NOW(), 'system'),
+    ('7b2e1d3f-2e4c-4d6b-8f12-000000000005', 'Anesthesia Machine', 'Equipment', 'AnesthesiaMachine', 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO "AppointmentTypeResources" (id, appointment_type_id, category, quantity, active_status, created_at, created_by, modified_at, modified_by)
+VALUES
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000001', '6a1f0c2e-1d3b-4c5a-9e01-000000000001', 'ExamRoom', 1, 1, NOW(), 'system', NOW(), 'system'),
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000002', '6a1f0c2e-1d3b-4c5a-9e01-000000000002', 'ExamRoom', 1, 1, NOW(), 'system', NOW(), 'system'),
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000003', '6a1f0c2e-1d3b-4c5a-9e01-000000000003', 'OperatingTheatre', 1, 1, NOW(), 'system', NOW(), 'system'),
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000004', '6a1f0c2e-1d3b-4c5a-9e01-000000000003', 'AnesthesiaMachine', 1, 1, NOW(), 'system', NOW(), 'system'),
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000005', '6a1f0c2e-1d3b-4c5a-9e01-000000000004', 'ExamRoom', 1, 1, NOW(), 'system', NOW(), 'system'),
+    ('8c3f2e4a-3f5d-4e7c-9a23-000000000006', '6a1f0c2e-1d3b-4c5a-9e01-000000000004', 'AnesthesiaMachine', 1, 1, NOW(), 'system', NOW(), 'system')
+ON CONFLICT (id) DO NOTHING;
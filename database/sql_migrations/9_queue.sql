@@ -0,0 +1,52 @@
+-- +migrate Up
+
+---------------------------------------------------------
+-- QUEUE ENTRIES (waiting room: checked-in appointments and walk-ins)
+---------------------------------------------------------
+CREATE TABLE IF NOT EXISTS "QueueEntries"
+(
+    id uuid NOT NULL,
+    appointment_id uuid,
+    pet_id uuid,
+    visitor_name character varying(100), -- walk-in without a registered pet
+    kind character varying(20) NOT NULL, -- Appointment, WalkIn
+    triage_priority integer NOT NULL DEFAULT 0, -- higher = more urgent
+    triage_notes text,
+    status character varying(20) NOT NULL, -- Waiting, InRoom, Done, Left
+    doctor_id uuid,
+    resource_id uuid, -- room the patient is in
+    checked_in_at timestamp(0) with time zone NOT NULL,
+    called_at timestamp(0) with time zone,
+    completed_at timestamp(0) with time zone,
+    active_status integer NOT NULL DEFAULT 1,
+    created_at timestamp(0) without time zone NOT NULL,
+    created_by character varying(50) NOT NULL,
+    modified_at timestamp(0) without time zone,
+    modified_by character varying(50),
+    CONSTRAINT "QueueEntries_pkey" PRIMARY KEY (id),
+    CONSTRAINT queueentries_appointment_id_to_appointments_id FOREIGN KEY (appointment_id)
+        REFERENCES "Appointments" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT queueentries_pet_id_to_pets_id FOREIGN KEY (pet_id)
+        REFERENCES "Pets" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT queueentries_doctor_id_to_users_id FOREIGN KEY (doctor_id)
+        REFERENCES "Users" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION,
+    CONSTRAINT queueentries_resource_id_to_resources_id FOREIGN KEY (resource_id)
+        REFERENCES "Resources" (id)
+        ON UPDATE NO ACTION
+        ON DELETE NO ACTION
+);
+
+-- an appointment can only be checked in once
+CREATE UNIQUE INDEX IF NOT EXISTS queueentries_appointment_id_key
+    ON "QueueEntries" (appointment_id)
+    WHERE active_status = 1;
+
+CREATE INDEX IF NOT EXISTS queueentries_checked_in_at_idx
+    ON "QueueEntries" (checked_in_at)
+    WHERE active_status = 1;
@@ -7,6 +7,8 @@ import (
 	"os"
 	"vetclinic-rest-api/database"
 	"vetclinic-rest-api/routers"
+	"vetclinic-rest-api/services"
+	"vetclinic-rest-api/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -23,12 +25,14 @@ func main() {
     if err != nil {
         panic("Error loading .env file")
     }
-	dbInfo := fmt.Sprintf(`host=%s port=%s user=%s password=%s dbname=%s sslmode=disable`,
+	// session timezone = clinic timezone, so timestamptz values come back in clinic local time
+	dbInfo := fmt.Sprintf(`host=%s port=%s user=%s password=%s dbname=%s sslmode=disable timezone=%s`,
         os.Getenv("PGHOST"),
         os.Getenv("PGPORT"),
         os.Getenv("PGUSER"),
         os.Getenv("PGPASSWORD"),
         os.Getenv("PGDATABASE"),
+        utils.ClinicTimezone(),
     )
 	db, err = sql.Open("postgres", dbInfo)
 	if err != nil {
@@ -42,7 +46,23 @@ func main() {
 	}
 
 	database.DBMigrate(db)
+	services.StartWaitlistMatcher(db)
+	services.StartReminderScheduler(db)
+	services.StartNoShowScheduler(db)
+
+	// local file sink for dev/testing, replace with real SMS/email senders in production
+	fileSender := services.NewFileSender(utils.GetEnv("NOTIFY_SINK_DIR", "notifications_out"))
+	services.StartNotificationDispatcher(db, map[string]services.Sender{
+		"SMS":   fileSender,
+		"Email": fileSender,
+	})
+
 	router := gin.Default()
+	// X-Forwarded-For is only believed from TRUSTED_PROXIES (IPs/CIDRs), so ClientIP (e.g. the IP recorded
+	// with a consent signature) can't be spoofed by the client; without proxies it is the peer address
+	if err := router.SetTrustedProxies(utils.GetEnvList("TRUSTED_PROXIES")); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 	routers.SetupRoutes(router, db)
 
 	port := os.Getenv("PORT")
@@ -3,6 +3,7 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 	"vetclinic-rest-api/utils"
 
 	"github.com/gin-gonic/gin"
@@ -23,53 +24,101 @@ func JWTAuth(requiredRoles ...string) gin.HandlerFunc {
 
         tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-        // parse token
-        token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-            return jwtSecret, nil
-        })
-
-        if err != nil || !token.Valid {
+        // stream tickets only open event streams, they are not login tokens
+        claims, ok := parseClaims(tokenString)
+        if !ok || claims["purpose"] != nil {
             c.AbortWithStatus(http.StatusUnauthorized)
             return
         }
 
-        claims, ok := token.Claims.(jwt.MapClaims)
-        if !ok {
-            c.AbortWithStatus(http.StatusUnauthorized)
+        authorize(c, claims, requiredRoles)
+    }
+}
+
+// StreamTicketAuth is JWTAuth for server-sent event endpoints. Browser EventSource clients can't set headers,
+// so instead of the JWT they pass a short-lived ticket from NewStreamTicket as ?ticket=
+func StreamTicketAuth(requiredRoles ...string) gin.HandlerFunc {
+    jwtAuth := JWTAuth(requiredRoles...)
+    return func(c *gin.Context) {
+        ticket := c.Query("ticket")
+        if c.GetHeader("Authorization") != "" || ticket == "" {
+            jwtAuth(c)
             return
         }
 
-        role, ok := claims["role"].(string)
-        if !ok {
+        claims, ok := parseClaims(ticket)
+        if !ok || claims["purpose"] != streamTicketPurpose {
             c.AbortWithStatus(http.StatusUnauthorized)
             return
         }
 
-        // Check if the role exactly the one that needed
-        allowed := false
-        for _, r := range requiredRoles {
-            if role == r {
-                allowed = true
-                break
-            }
-        }
+        authorize(c, claims, requiredRoles)
+    }
+}
 
-        if !allowed {
-            c.AbortWithStatus(http.StatusForbidden)
-            return
-        }
+const streamTicketPurpose = "stream"
 
-        // Extract user_id from claims
-        userId, ok := claims["user_id"].(string)
-        if !ok {
-            c.AbortWithStatus(http.StatusUnauthorized)
-            return
+// StreamTicketTTL is how long a stream ticket can be used to open a stream, an open stream isn't cut off
+const StreamTicketTTL = 30 * time.Second
+
+// NewStreamTicket signs a ticket for the user of the current request, to be used once in an EventSource URL.
+// It expires quickly so a URL that ends up in a proxy or access log is of no use.
+func NewStreamTicket(role string, userId string) (string, error) {
+    claims := jwt.MapClaims{
+        "user_id": userId,
+        "role":    role,
+        "purpose": streamTicketPurpose,
+        "exp":     time.Now().Add(StreamTicketTTL).Unix(),
+    }
+    return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+func parseClaims(tokenString string) (jwt.MapClaims, bool) {
+    // parse token
+    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+        return jwtSecret, nil
+    })
+
+    if err != nil || !token.Valid {
+        return nil, false
+    }
+
+    claims, ok := token.Claims.(jwt.MapClaims)
+    return claims, ok
+}
+
+// authorize checks the role of the claims and saves role and user_id to the context
+func authorize(c *gin.Context, claims jwt.MapClaims, requiredRoles []string) {
+    role, ok := claims["role"].(string)
+    if !ok {
+        c.AbortWithStatus(http.StatusUnauthorized)
+        return
+    }
+
+    // Check if the role exactly the one that needed
+    allowed := false
+    for _, r := range requiredRoles {
+        if role == r {
+            allowed = true
+            break
         }
+    }
 
-        // Save role and user_id to context
-        c.Set("role", role)
-        c.Set("user_id", userId)
+    if !allowed {
+        c.AbortWithStatus(http.StatusForbidden)
+        return
+    }
 
-        c.Next()
+    // Extract user_id from claims
+    userId, ok := claims["user_id"].(string)
+    if !ok {
+        c.AbortWithStatus(http.StatusUnauthorized)
+        return
     }
-}
\ No newline at end of file
+
+    // Save role and user_id to context
+    c.Set("role", role)
+    c.Set("user_id", userId)
+
+    c.Next()
+}
@@ -50,6 +50,14 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		petsGroup.GET("/:id/profile", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
 			controllers.FetchPetProfile(c, db)
 		})
+		// Get chronological medical timeline of a pet, paginated (Doctor, Staff and Admin)
+		petsGroup.GET("/:id/timeline", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetPetTimeline(c, db)
+		})
+		// Print the full medical history of a pet as PDF (Doctor, Staff and Admin)
+		petsGroup.GET("/:id/medical-history.pdf", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetPetMedicalHistoryPDF(c, db)
+		})
 		// Get pets data based on owner name and phone (all roles)
 		petsGroup.GET("/by-owner/:owner_name/:owner_phone", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
 			controllers.FetchPetsByOwner(c, db)
@@ -73,6 +81,10 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		appointmentsGroup.POST("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
 			controllers.CreateAppointment(c, db)
 		})
+		// Get Appointments by datetime range + filters (all roles)
+		appointmentsGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.ListAppointments(c, db)
+		})
 		// Fetch appointment by ID (all roles)
 		appointmentsGroup.GET("/:id", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
 			controllers.FetchAppointment(c, db)
@@ -105,6 +117,26 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		appointmentsGroup.GET("/:id/full", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
 			controllers.GetFullAppointmentDetail(c, db)
 		})
+		// Print visit summary (appointment, medical record, treatments, total cost) as PDF (all roles)
+		appointmentsGroup.GET("/:id/summary.pdf", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetVisitSummaryPDF(c, db)
+		})
+		// Get reminders/notifications of appointment with delivery status (all roles)
+		appointmentsGroup.GET("/:id/notifications", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetNotificationsByAppointmentId(c, db)
+		})
+		// Get rooms/equipment reserved for an appointment (all roles)
+		appointmentsGroup.GET("/:id/resources", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetAppointmentResources(c, db)
+		})
+		// Generate a consent from a template, defaults to the appointment type's template (all roles)
+		appointmentsGroup.POST("/:id/consents", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateAppointmentConsent(c, db)
+		})
+		// Get consents of an appointment (all roles)
+		appointmentsGroup.GET("/:id/consents", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetAppointmentConsents(c, db)
+		})
 	}
 	medicalGroup := router.Group("api/medical-records")
 	{
@@ -124,6 +156,34 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		medicalGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
 			controllers.UpdateMedicalRecordActiveStatus(c, db)
 		})
+		// Record vitals for a Medical Record (all roles)
+		medicalGroup.POST("/:id/vitals", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.AddVitals(c, db)
+		})
+		// Sign/finalize Medical Record (attending Doctor)
+		medicalGroup.PUT("/:id/sign", middleware.JWTAuth("Doctor"), func(c *gin.Context) {
+			controllers.SignMedicalRecord(c, db)
+		})
+		// Add addendum to a signed Medical Record (Doctor and Admin)
+		medicalGroup.POST("/:id/addenda", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateAddendum(c, db)
+		})
+		// Correct (remove) a signed Medical Record with audit (Admin)
+		medicalGroup.POST("/:id/corrections", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateMedicalRecordCorrection(c, db)
+		})
+		// Get correction audit of a Medical Record (Admin)
+		medicalGroup.GET("/:id/corrections", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.GetMedicalRecordCorrections(c, db)
+		})
+		// Get version history of a Medical Record (Doctor, Staff and Admin)
+		medicalGroup.GET("/:id/versions", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetMedicalRecordVersions(c, db)
+		})
+		// Compare two versions of a Medical Record, ?from=&to= (Doctor, Staff and Admin)
+		medicalGroup.GET("/:id/versions/diff", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetMedicalRecordVersionDiff(c, db)
+		})
 	}
 	treatmentGroup := router.Group("api/treatments")
 	{
@@ -143,5 +203,556 @@ func SetupRoutes(router *gin.Engine, db *sql.DB) {
 		treatmentGroup.PUT("/:id/active-status", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
 			controllers.UpdateTreatmentActiveStatus(c, db)
 		})
+		// Get version history of a treatment (Doctor, Staff and Admin)
+		treatmentGroup.GET("/:id/versions", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetTreatmentVersions(c, db)
+		})
+		// Compare two versions of a treatment, ?from=&to= (Doctor, Staff and Admin)
+		treatmentGroup.GET("/:id/versions/diff", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetTreatmentVersionDiff(c, db)
+		})
+	}
+	prescriptionGroup := router.Group("api/prescriptions")
+	{
+		// Create prescription (Doctor and Admin)
+		prescriptionGroup.POST("", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreatePrescription(c, db)
+		})
+		// Get prescription with its dispenses (Doctor, Staff and Admin)
+		prescriptionGroup.GET("/:id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPrescription(c, db)
+		})
+		// Get prescriptions based on medical record id (Doctor, Staff and Admin)
+		prescriptionGroup.GET("/medicalrecord/:medicalrecord_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPrescriptionsByMedicalRecordId(c, db)
+		})
+		// Get active prescriptions of a pet, ?status=all for every status (Doctor, Staff and Admin)
+		prescriptionGroup.GET("/pet/:pet_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPetPrescriptions(c, db)
+		})
+		// Record a fill/refill (Doctor, Staff and Admin)
+		prescriptionGroup.POST("/:id/dispenses", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.DispensePrescription(c, db)
+		})
+		// Cancel an active prescription (Doctor and Admin)
+		prescriptionGroup.PUT("/:id/cancel", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CancelPrescription(c, db)
+		})
+		// Soft delete prescription entered by mistake (Doctor and Admin)
+		prescriptionGroup.PUT("/:id/active-status", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.UpdatePrescriptionActiveStatus(c, db)
+		})
+	}
+	labReferenceRangeGroup := router.Group("api/lab-reference-ranges")
+	{
+		// Create reference range (Admin)
+		labReferenceRangeGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateLabReferenceRange(c, db)
+		})
+		// Get reference ranges, ?analyte_code=&species= (Doctor, Staff and Admin)
+		labReferenceRangeGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetLabReferenceRanges(c, db)
+		})
+		// Update reference range (Admin)
+		labReferenceRangeGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateLabReferenceRange(c, db)
+		})
+		// Reference range soft delete (Admin)
+		labReferenceRangeGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateLabReferenceRangeActiveStatus(c, db)
+		})
+	}
+	labOrderGroup := router.Group("api/lab-orders")
+	{
+		// Create lab order for an appointment (Doctor and Admin)
+		labOrderGroup.POST("", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateLabOrder(c, db)
+		})
+		// Lab bench worklist, orders waiting for results (Doctor, Staff and Admin)
+		labOrderGroup.GET("/open", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetOpenLabOrders(c, db)
+		})
+		// Import analyzer results from CSV or HL7 v2 ORU (Doctor, Staff and Admin)
+		labOrderGroup.POST("/import", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.ImportLabResults(c, db)
+		})
+		// Get lab orders based on appointment id (Doctor, Staff and Admin)
+		labOrderGroup.GET("/appointment/:appointment_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetLabOrdersByAppointmentId(c, db)
+		})
+		// Get lab orders of a pet, ?status= (Doctor, Staff and Admin)
+		labOrderGroup.GET("/pet/:pet_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPetLabOrders(c, db)
+		})
+		// Get lab order with its results (Doctor, Staff and Admin)
+		labOrderGroup.GET("/:id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetLabOrder(c, db)
+		})
+		// Update lab order status: Collected, InProgress, Cancelled (Doctor, Staff and Admin)
+		labOrderGroup.PUT("/:id/status", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateLabOrderStatus(c, db)
+		})
+		// Enter results by hand (Doctor, Staff and Admin)
+		labOrderGroup.POST("/:id/results", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.AddLabResults(c, db)
+		})
+		// Soft delete lab order without results (Doctor and Admin)
+		labOrderGroup.PUT("/:id/active-status", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.UpdateLabOrderActiveStatus(c, db)
+		})
+	}
+	consentTemplateGroup := router.Group("api/consent-templates")
+	{
+		// Create consent template (Admin only)
+		consentTemplateGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateConsentTemplate(c, db)
+		})
+		// Get consent templates, ?type_id= (all roles)
+		consentTemplateGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetConsentTemplates(c, db)
+		})
+		// Update consent template, generated consents keep their text (Admin only)
+		consentTemplateGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateConsentTemplate(c, db)
+		})
+		// Soft delete consent template (Admin only)
+		consentTemplateGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateConsentTemplateActiveStatus(c, db)
+		})
+	}
+	consentGroup := router.Group("api/consents")
+	{
+		// Get consent with its rendered text (all roles)
+		consentGroup.GET("/:id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetConsent(c, db)
+		})
+		// Get the captured signature image (all roles)
+		consentGroup.GET("/:id/signature", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetConsentSignature(c, db)
+		})
+		// Capture the owner's signature on the clinic device (all roles)
+		consentGroup.POST("/:id/sign", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.SignConsent(c, db)
+		})
+		// Revoke a pending or signed consent (Doctor and Admin)
+		consentGroup.PUT("/:id/revoke", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.RevokeConsent(c, db)
+		})
+	}
+	clinicProfileGroup := router.Group("api/clinic-profile")
+	{
+		// Get clinic branding printed on PDFs (all roles)
+		clinicProfileGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetClinicProfile(c, db)
+		})
+		// Update clinic name, address, contacts, footer and accent color (Admin only)
+		clinicProfileGroup.PUT("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateClinicProfile(c, db)
+		})
+		// Get clinic logo (all roles)
+		clinicProfileGroup.GET("/logo", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetClinicLogo(c, db)
+		})
+		// Upload clinic logo, PNG or JPEG as multipart file (Admin only)
+		clinicProfileGroup.PUT("/logo", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateClinicLogo(c, db)
+		})
+	}
+	searchGroup := router.Group("api/search")
+	{
+		// Full-text search over diagnoses, clinical notes, addenda and treatments; Staff only diagnoses and treatments (Doctor, Staff and Admin)
+		searchGroup.GET("/clinical", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.SearchClinicalNotes(c, db)
+		})
+	}
+	referralGroup := router.Group("api/referrals")
+	{
+		// Create an outgoing referral in Draft, Doctors refer as themselves (Doctor and Admin)
+		referralGroup.POST("", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateReferral(c, db)
+		})
+		// Get referrals, optional status and direction filters (all roles)
+		referralGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetReferrals(c, db)
+		})
+		// Import a signed package from another clinic as an incoming referral (all roles)
+		referralGroup.POST("/import", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.ImportReferral(c, db)
+		})
+		// Get referrals of a pet (all roles)
+		referralGroup.GET("/pet/:pet_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetReferralsByPet(c, db)
+		})
+		// Get referral (all roles)
+		referralGroup.GET("/:id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetReferral(c, db)
+		})
+		// Update a Draft referral (Doctor and Admin)
+		referralGroup.PUT("/:id", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.UpdateReferral(c, db)
+		})
+		// Move a referral along its status flow (all roles)
+		referralGroup.PUT("/:id/status", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateReferralStatus(c, db)
+		})
+		// Download the signed zip package, a Draft becomes Sent (Doctor and Admin)
+		referralGroup.GET("/:id/export", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.ExportReferral(c, db)
+		})
+		// List the files of an imported package (Doctor and Admin)
+		referralGroup.GET("/:id/documents", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetReferralDocuments(c, db)
+		})
+		// Download a file of an imported package (Doctor and Admin)
+		referralGroup.GET("/:id/documents/:document_id", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetReferralDocument(c, db)
+		})
+		// Soft delete / restore referral (Admin only)
+		referralGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateReferralActiveStatus(c, db)
+		})
+	}
+	invoiceGroup := router.Group("api/invoices")
+	{
+		// Create a Draft invoice from the appointment type price and treatments (Staff and Admin)
+		invoiceGroup.POST("/appointment/:appointment_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.CreateInvoice(c, db)
+		})
+		// Get invoices of an appointment (all roles)
+		invoiceGroup.GET("/appointment/:appointment_id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetInvoicesByAppointment(c, db)
+		})
+		// Get invoices of an owner, optional status filter (Staff and Admin)
+		invoiceGroup.GET("/owner/:owner_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetInvoicesByOwner(c, db)
+		})
+		// Get invoices, optional status and date filters (Staff and Admin)
+		invoiceGroup.GET("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetInvoices(c, db)
+		})
+		// Get invoice with its lines (all roles)
+		invoiceGroup.GET("/:id", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetInvoice(c, db)
+		})
+		// Update bill-to and notes of a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateInvoice(c, db)
+		})
+		// Copy the appointment type price and treatments again into a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id/refresh", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.RefreshInvoice(c, db)
+		})
+		// Add a line by hand to a Draft (Staff and Admin)
+		invoiceGroup.POST("/:id/lines", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.AddInvoiceLine(c, db)
+		})
+		// Update a line of a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id/lines/:line_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateInvoiceLine(c, db)
+		})
+		// Remove / restore a line of a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id/lines/:line_id/active-status", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateInvoiceLineActiveStatus(c, db)
+		})
+		// Discount a line of a Draft, Staff up to the clinic's staff discount limit (Staff and Admin)
+		invoiceGroup.PUT("/:id/lines/:line_id/discount", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.SetInvoiceLineDiscount(c, db)
+		})
+		// Remove the discount of a line of a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id/lines/:line_id/discount/remove", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.RemoveInvoiceLineDiscount(c, db)
+		})
+		// Discount the whole of a Draft, Staff up to the clinic's staff discount limit (Staff and Admin)
+		invoiceGroup.PUT("/:id/discount", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.SetInvoiceDiscount(c, db)
+		})
+		// Remove the invoice discount of a Draft (Staff and Admin)
+		invoiceGroup.PUT("/:id/discount/remove", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.RemoveInvoiceDiscount(c, db)
+		})
+		// Issue with the next invoice number, lines are frozen (Staff and Admin)
+		invoiceGroup.PUT("/:id/issue", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.IssueInvoice(c, db)
+		})
+		// Void a Draft (Staff and Admin) or an issued invoice (Admin only)
+		invoiceGroup.PUT("/:id/void", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.VoidInvoice(c, db)
+		})
+		// Soft delete a Draft or Void invoice (Admin only)
+		invoiceGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateInvoiceActiveStatus(c, db)
+		})
+	}
+	taxRateGroup := router.Group("api/tax-rates")
+	{
+		// Create a tax rate for a billing category (Admin only)
+		taxRateGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateTaxRate(c, db)
+		})
+		// Get tax rates (all roles)
+		taxRateGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetTaxRates(c, db)
+		})
+		// Update name or rate, Draft invoices follow, issued invoices keep their rate (Admin only)
+		taxRateGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateTaxRate(c, db)
+		})
+		// Soft delete / restore tax rate (Admin only)
+		taxRateGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateTaxRateActiveStatus(c, db)
+		})
+	}
+	paymentGroup := router.Group("api/payments")
+	{
+		// Record a payment split over one or more issued invoices (Staff and Admin)
+		paymentGroup.POST("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.CreatePayment(c, db)
+		})
+		// Get payments, optional method, status and date filters (Staff and Admin)
+		paymentGroup.GET("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPayments(c, db)
+		})
+		// Get payments of an invoice (Staff and Admin)
+		paymentGroup.GET("/invoice/:invoice_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPaymentsByInvoice(c, db)
+		})
+		// Get payments of an owner (Staff and Admin)
+		paymentGroup.GET("/owner/:owner_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPaymentsByOwner(c, db)
+		})
+		// Get what an owner was invoiced, paid and still owes (Staff and Admin)
+		paymentGroup.GET("/owner/:owner_id/balance", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetOwnerBalance(c, db)
+		})
+		// Get payment with its allocations (Staff and Admin)
+		paymentGroup.GET("/:id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPayment(c, db)
+		})
+		// Print the receipt (Staff and Admin)
+		paymentGroup.GET("/:id/receipt.pdf", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.GetPaymentReceiptPDF(c, db)
+		})
+		// Void a payment recorded by mistake (Admin only)
+		paymentGroup.PUT("/:id/void", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.VoidPayment(c, db)
+		})
+	}
+	waitlistGroup := router.Group("api/waitlist")
+	{
+		// Add pet to waitlist (Staff and Admin)
+		waitlistGroup.POST("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.CreateWaitlistEntry(c, db)
+		})
+		// Get waitlist by status, default Waiting (all roles)
+		waitlistGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetWaitlist(c, db)
+		})
+		// Decline offer / cancel waitlist entry (Staff and Admin)
+		waitlistGroup.PUT("/:id/status", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateWaitlistStatus(c, db)
+		})
+		// Convert waitlist entry into appointment (Staff and Admin)
+		waitlistGroup.POST("/:id/convert", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.ConvertWaitlistEntry(c, db)
+		})
+		// Soft delete waitlist entry (Staff and Admin)
+		waitlistGroup.PUT("/:id/active-status", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateWaitlistActiveStatus(c, db)
+		})
+	}
+	calendarGroup := router.Group("api/calendar")
+	{
+		// Create .ics feed token for doctor (Doctor for own feed, Admin)
+		calendarGroup.POST("/doctor/:doctor_id/tokens", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateCalendarToken(c, db)
+		})
+		// Get feed tokens of doctor (Doctor for own feed, Admin)
+		calendarGroup.GET("/doctor/:doctor_id/tokens", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetCalendarTokensByDoctorId(c, db)
+		})
+		// Revoke feed token (Doctor for own feed, Admin)
+		calendarGroup.PUT("/tokens/:id/revoke", middleware.JWTAuth("Doctor", "Admin"), func(c *gin.Context) {
+			controllers.RevokeCalendarToken(c, db)
+		})
+		// iCalendar subscription feed (token protected, no JWT)
+		calendarGroup.GET("/feed/:token", func(c *gin.Context) {
+			controllers.GetDoctorCalendarFeed(c, db)
+		})
+	}
+	appointmentTypesGroup := router.Group("api/appointment-types")
+	{
+		// Create appointment type (Admin only)
+		appointmentTypesGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateAppointmentType(c, db)
+		})
+		// Get service catalog (all roles)
+		appointmentTypesGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetAppointmentTypes(c, db)
+		})
+		// Update appointment type (Admin only)
+		appointmentTypesGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateAppointmentType(c, db)
+		})
+		// Soft delete appointment type (Admin only)
+		appointmentTypesGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateAppointmentTypeActiveStatus(c, db)
+		})
+		// Get resource requirements of an appointment type (all roles)
+		appointmentTypesGroup.GET("/:id/resources", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetAppointmentTypeResources(c, db)
+		})
+		// Replace resource requirements of an appointment type (Admin only)
+		appointmentTypesGroup.PUT("/:id/resources", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.SetAppointmentTypeResources(c, db)
+		})
+	}
+	ownersGroup := router.Group("api/owners")
+	{
+		// Owner login
+		ownersGroup.POST("/login", func(c *gin.Context) {
+			controllers.LoginOwner(c, db)
+		})
+		// Register owner account (Staff and Admin)
+		ownersGroup.POST("", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.RegisterOwner(c, db)
+		})
+		// Get owner profile with pets (all roles)
+		ownersGroup.GET("/:id/profile", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.FetchOwnerProfile(c, db)
+		})
+	}
+	ownerSelfGroup := router.Group("api/owner", middleware.JWTAuth("Owner"))
+	{
+		// Own profile and pets (Owner)
+		ownerSelfGroup.GET("/me", func(c *gin.Context) {
+			controllers.GetMyOwnerProfile(c, db)
+		})
+		// Available slots (Owner)
+		ownerSelfGroup.GET("/slots", func(c *gin.Context) {
+			controllers.GetAvailableSlots(c, db)
+		})
+		// Cancellation reasons an owner can pick (Owner)
+		ownerSelfGroup.GET("/cancellation-reasons", func(c *gin.Context) {
+			controllers.GetCancellationReasons(c, db)
+		})
+		// Upcoming visits (Owner)
+		ownerSelfGroup.GET("/appointments", func(c *gin.Context) {
+			controllers.GetMyUpcomingAppointments(c, db)
+		})
+		// Request appointment (Owner)
+		ownerSelfGroup.POST("/appointments", func(c *gin.Context) {
+			controllers.RequestMyAppointment(c, db)
+		})
+		// Cancel own appointment (Owner)
+		ownerSelfGroup.PUT("/appointments/:id/cancel", func(c *gin.Context) {
+			controllers.CancelMyAppointment(c, db)
+		})
+		// Issued and void invoices (Owner)
+		ownerSelfGroup.GET("/invoices", func(c *gin.Context) {
+			controllers.GetMyInvoices(c, db)
+		})
+		// Outstanding balance and open invoices (Owner)
+		ownerSelfGroup.GET("/balance", func(c *gin.Context) {
+			controllers.GetMyBalance(c, db)
+		})
+	}
+
+	resourcesGroup := router.Group("api/resources")
+	{
+		// Create resource (Admin only)
+		resourcesGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateResource(c, db)
+		})
+		// Get rooms/equipment, ?category= (all roles)
+		resourcesGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetResources(c, db)
+		})
+		// Update resource (Admin only)
+		resourcesGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateResource(c, db)
+		})
+		// Soft delete resource (Admin only)
+		resourcesGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateResourceActiveStatus(c, db)
+		})
+		// Get bookings of a resource for a day, ?date=YYYY-MM-DD (all roles)
+		resourcesGroup.GET("/:id/bookings", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetResourceBookings(c, db)
+		})
+	}
+
+	queueGroup := router.Group("api/queue")
+	{
+		// Get today's waiting-room board, ?all=true includes finished (all roles)
+		queueGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetQueue(c, db)
+		})
+		// Ticket for opening the live board from a browser EventSource (all roles)
+		queueGroup.POST("/stream/ticket", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.CreateQueueStreamTicket(c)
+		})
+		// Live board as server-sent events, JWT in header or ?ticket= (all roles)
+		queueGroup.GET("/stream", middleware.StreamTicketAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.StreamQueue(c, db)
+		})
+		// Check in today's appointment (Staff, Admin)
+		queueGroup.POST("/check-in/:appointment_id", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.CheckInAppointment(c, db)
+		})
+		// Add walk-in (Staff, Admin)
+		queueGroup.POST("/walk-in", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.CreateWalkIn(c, db)
+		})
+		// Update triage priority/notes (all roles)
+		queueGroup.PUT("/:id/triage", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.TriageQueueEntry(c, db)
+		})
+		// Move patient: Waiting, InRoom (with room), Done, Left (all roles)
+		queueGroup.PUT("/:id/status", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.UpdateQueueEntryStatus(c, db)
+		})
+		// Soft delete queue entry (Staff, Admin)
+		queueGroup.PUT("/:id/active-status", middleware.JWTAuth("Staff", "Admin"), func(c *gin.Context) {
+			controllers.UpdateQueueEntryActiveStatus(c, db)
+		})
+	}
+
+	diagnosesGroup := router.Group("api/diagnoses")
+	{
+		// Search diagnosis catalog for autocomplete, ?q=&species=&limit= (Doctor, Staff and Admin)
+		diagnosesGroup.GET("", middleware.JWTAuth("Doctor","Staff", "Admin"), func(c *gin.Context) {
+			controllers.SearchDiagnosisCodes(c, db)
+		})
+		// Create diagnosis code (Admin)
+		diagnosesGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateDiagnosisCode(c, db)
+		})
+		// Import terminology list from CSV (Admin)
+		diagnosesGroup.POST("/import", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.ImportDiagnosisCatalog(c, db)
+		})
+		// Update diagnosis code term, synonyms and species (Admin)
+		diagnosesGroup.PUT("/:id", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateDiagnosisCode(c, db)
+		})
+		// Diagnosis code soft delete (Admin)
+		diagnosesGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateDiagnosisCodeActiveStatus(c, db)
+		})
+	}
+	cancellationReasonsGroup := router.Group("api/cancellation-reasons")
+	{
+		// Create cancellation reason (Admin only)
+		cancellationReasonsGroup.POST("", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.CreateCancellationReason(c, db)
+		})
+		// Get cancellation reasons, ?party=Owner|Clinic (all roles)
+		cancellationReasonsGroup.GET("", middleware.JWTAuth("Staff","Doctor", "Admin"), func(c *gin.Context) {
+			controllers.GetCancellationReasons(c, db)
+		})
+		// Soft delete cancellation reason (Admin only)
+		cancellationReasonsGroup.PUT("/:id/active-status", middleware.JWTAuth("Admin"), func(c *gin.Context) {
+			controllers.UpdateCancellationReasonActiveStatus(c, db)
+		})
 	}
 }
\ No newline at end of file
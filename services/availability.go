@@ -0,0 +1,191 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+
+	"github.com/google/uuid"
+)
+
+// Querier is satisfied by both *sql.DB and *sql.Tx
+type Querier interface {
+    Exec(query string, args ...interface{}) (sql.Result, error)
+    Query(query string, args ...interface{}) (*sql.Rows, error)
+    QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// BookingRules are the clinic-defined limits for owner self-service booking (env configurable)
+type BookingRules struct {
+    OpenHour          int
+    CloseHour         int
+    SlotMinutes       int
+    MinLeadHours      int
+    MaxAdvanceDays    int
+    CancelNoticeHours int
+    LateCancelHours   int
+}
+
+func LoadBookingRules() BookingRules {
+    return BookingRules{
+        OpenHour:          utils.GetEnvInt("CLINIC_OPEN_HOUR", 9),
+        CloseHour:         utils.GetEnvInt("CLINIC_CLOSE_HOUR", 17),
+        SlotMinutes:       utils.GetEnvInt("BOOKING_SLOT_MINUTES", 30),
+        MinLeadHours:      utils.GetEnvInt("BOOKING_MIN_LEAD_HOURS", 2),
+        MaxAdvanceDays:    utils.GetEnvInt("BOOKING_MAX_ADVANCE_DAYS", 60),
+        CancelNoticeHours: utils.GetEnvInt("CANCEL_MIN_NOTICE_HOURS", 24),
+        LateCancelHours:   utils.GetEnvInt("LATE_CANCEL_WINDOW_HOURS", 24),
+    }
+}
+
+// AppointmentDuration returns the default duration of an appointment type, 30 minutes when untyped
+func AppointmentDuration(q Querier, typeId *uuid.UUID) (time.Duration, error) {
+    if typeId == nil {
+        return 30 * time.Minute, nil
+    }
+    var minutes int
+    err := q.QueryRow(`SELECT default_duration_minutes FROM "AppointmentTypes" WHERE id=$1 AND active_status=1`,
+        *typeId).Scan(&minutes)
+    if err != nil {
+        return 0, err
+    }
+    return time.Duration(minutes) * time.Minute, nil
+}
+
+// DoctorIsFree checks that the doctor has no other (non-cancelled) appointment overlapping [start, start+duration)
+func DoctorIsFree(q Querier, doctorId uuid.UUID, start time.Time, duration time.Duration, excludeAppointmentId *uuid.UUID) (bool, error) {
+    var busy bool
+    err := q.QueryRow(`SELECT EXISTS (
+                        SELECT 1 FROM "Appointments" a
+                        LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                        WHERE a.doctor_id=$1 AND a.active_status=1 AND a.status <> 'Cancelled'
+                        AND ($4::uuid IS NULL OR a.id <> $4)
+                        AND a.appointment_datetime < $3
+                        AND a.appointment_datetime + make_interval(mins => COALESCE(t.default_duration_minutes, 30)) > $2
+                    )`, doctorId, start, start.Add(duration), excludeAppointmentId).Scan(&busy)
+    return !busy, err
+}
+
+// WithinOpeningHours checks that [start, start+duration) fits into the clinic opening hours of that day
+func (r BookingRules) WithinOpeningHours(start time.Time, duration time.Duration) bool {
+    local := start.In(utils.ClinicLocation())
+    opensAt := time.Date(local.Year(), local.Month(), local.Day(), r.OpenHour, 0, 0, 0, local.Location())
+    closesAt := time.Date(local.Year(), local.Month(), local.Day(), r.CloseHour, 0, 0, 0, local.Location())
+    return !local.Before(opensAt) && !local.Add(duration).After(closesAt)
+}
+
+// IsLateCancellation: cancelled less than LATE_CANCEL_WINDOW_HOURS before the appointment
+func (r BookingRules) IsLateCancellation(appointmentDatetime time.Time) bool {
+    return time.Until(appointmentDatetime) < time.Duration(r.LateCancelHours)*time.Hour
+}
+
+// WithinBookingWindow checks the minimum lead time and maximum advance booking
+func (r BookingRules) WithinBookingWindow(start time.Time) bool {
+    now := time.Now()
+    return !start.Before(now.Add(time.Duration(r.MinLeadHours)*time.Hour)) &&
+        !start.After(now.AddDate(0, 0, r.MaxAdvanceDays))
+}
+
+// AvailableSlots lists free slots on the given clinic day for one doctor (or every user with the role the type requires)
+func AvailableSlots(db *sql.DB, day time.Time, doctorId *uuid.UUID, typeId *uuid.UUID, rules BookingRules) ([]structs.AvailableSlot, error) {
+    duration, err := AppointmentDuration(db, typeId)
+    if err != nil {
+        return nil, err
+    }
+    requiredRole := "Doctor"
+    if typeId != nil {
+        if err := db.QueryRow(`SELECT required_role FROM "AppointmentTypes" WHERE id=$1`, *typeId).Scan(&requiredRole); err != nil {
+            return nil, err
+        }
+    }
+
+    // 1. Candidate doctors
+    rows, err := db.Query(`SELECT id, name FROM "Users"
+                        WHERE role=$1 AND active_status=1 AND ($2::uuid IS NULL OR id=$2)
+                        ORDER BY name ASC`, requiredRole, doctorId)
+    if err != nil {
+        return nil, err
+    }
+    type doctor struct {
+        id   uuid.UUID
+        name string
+    }
+    var doctors []doctor
+    for rows.Next() {
+        var d doctor
+        if err := rows.Scan(&d.id, &d.name); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        doctors = append(doctors, d)
+    }
+    rows.Close()
+
+    // 2. Busy intervals of that day
+    loc := utils.ClinicLocation()
+    dayStart := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+    dayEnd := dayStart.AddDate(0, 0, 1)
+
+    busyRows, err := db.Query(`SELECT a.doctor_id, a.appointment_datetime, COALESCE(t.default_duration_minutes, 30)
+                        FROM "Appointments" a
+                        LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                        WHERE a.active_status=1 AND a.status <> 'Cancelled' AND a.doctor_id IS NOT NULL
+                        AND a.appointment_datetime >= $1 AND a.appointment_datetime < $2`,
+        dayStart.Add(-12*time.Hour), dayEnd)
+    if err != nil {
+        return nil, err
+    }
+    type interval struct{ start, end time.Time }
+    busy := map[uuid.UUID][]interval{}
+    for busyRows.Next() {
+        var (
+            id      uuid.UUID
+            start   time.Time
+            minutes int
+        )
+        if err := busyRows.Scan(&id, &start, &minutes); err != nil {
+            busyRows.Close()
+            return nil, err
+        }
+        busy[id] = append(busy[id], interval{start, start.Add(time.Duration(minutes) * time.Minute)})
+    }
+    busyRows.Close()
+
+    // 3. Rooms/equipment the appointment type needs
+    capacity, err := loadResourceCapacity(db, typeId, dayStart, dayEnd)
+    if err != nil {
+        return nil, err
+    }
+
+    // 4. Walk the opening hours
+    slots := []structs.AvailableSlot{}
+    step := time.Duration(rules.SlotMinutes) * time.Minute
+    if step <= 0 {
+        step = 30 * time.Minute
+    }
+    opensAt := dayStart.Add(time.Duration(rules.OpenHour) * time.Hour)
+    closesAt := dayStart.Add(time.Duration(rules.CloseHour) * time.Hour)
+    for start := opensAt; !start.Add(duration).After(closesAt); start = start.Add(step) {
+        if !rules.WithinBookingWindow(start) {
+            continue
+        }
+        end := start.Add(duration)
+        if !capacity.fits(start, end) {
+            continue
+        }
+        for _, d := range doctors {
+            free := true
+            for _, b := range busy[d.id] {
+                if b.start.Before(end) && b.end.After(start) {
+                    free = false
+                    break
+                }
+            }
+            if free {
+                slots = append(slots, structs.AvailableSlot{DoctorId: d.id, DoctorName: d.name, Start: start, End: end})
+            }
+        }
+    }
+    return slots, nil
+}
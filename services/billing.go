@@ -0,0 +1,273 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"vetclinic-rest-api/structs"
+
+	"github.com/google/uuid"
+)
+
+// BillableLines lists what an appointment is charged for: the service catalog price of its type
+// (when not zero) followed by the active treatments of its medical record, oldest first
+func BillableLines(q Querier, appointmentId uuid.UUID) ([]structs.InvoiceLine, error) {
+    lines := []structs.InvoiceLine{}
+
+    var typeId *uuid.UUID
+    var typeName, typeCategory string
+    var price int
+    err := q.QueryRow(`SELECT t.id, COALESCE(t.name, ''), COALESCE(t.billing_category, ''), COALESCE(t.default_price, 0)
+                    FROM "Appointments" a
+                    LEFT JOIN "AppointmentTypes" t ON t.id = a.appointment_type_id
+                    WHERE a.id=$1`, appointmentId).Scan(&typeId, &typeName, &typeCategory, &price)
+    if err != nil {
+        return nil, err
+    }
+    if typeId != nil && price > 0 {
+        lines = append(lines, structs.InvoiceLine{
+            Source: "AppointmentType", SourceId: typeId, Description: typeName, Category: typeCategory, Quantity: 1, UnitPrice: price,
+        })
+    }
+
+    rows, err := q.Query(`SELECT tr.id, tr.description, tr.billing_category, tr.cost
+                    FROM "Treatments" tr
+                    JOIN "MedicalRecords" m ON m.id = tr.medicalrecord_id AND m.active_status=1
+                    WHERE m.appointment_id=$1 AND tr.active_status=1
+                    ORDER BY tr.created_at ASC`, appointmentId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var treatmentId uuid.UUID
+        line := structs.InvoiceLine{Source: "Treatment", Quantity: 1}
+        if err := rows.Scan(&treatmentId, &line.Description, &line.Category, &line.UnitPrice); err != nil {
+            return nil, err
+        }
+        line.SourceId = &treatmentId
+        lines = append(lines, line)
+    }
+    return lines, rows.Err()
+}
+
+// NextInvoiceNumber takes the next number of the clinic's invoice sequence. Call it in the transaction that
+// issues the invoice: the row stays locked until commit and a rollback gives the number back.
+func NextInvoiceNumber(q Querier, clinicProfileId uuid.UUID) (string, error) {
+    return nextDocumentNumber(q, clinicProfileId, "invoice")
+}
+
+// NextReceiptNumber takes the next number of the clinic's receipt sequence, see NextInvoiceNumber
+func NextReceiptNumber(q Querier, clinicProfileId uuid.UUID) (string, error) {
+    return nextDocumentNumber(q, clinicProfileId, "receipt")
+}
+
+// nextDocumentNumber increments the <kind>_prefix / next_<kind>_number pair of the clinic profile
+func nextDocumentNumber(q Querier, clinicProfileId uuid.UUID, kind string) (string, error) {
+    var prefix string
+    var number int
+    err := q.QueryRow(`UPDATE "ClinicProfile" SET next_`+kind+`_number = next_`+kind+`_number + 1
+                    WHERE id=$1
+                    RETURNING `+kind+`_prefix, next_`+kind+`_number - 1`, clinicProfileId).Scan(&prefix, &number)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s%06d", prefix, number), nil
+}
+
+// Discount types of invoice lines and invoices
+const (
+    DiscountPercent = "Percent"
+    DiscountFixed   = "Fixed"
+)
+
+// percentOf is percent of amount rounded half up to a whole unit, the percent is taken with two decimals
+func percentOf(amount int, percent float64) int {
+    basisPoints := int(math.Round(percent * 100))
+    return (amount*basisPoints + 5000) / 10000
+}
+
+// DiscountAmount is what a discount takes off base: a percentage of it, or a fixed sum capped at base
+func DiscountAmount(base int, discountType string, value float64) int {
+    amount := 0
+    switch discountType {
+    case DiscountPercent:
+        amount = percentOf(base, value)
+    case DiscountFixed:
+        amount = int(math.Round(value))
+    }
+    if amount > base {
+        amount = base
+    }
+    if amount < 0 {
+        amount = 0
+    }
+    return amount
+}
+
+// CalculateInvoiceTotals works out every amount of an invoice and its lines, nowhere else computes them:
+//  1. amount = quantity * unit price
+//  2. the line discount comes off the amount
+//  3. the invoice discount comes off what is left of all lines, spread over the lines in proportion
+//  4. tax at the line's rate on what is left of the line, rounded per line
+//  5. the grand total is rounded half up to a multiple of roundingUnit, the difference is the rounding adjustment
+// Percentages are rounded half up to whole units where they are applied.
+func CalculateInvoiceTotals(invoice *structs.Invoice, lines []structs.InvoiceLine, roundingUnit int) {
+    invoice.Subtotal, invoice.DiscountTotal, invoice.TaxTotal = 0, 0, 0
+
+    net := make([]int, len(lines))
+    base := 0
+    for i := range lines {
+        l := &lines[i]
+        l.Amount = l.Quantity * l.UnitPrice
+        l.DiscountAmount = DiscountAmount(l.Amount, l.DiscountType, l.DiscountValue)
+        net[i] = l.Amount - l.DiscountAmount
+        base += net[i]
+        invoice.Subtotal += l.Amount
+        invoice.DiscountTotal += l.DiscountAmount
+    }
+    invoice.DiscountAmount = DiscountAmount(base, invoice.DiscountType, invoice.DiscountValue)
+    invoice.DiscountTotal += invoice.DiscountAmount
+
+    shares := spread(invoice.DiscountAmount, net)
+    total := 0
+    for i := range lines {
+        l := &lines[i]
+        l.InvoiceDiscountShare = shares[i]
+        l.TaxAmount = percentOf(net[i]-shares[i], l.TaxRate)
+        l.Total = net[i] - shares[i] + l.TaxAmount
+        invoice.TaxTotal += l.TaxAmount
+        total += l.Total
+    }
+
+    if roundingUnit < 1 {
+        roundingUnit = 1
+    }
+    invoice.Total = (2*total + roundingUnit) / (2 * roundingUnit) * roundingUnit
+    invoice.RoundingAdjustment = invoice.Total - total
+}
+
+// spread splits amount (at most the sum of weights) in proportion to the weights. What rounding down
+// leaves over goes one unit each to the largest remainders, earlier lines first on ties.
+func spread(amount int, weights []int) []int {
+    shares := make([]int, len(weights))
+    sum := 0
+    for _, w := range weights {
+        sum += w
+    }
+    if amount == 0 || sum == 0 {
+        return shares
+    }
+
+    given := 0
+    remainders := make([]int, len(weights))
+    order := make([]int, len(weights))
+    for i, w := range weights {
+        shares[i] = amount * w / sum
+        remainders[i] = amount * w % sum
+        given += shares[i]
+        order[i] = i
+    }
+    sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+    for k := 0; given < amount; k++ {
+        shares[order[k]]++
+        given++
+    }
+    return shares
+}
+
+// RecalculateInvoice stores the amounts of an invoice and its active lines, see CalculateInvoiceTotals.
+// Lines take the current rate of their category, so only call it while the invoice is a Draft
+// and when issuing it: the rates of an issued invoice stay the ones it was issued with.
+func RecalculateInvoice(q Querier, invoiceId uuid.UUID) error {
+    var invoice structs.Invoice
+    var roundingUnit int
+    err := q.QueryRow(`SELECT i.id, COALESCE(i.discount_type, ''), COALESCE(i.discount_value, 0), cp.rounding_unit
+                    FROM "Invoices" i
+                    JOIN "ClinicProfile" cp ON cp.id = i.clinic_profile_id
+                    WHERE i.id=$1`, invoiceId).Scan(&invoice.Id, &invoice.DiscountType, &invoice.DiscountValue, &roundingUnit)
+    if err != nil {
+        return err
+    }
+
+    rows, err := q.Query(`SELECT l.id, l.quantity, l.unit_price, COALESCE(l.discount_type, ''), COALESCE(l.discount_value, 0),
+                        COALESCE(t.rate, 0)
+                    FROM "InvoiceLines" l
+                    LEFT JOIN "TaxRates" t ON lower(t.category) = lower(l.category) AND t.active_status=1
+                    WHERE l.invoice_id=$1 AND l.active_status=1
+                    ORDER BY l.source = 'Manual' ASC, l.sort_order ASC, l.created_at ASC`, invoiceId)
+    if err != nil {
+        return err
+    }
+    lines := []structs.InvoiceLine{}
+    for rows.Next() {
+        var l structs.InvoiceLine
+        if err := rows.Scan(&l.Id, &l.Quantity, &l.UnitPrice, &l.DiscountType, &l.DiscountValue, &l.TaxRate); err != nil {
+            rows.Close()
+            return err
+        }
+        lines = append(lines, l)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    CalculateInvoiceTotals(&invoice, lines, roundingUnit)
+
+    for _, l := range lines {
+        _, err := q.Exec(`UPDATE "InvoiceLines"
+                    SET amount=$1, discount_amount=$2, invoice_discount_share=$3, tax_rate=$4, tax_amount=$5, total=$6
+                    WHERE id=$7`,
+            l.Amount, l.DiscountAmount, l.InvoiceDiscountShare, l.TaxRate, l.TaxAmount, l.Total, l.Id,
+        )
+        if err != nil {
+            return err
+        }
+    }
+    _, err = q.Exec(`UPDATE "Invoices"
+                    SET subtotal=$1, discount_amount=$2, discount_total=$3, tax_total=$4, rounding_adjustment=$5, total=$6
+                    WHERE id=$7`,
+        invoice.Subtotal, invoice.DiscountAmount, invoice.DiscountTotal, invoice.TaxTotal, invoice.RoundingAdjustment,
+        invoice.Total, invoice.Id,
+    )
+    return err
+}
+
+// InvoicePaid is the amount of recorded payments allocated to an invoice
+func InvoicePaid(q Querier, invoiceId uuid.UUID) (int, error) {
+    var paid int
+    err := q.QueryRow(`SELECT COALESCE(SUM(pa.amount), 0)
+                    FROM "PaymentAllocations" pa
+                    JOIN "Payments" p ON p.id = pa.payment_id AND p.status='Recorded' AND p.active_status=1
+                    WHERE pa.invoice_id=$1 AND pa.active_status=1`, invoiceId).Scan(&paid)
+    return paid, err
+}
+
+// InvoicePaidAsOf is InvoicePaid as it stood right after a payment: the payment itself plus the recorded payments
+// received before it, so a receipt printed again later still shows the balance left on that day
+func InvoicePaidAsOf(q Querier, invoiceId uuid.UUID, paymentId uuid.UUID) (int, error) {
+    var paid int
+    err := q.QueryRow(`SELECT COALESCE(SUM(pa.amount), 0)
+                    FROM "PaymentAllocations" pa
+                    JOIN "Payments" p ON p.id = pa.payment_id AND p.active_status=1
+                    JOIN "Payments" this ON this.id = $2
+                    WHERE pa.invoice_id=$1 AND pa.active_status=1
+                    AND (p.id = this.id
+                        OR (p.status='Recorded' AND (p.received_at, p.created_at, p.receipt_number) < (this.received_at, this.created_at, this.receipt_number)))`,
+        invoiceId, paymentId).Scan(&paid)
+    return paid, err
+}
+
+// PaymentStatus names how far an issued invoice is paid, other invoices have none
+func PaymentStatus(status string, total, paid int) string {
+    switch {
+    case status != "Issued":
+        return ""
+    case paid >= total:
+        return "Paid"
+    case paid > 0:
+        return "PartiallyPaid"
+    }
+    return "Unpaid"
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"vetclinic-rest-api/structs"
+)
+
+func TestSpread(t *testing.T) {
+    tests := []struct {
+        name    string
+        amount  int
+        weights []int
+        want    []int
+    }{
+        {"nothing to spread", 0, []int{100, 200}, []int{0, 0}},
+        {"no weights", 50, []int{0, 0}, []int{0, 0}},
+        {"exact proportion", 30, []int{100, 200}, []int{10, 20}},
+        {"leftover to the largest remainder", 5, []int{100, 50, 50}, []int{3, 1, 1}},
+        {"ties go to earlier lines", 10, []int{1, 1, 1}, []int{4, 3, 3}},
+        {"larger remainder wins over order", 1, []int{1, 2}, []int{0, 1}},
+        {"whole weight", 300, []int{100, 200}, []int{100, 200}},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := spread(tt.amount, tt.weights)
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Fatalf("spread(%d, %v) = %v, want %v", tt.amount, tt.weights, got, tt.want)
+            }
+            sum := 0
+            for _, share := range got {
+                sum += share
+            }
+            weightSum := 0
+            for _, w := range tt.weights {
+                weightSum += w
+            }
+            if weightSum > 0 && sum != tt.amount {
+                t.Fatalf("spread(%d, %v) gives %d in total", tt.amount, tt.weights, sum)
+            }
+        })
+    }
+}
+
+func TestPercentOfRoundsHalfUp(t *testing.T) {
+    tests := []struct {
+        amount  int
+        percent float64
+        want    int
+    }{
+        {1000, 10, 100},
+        {105, 10, 11},   // 10.5
+        {104, 10, 10},   // 10.4
+        {810, 11, 89},   // 89.1
+        {1000, 12.5, 125},
+        {999, 33.33, 333}, // 332.97
+        {0, 50, 0},
+    }
+    for _, tt := range tests {
+        if got := percentOf(tt.amount, tt.percent); got != tt.want {
+            t.Errorf("percentOf(%d, %v) = %d, want %d", tt.amount, tt.percent, got, tt.want)
+        }
+    }
+}
+
+func TestDiscountAmount(t *testing.T) {
+    tests := []struct {
+        base         int
+        discountType string
+        value        float64
+        want         int
+    }{
+        {1000, DiscountPercent, 10, 100},
+        {1000, DiscountPercent, 100, 1000},
+        {1000, DiscountFixed, 250, 250},
+        {200, DiscountFixed, 250, 200}, // capped at what it comes off
+        {1000, "", 10, 0},
+    }
+    for _, tt := range tests {
+        if got := DiscountAmount(tt.base, tt.discountType, tt.value); got != tt.want {
+            t.Errorf("DiscountAmount(%d, %q, %v) = %d, want %d", tt.base, tt.discountType, tt.value, got, tt.want)
+        }
+    }
+}
+
+func TestCalculateInvoiceTotals(t *testing.T) {
+    invoice := structs.Invoice{DiscountType: DiscountFixed, DiscountValue: 120}
+    lines := []structs.InvoiceLine{
+        {Quantity: 2, UnitPrice: 500, DiscountType: DiscountPercent, DiscountValue: 10, TaxRate: 11},
+        {Quantity: 1, UnitPrice: 300},
+    }
+
+    CalculateInvoiceTotals(&invoice, lines, 1)
+
+    // line 1: 1000 - 100 line discount = 900, takes 90 of the invoice discount, 11% tax on 810 = 89.1
+    // line 2: 300, takes 30 of the invoice discount, no tax
+    wantLines := []struct{ amount, discount, share, tax, total int }{
+        {1000, 100, 90, 89, 899},
+        {300, 0, 30, 0, 270},
+    }
+    for i, want := range wantLines {
+        l := lines[i]
+        if l.Amount != want.amount || l.DiscountAmount != want.discount || l.InvoiceDiscountShare != want.share ||
+            l.TaxAmount != want.tax || l.Total != want.total {
+            t.Errorf("line %d = amount %d, discount %d, share %d, tax %d, total %d; want %+v",
+                i, l.Amount, l.DiscountAmount, l.InvoiceDiscountShare, l.TaxAmount, l.Total, want)
+        }
+    }
+    if invoice.Subtotal != 1300 || invoice.DiscountAmount != 120 || invoice.DiscountTotal != 220 || invoice.TaxTotal != 89 {
+        t.Errorf("invoice = subtotal %d, discount %d, discount total %d, tax %d; want 1300, 120, 220, 89",
+            invoice.Subtotal, invoice.DiscountAmount, invoice.DiscountTotal, invoice.TaxTotal)
+    }
+    if invoice.Total != 1169 || invoice.RoundingAdjustment != 0 {
+        t.Errorf("invoice total %d (rounding %d), want 1169 (0)", invoice.Total, invoice.RoundingAdjustment)
+    }
+    if invoice.Total != invoice.Subtotal-invoice.DiscountTotal+invoice.TaxTotal+invoice.RoundingAdjustment {
+        t.Errorf("total %d doesn't add up", invoice.Total)
+    }
+}
+
+func TestCalculateInvoiceTotalsInvoiceDiscountComesOffLineDiscounts(t *testing.T) {
+    invoice := structs.Invoice{DiscountType: DiscountPercent, DiscountValue: 50}
+    lines := []structs.InvoiceLine{
+        {Quantity: 1, UnitPrice: 1000, DiscountType: DiscountFixed, DiscountValue: 400},
+    }
+
+    CalculateInvoiceTotals(&invoice, lines, 1)
+
+    if invoice.DiscountAmount != 300 || invoice.DiscountTotal != 700 || invoice.Total != 300 {
+        t.Errorf("invoice discount %d, discount total %d, total %d; want 300, 700, 300",
+            invoice.DiscountAmount, invoice.DiscountTotal, invoice.Total)
+    }
+}
+
+func TestCalculateInvoiceTotalsRounding(t *testing.T) {
+    tests := []struct {
+        name         string
+        unitPrice    int
+        roundingUnit int
+        wantTotal    int
+    }{
+        {"unit of 1 keeps the total", 1169, 1, 1169},
+        {"rounds down below half", 1169, 50, 1150},
+        {"rounds half up", 1175, 50, 1200},
+        {"rounds up above half", 1176, 50, 1200},
+        {"already a multiple", 1200, 100, 1200},
+        {"unit below 1 is taken as 1", 1169, 0, 1169},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var invoice structs.Invoice
+            lines := []structs.InvoiceLine{{Quantity: 1, UnitPrice: tt.unitPrice}}
+            CalculateInvoiceTotals(&invoice, lines, tt.roundingUnit)
+            if invoice.Total != tt.wantTotal {
+                t.Fatalf("total = %d, want %d", invoice.Total, tt.wantTotal)
+            }
+            if invoice.RoundingAdjustment != tt.wantTotal-tt.unitPrice {
+                t.Fatalf("rounding adjustment = %d, want %d", invoice.RoundingAdjustment, tt.wantTotal-tt.unitPrice)
+            }
+        })
+    }
+}
+
+func TestCalculateInvoiceTotalsWithoutLines(t *testing.T) {
+    invoice := structs.Invoice{DiscountType: DiscountFixed, DiscountValue: 100}
+    CalculateInvoiceTotals(&invoice, nil, 50)
+    if invoice.Subtotal != 0 || invoice.DiscountTotal != 0 || invoice.Total != 0 || invoice.RoundingAdjustment != 0 {
+        t.Errorf("empty invoice = %+v, want all zero", invoice)
+    }
+}
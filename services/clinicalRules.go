@@ -0,0 +1,82 @@
+package services
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// RuleViolation is a broken clinical invariant, Status is the HTTP status to answer with
+// (409 when the current state conflicts with the request, 422 when the request references the wrong thing)
+type RuleViolation struct {
+    Status  int
+    Message string
+}
+
+func (e *RuleViolation) Error() string {
+    return e.Message
+}
+
+func conflict(message string) *RuleViolation {
+    return &RuleViolation{Status: http.StatusConflict, Message: message}
+}
+
+func unprocessable(message string) *RuleViolation {
+    return &RuleViolation{Status: http.StatusUnprocessableEntity, Message: message}
+}
+
+// CheckMedicalRecordAppointment checks that a new medical record can be written for the appointment and returns
+// the appointment's pet, which the record belongs to. A petId other than uuid.Nil must be that pet.
+func CheckMedicalRecordAppointment(q Querier, appointmentId, petId uuid.UUID) (uuid.UUID, error) {
+    var appointmentPetId uuid.UUID
+    var status string
+    err := q.QueryRow(`SELECT pet_id, status FROM "Appointments" WHERE id=$1 AND active_status=1`,
+        appointmentId).Scan(&appointmentPetId, &status)
+    if err == sql.ErrNoRows {
+        return uuid.Nil, unprocessable("Appointment not found")
+    }
+    if err != nil {
+        return uuid.Nil, err
+    }
+    if petId != uuid.Nil && petId != appointmentPetId {
+        return uuid.Nil, unprocessable("PetId doesn't match the pet of the appointment")
+    }
+    if status == "Cancelled" || status == "NoShow" {
+        return uuid.Nil, conflict("Appointment is " + status + ", medical records can't be added")
+    }
+
+    var exists bool
+    err = q.QueryRow(`SELECT EXISTS (SELECT 1 FROM "MedicalRecords" WHERE appointment_id=$1 AND active_status=1)`,
+        appointmentId).Scan(&exists)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    if exists {
+        return uuid.Nil, conflict("Appointment already has a medical record")
+    }
+    return appointmentPetId, nil
+}
+
+// CheckTreatingDoctor checks that doctorId is an active user with the Doctor role
+func CheckTreatingDoctor(q Querier, doctorId uuid.UUID) error {
+    if doctorId == uuid.Nil {
+        return unprocessable("DoctorId is required")
+    }
+    var role string
+    err := q.QueryRow(`SELECT role FROM "Users" WHERE id=$1 AND active_status=1`, doctorId).Scan(&role)
+    if err == sql.ErrNoRows || (err == nil && role != "Doctor") {
+        return unprocessable("The treating doctor must be an active Doctor")
+    }
+    return err
+}
+
+// DuplicateMedicalRecord turns the unique index violation of a concurrent insert into the same error
+// CheckMedicalRecordAppointment gives, other errors are returned unchanged
+func DuplicateMedicalRecord(err error) error {
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" && pqErr.Constraint == "medicalrecords_appointment_id_active_key" {
+        return conflict("Appointment already has a medical record")
+    }
+    return err
+}
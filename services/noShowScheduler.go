@@ -0,0 +1,55 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"time"
+	"vetclinic-rest-api/utils"
+)
+
+// StartNoShowScheduler marks pending appointments as NoShow when the pet never checked in.
+// Only appointments that ended within NO_SHOW_LOOKBACK_HOURS are considered, so old history is never rewritten.
+func StartNoShowScheduler(db *sql.DB) {
+    interval := time.Duration(utils.GetEnvPositiveInt("NO_SHOW_SCAN_INTERVAL_SECONDS", 300)) * time.Second
+    grace := utils.GetEnvInt("NO_SHOW_GRACE_MINUTES", 30)
+    lookback := time.Duration(utils.GetEnvInt("NO_SHOW_LOOKBACK_HOURS", 24)) * time.Hour
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            if err := markNoShows(db, grace, lookback); err != nil {
+                log.Println("Error marking no-shows:", err)
+            }
+        }
+    }()
+}
+
+func markNoShows(db *sql.DB, graceMinutes int, lookback time.Duration) error {
+    now := time.Now()
+
+    // a visit with a medical record obviously happened, even if the front desk skipped the check-in
+    rows, err := db.Query(`UPDATE "Appointments" a
+                        SET status='NoShow', no_show_at=$1, modified_at=$2, modified_by='system'
+                        WHERE a.status='Pending' AND a.active_status=1
+                        AND a.appointment_datetime >= $3
+                        AND a.appointment_datetime + make_interval(mins => $4 + COALESCE(
+                            (SELECT t.default_duration_minutes FROM "AppointmentTypes" t WHERE t.id = a.appointment_type_id), 30
+                        )) < $1
+                        AND NOT EXISTS (SELECT 1 FROM "QueueEntries" q WHERE q.appointment_id = a.id AND q.active_status=1)
+                        AND NOT EXISTS (SELECT 1 FROM "MedicalRecords" m WHERE m.appointment_id = a.id AND m.active_status=1)
+                        RETURNING a.id`, now, now, now.Add(-lookback), graceMinutes)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    marked := 0
+    for rows.Next() {
+        marked++
+    }
+    if marked > 0 {
+        log.Println("Appointments marked as no-show:", marked)
+    }
+    return rows.Err()
+}
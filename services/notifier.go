@@ -0,0 +1,147 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"vetclinic-rest-api/structs"
+	"vetclinic-rest-api/utils"
+)
+
+// Sender delivers one outbox message over a channel (SMS, Email, ...)
+type Sender interface {
+    Send(n structs.Notification) error
+}
+
+// FileSender writes every message as a JSON line to <dir>/<channel>.log, for dev/testing
+type FileSender struct {
+    Dir string
+    mu  sync.Mutex
+}
+
+func NewFileSender(dir string) *FileSender {
+    return &FileSender{Dir: dir}
+}
+
+func (s *FileSender) Send(n structs.Notification) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+        return err
+    }
+    f, err := os.OpenFile(filepath.Join(s.Dir, n.Channel+".log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    line, err := json.Marshal(map[string]interface{}{
+        "id":        n.Id,
+        "kind":      n.Kind,
+        "recipient": n.Recipient,
+        "subject":   n.Subject,
+        "message":   n.Message,
+        "sent_at":   time.Now(),
+    })
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(append(line, '\n'))
+    return err
+}
+
+// StartNotificationDispatcher delivers pending outbox messages, retrying failures with exponential backoff
+func StartNotificationDispatcher(db *sql.DB, senders map[string]Sender) {
+    interval := time.Duration(utils.GetEnvPositiveInt("NOTIFY_DISPATCH_INTERVAL_SECONDS", 15)) * time.Second
+    maxAttempts := utils.GetEnvPositiveInt("NOTIFY_MAX_ATTEMPTS", 5)
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            if err := dispatchPending(db, senders, maxAttempts); err != nil {
+                log.Println("Error dispatching notifications:", err)
+            }
+        }
+    }()
+}
+
+// sendingLease is how long a claimed message may stay in Sending before another run picks it up again
+// (the instance that claimed it crashed or lost its connection mid-send)
+const sendingLease = 5 * time.Minute
+
+func dispatchPending(db *sql.DB, senders map[string]Sender, maxAttempts int) error {
+    for i := 0; i < 50; i++ {
+        n, err := claimNotification(db)
+        if err == sql.ErrNoRows {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        if err := deliverNotification(db, senders, n, maxAttempts); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// claimNotification marks the next due message as Sending and commits right away, so parallel
+// instances never deliver it twice and no row lock is held during the external call
+func claimNotification(db *sql.DB) (structs.Notification, error) {
+    var n structs.Notification
+    now := time.Now()
+    err := db.QueryRow(`UPDATE "Notifications"
+                    SET status='Sending', next_attempt_at=$2, modified_at=$1, modified_by='system'
+                    WHERE id = (
+                        SELECT id FROM "Notifications"
+                        WHERE active_status=1
+                        AND (status='Pending' OR status='Sending')
+                        AND (next_attempt_at IS NULL OR next_attempt_at <= $1)
+                        ORDER BY created_at ASC
+                        LIMIT 1
+                        FOR UPDATE SKIP LOCKED
+                    )
+                    RETURNING id, channel, kind, recipient, COALESCE(subject, ''), message, attempts`,
+        now, now.Add(sendingLease)).Scan(&n.Id, &n.Channel, &n.Kind, &n.Recipient, &n.Subject, &n.Message, &n.Attempts)
+    return n, err
+}
+
+func deliverNotification(db *sql.DB, senders map[string]Sender, n structs.Notification, maxAttempts int) error {
+    sendErr := fmt.Errorf("no sender configured for channel %s", n.Channel)
+    if sender, ok := senders[n.Channel]; ok {
+        sendErr = sender.Send(n)
+    }
+
+    var err error
+    attempts := n.Attempts + 1
+    if sendErr == nil {
+        _, err = db.Exec(`UPDATE "Notifications"
+                        SET status='Sent', attempts=$1, sent_at=$2, last_error=NULL, modified_at=$2, modified_by='system'
+                        WHERE id=$3 AND status='Sending'`, attempts, time.Now(), n.Id)
+    } else if attempts >= maxAttempts {
+        _, err = db.Exec(`UPDATE "Notifications"
+                        SET status='Failed', attempts=$1, last_error=$2, modified_at=$3, modified_by='system'
+                        WHERE id=$4 AND status='Sending'`, attempts, sendErr.Error(), time.Now(), n.Id)
+    } else {
+        _, err = db.Exec(`UPDATE "Notifications"
+                        SET status='Pending', attempts=$1, last_error=$2, next_attempt_at=$3, modified_at=$4, modified_by='system'
+                        WHERE id=$5 AND status='Sending'`, attempts, sendErr.Error(), time.Now().Add(retryBackoff(attempts)), time.Now(), n.Id)
+    }
+    return err
+}
+
+// retryBackoff: 1m, 2m, 4m, ... capped at 1h
+func retryBackoff(attempts int) time.Duration {
+    backoff := time.Minute << (attempts - 1)
+    if backoff > time.Hour || backoff <= 0 {
+        return time.Hour
+    }
+    return backoff
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"vetclinic-rest-api/structs"
+)
+
+// QueueEvent is one change of the waiting-room board pushed to SSE subscribers
+type QueueEvent struct {
+    Action string             `json:"action"` // CheckedIn, WalkIn, Triaged, StatusChanged, Removed
+    Entry  structs.QueueEntry `json:"entry"`
+}
+
+// queueBroker fans queue changes out to every connected dashboard (in-process, per API instance)
+type queueBroker struct {
+    mu          sync.Mutex
+    subscribers map[chan QueueEvent]struct{}
+}
+
+var queue = &queueBroker{subscribers: map[chan QueueEvent]struct{}{}}
+
+// SubscribeQueue registers a dashboard; call UnsubscribeQueue when the client disconnects
+func SubscribeQueue() chan QueueEvent {
+    ch := make(chan QueueEvent, 16)
+    queue.mu.Lock()
+    queue.subscribers[ch] = struct{}{}
+    queue.mu.Unlock()
+    return ch
+}
+
+func UnsubscribeQueue(ch chan QueueEvent) {
+    queue.mu.Lock()
+    delete(queue.subscribers, ch)
+    queue.mu.Unlock()
+}
+
+// PublishQueueEvent never blocks the request: a dashboard that doesn't keep up misses the event
+// and catches up with the snapshot on reconnect
+func PublishQueueEvent(action string, entry structs.QueueEntry) {
+    event := QueueEvent{Action: action, Entry: entry}
+    queue.mu.Lock()
+    defer queue.mu.Unlock()
+    for ch := range queue.subscribers {
+        select {
+        case ch <- event:
+        default:
+            log.Println("Queue subscriber is too slow, dropping event for entry:", entry.Id)
+        }
+    }
+}
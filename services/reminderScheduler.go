@@ -0,0 +1,112 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	"vetclinic-rest-api/utils"
+
+	"github.com/google/uuid"
+)
+
+// StartReminderScheduler scans upcoming appointments and writes reminders (default 48h and 2h before) to the outbox
+func StartReminderScheduler(db *sql.DB) {
+    interval := time.Duration(utils.GetEnvPositiveInt("REMINDER_SCAN_INTERVAL_SECONDS", 60)) * time.Second
+    offsets := utils.GetEnvIntList("REMINDER_OFFSETS_HOURS", []int{48, 2})
+    sort.Ints(offsets)
+
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for range ticker.C {
+            if err := scheduleReminders(db, offsets); err != nil {
+                log.Println("Error scheduling appointment reminders:", err)
+            }
+        }
+    }()
+}
+
+func scheduleReminders(db *sql.DB, offsets []int) error {
+    now := time.Now()
+
+    // offsets are ascending; each appointment only gets the closest reminder still ahead of it,
+    // so an appointment booked 1h in advance doesn't receive the 48h and 2h reminders at once
+    lowerBound := now
+    for _, hours := range offsets {
+        upperBound := now.Add(time.Duration(hours) * time.Hour)
+        if err := createReminders(db, fmt.Sprintf("Reminder%dh", hours), lowerBound, upperBound); err != nil {
+            return err
+        }
+        lowerBound = upperBound
+    }
+    return nil
+}
+
+func createReminders(db *sql.DB, kind string, from, to time.Time) error {
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    // Registered owners are reached on their own phone first, then the phone on the pet, then by email
+    rows, err := tx.Query(`SELECT a.id, a.appointment_datetime, p.name,
+                            COALESCE(NULLIF(o.phone, ''), NULLIF(p.owner_phone, ''), ''), COALESCE(o.email, '')
+                        FROM "Appointments" a
+                        JOIN "Pets" p ON p.id = a.pet_id
+                        LEFT JOIN "Owners" o ON o.id = p.owner_id AND o.active_status=1
+                        WHERE a.status='Pending' AND a.active_status=1
+                        AND a.appointment_datetime > $1 AND a.appointment_datetime <= $2
+                        AND (COALESCE(o.phone, '') <> '' OR COALESCE(p.owner_phone, '') <> '' OR COALESCE(o.email, '') <> '')
+                        AND NOT EXISTS (
+                            SELECT 1 FROM "Notifications" n WHERE n.appointment_id = a.id AND n.kind = $3
+                        )`, from, to, kind)
+    if err != nil {
+        return err
+    }
+
+    type reminder struct {
+        appointmentId uuid.UUID
+        datetime      time.Time
+        petName       string
+        ownerPhone    string
+        ownerEmail    string
+    }
+    var reminders []reminder
+    for rows.Next() {
+        var r reminder
+        if err := rows.Scan(&r.appointmentId, &r.datetime, &r.petName, &r.ownerPhone, &r.ownerEmail); err != nil {
+            rows.Close()
+            return err
+        }
+        reminders = append(reminders, r)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    now := time.Now()
+    for _, r := range reminders {
+        channel, recipient := "SMS", r.ownerPhone
+        if recipient == "" {
+            channel, recipient = "Email", r.ownerEmail
+        }
+        message := fmt.Sprintf("Reminder: %s has an appointment at VetClinic on %s.",
+            r.petName, r.datetime.In(utils.ClinicLocation()).Format("02 Jan 2006 15:04"))
+        _, err = tx.Exec(`INSERT INTO "Notifications"
+                        (id, appointment_id, kind, channel, recipient, subject, message, status,
+                        attempts, next_attempt_at, active_status, created_at, created_by, modified_at, modified_by)
+                        VALUES ($1,$2,$3,$4,$5,$6,$7,'Pending',0,$8,1,$8,'system',$8,'system')
+                        ON CONFLICT DO NOTHING`,
+            uuid.New(), r.appointmentId, kind, channel, recipient, "Appointment reminder", message, now,
+        )
+        if err != nil {
+            return err
+        }
+    }
+
+    return tx.Commit()
+}
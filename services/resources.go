@@ -0,0 +1,180 @@
+package services
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResourceUnavailableError: no free resource of a required category in the requested time range
+type ResourceUnavailableError struct {
+    Category string
+}
+
+func (e *ResourceUnavailableError) Error() string {
+    return "No " + e.Category + " available at this time"
+}
+
+type resourceRequirement struct {
+    category string
+    quantity int
+}
+
+func loadResourceRequirements(q Querier, typeId *uuid.UUID) ([]resourceRequirement, error) {
+    if typeId == nil {
+        return nil, nil
+    }
+    rows, err := q.Query(`SELECT category, quantity FROM "AppointmentTypeResources"
+                        WHERE appointment_type_id=$1 AND active_status=1
+                        ORDER BY category ASC`, *typeId)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var requirements []resourceRequirement
+    for rows.Next() {
+        var r resourceRequirement
+        if err := rows.Scan(&r.category, &r.quantity); err != nil {
+            return nil, err
+        }
+        requirements = append(requirements, r)
+    }
+    return requirements, nil
+}
+
+// AllocateResources reserves free resources for every requirement of the appointment type in [start, start+duration).
+// Resource rows of a category are locked first, so two transactions can never take the same theatre.
+func AllocateResources(tx *sql.Tx, appointmentId uuid.UUID, typeId *uuid.UUID, start time.Time, duration time.Duration, createdBy string) error {
+    requirements, err := loadResourceRequirements(tx, typeId)
+    if err != nil {
+        return err
+    }
+    end := start.Add(duration)
+    now := time.Now()
+
+    for _, requirement := range requirements {
+        rows, err := tx.Query(`SELECT r.id,
+                                EXISTS (
+                                    SELECT 1 FROM "AppointmentResources" ar
+                                    JOIN "Appointments" a ON a.id = ar.appointment_id
+                                    WHERE ar.resource_id = r.id AND ar.active_status=1
+                                    AND a.active_status=1 AND a.status <> 'Cancelled'
+                                    AND ar.starts_at < $3 AND ar.ends_at > $2
+                                )
+                            FROM "Resources" r
+                            WHERE r.category=$1 AND r.active_status=1
+                            ORDER BY r.name ASC
+                            FOR UPDATE OF r`, requirement.category, start, end)
+        if err != nil {
+            return err
+        }
+        var free []uuid.UUID
+        for rows.Next() {
+            var id uuid.UUID
+            var busy bool
+            if err := rows.Scan(&id, &busy); err != nil {
+                rows.Close()
+                return err
+            }
+            if !busy {
+                free = append(free, id)
+            }
+        }
+        rows.Close()
+
+        if len(free) < requirement.quantity {
+            return &ResourceUnavailableError{Category: requirement.category}
+        }
+        for _, resourceId := range free[:requirement.quantity] {
+            _, err = tx.Exec(`INSERT INTO "AppointmentResources"
+                            (id, appointment_id, resource_id, starts_at, ends_at,
+                            active_status, created_at, created_by, modified_at, modified_by)
+                            VALUES ($1,$2,$3,$4,$5,1,$6,$7,$6,$7)`,
+                uuid.New(), appointmentId, resourceId, start, end, now, createdBy)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// ReleaseResources frees every resource held by the appointment
+func ReleaseResources(q Querier, appointmentId string, modifiedBy string) error {
+    _, err := q.Exec(`UPDATE "AppointmentResources"
+                    SET active_status=0, modified_at=$1, modified_by=$2
+                    WHERE appointment_id=$3 AND active_status=1`, time.Now(), modifiedBy, appointmentId)
+    return err
+}
+
+// resourceCapacity answers "are the resources of this type free in [start, end)" for the slot finder
+type resourceCapacity struct {
+    requirements []resourceRequirement
+    total        map[string]int
+    bookings     map[string][][2]time.Time // category -> booked intervals (one per held resource)
+}
+
+func loadResourceCapacity(db *sql.DB, typeId *uuid.UUID, from, to time.Time) (*resourceCapacity, error) {
+    requirements, err := loadResourceRequirements(db, typeId)
+    if err != nil || len(requirements) == 0 {
+        return &resourceCapacity{}, err
+    }
+    capacity := &resourceCapacity{
+        requirements: requirements,
+        total:        map[string]int{},
+        bookings:     map[string][][2]time.Time{},
+    }
+
+    rows, err := db.Query(`SELECT category, COUNT(*) FROM "Resources" WHERE active_status=1 GROUP BY category`)
+    if err != nil {
+        return nil, err
+    }
+    for rows.Next() {
+        var category string
+        var count int
+        if err := rows.Scan(&category, &count); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        capacity.total[category] = count
+    }
+    rows.Close()
+
+    rows, err = db.Query(`SELECT r.category, ar.starts_at, ar.ends_at
+                        FROM "AppointmentResources" ar
+                        JOIN "Resources" r ON r.id = ar.resource_id
+                        JOIN "Appointments" a ON a.id = ar.appointment_id
+                        WHERE ar.active_status=1 AND a.active_status=1 AND a.status <> 'Cancelled'
+                        AND ar.starts_at < $2 AND ar.ends_at > $1`, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    for rows.Next() {
+        var category string
+        var startsAt, endsAt time.Time
+        if err := rows.Scan(&category, &startsAt, &endsAt); err != nil {
+            return nil, err
+        }
+        capacity.bookings[category] = append(capacity.bookings[category], [2]time.Time{startsAt, endsAt})
+    }
+    return capacity, nil
+}
+
+// fits is conservative: every overlapping booking counts as one busy resource, so a slot may be hidden but a taken one is never offered
+func (rc *resourceCapacity) fits(start, end time.Time) bool {
+    for _, requirement := range rc.requirements {
+        busy := 0
+        for _, b := range rc.bookings[requirement.category] {
+            if b[0].Before(end) && b[1].After(start) {
+                busy++
+            }
+        }
+        if rc.total[requirement.category]-busy < requirement.quantity {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+	"vetclinic-rest-api/utils"
+
+	"github.com/google/uuid"
+)
+
+// freed appointment ids waiting to be matched against the waitlist
+var freedSlots = make(chan string, 100)
+
+// StartWaitlistMatcher runs the background matcher that offers freed slots to waitlisted pets.
+// Offers left unanswered for WAITLIST_OFFER_TTL_MINUTES go back to the queue and the slot is offered to the next entry.
+func StartWaitlistMatcher(db *sql.DB) {
+    go func() {
+        for appointmentId := range freedSlots {
+            if err := offerFreedSlot(db, appointmentId); err != nil {
+                log.Println("Error offering freed slot to waitlist:", err)
+            }
+        }
+    }()
+
+    ttl := time.Duration(utils.GetEnvPositiveInt("WAITLIST_OFFER_TTL_MINUTES", 120)) * time.Minute
+    go func() {
+        ticker := time.NewTicker(time.Minute)
+        defer ticker.Stop()
+        for range ticker.C {
+            if err := expireOffers(db, ttl); err != nil {
+                log.Println("Error expiring waitlist offers:", err)
+            }
+        }
+    }()
+}
+
+// expireOffers puts offers older than ttl back to Waiting and queues their slots for the matcher again
+func expireOffers(db *sql.DB, ttl time.Duration) error {
+    now := time.Now()
+    rows, err := db.Query(`WITH expired AS (
+                            SELECT id, offered_appointment_id FROM "Waitlist"
+                            WHERE status='Offered' AND active_status=1 AND offered_at < $1
+                            FOR UPDATE SKIP LOCKED
+                        )
+                        UPDATE "Waitlist" w
+                        SET status='Waiting', offer_expired_datetime=w.offered_datetime,
+                            offered_datetime=NULL, offered_doctor_id=NULL, offered_at=NULL, offered_appointment_id=NULL,
+                            modified_at=$2, modified_by='system'
+                        FROM expired e
+                        WHERE w.id = e.id
+                        RETURNING e.offered_appointment_id`, now.Add(-ttl), now)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    var slots []string
+    for rows.Next() {
+        var appointmentId *uuid.UUID
+        if err := rows.Scan(&appointmentId); err != nil {
+            return err
+        }
+        if appointmentId != nil {
+            slots = append(slots, appointmentId.String())
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    for _, appointmentId := range slots {
+        SlotFreed(appointmentId)
+    }
+    return nil
+}
+
+// SlotFreed queues a cancelled or deactivated appointment for the matcher (never blocks the request)
+func SlotFreed(appointmentId string) {
+    select {
+    case freedSlots <- appointmentId:
+    default:
+        log.Println("Waitlist matcher queue is full, skipping appointment:", appointmentId)
+    }
+}
+
+func offerFreedSlot(db *sql.DB, appointmentId string) error {
+    tx, err := db.Begin()
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    // 1. The slot must really be free (cancelled or deactivated) and still ahead
+    var (
+        slotDatetime time.Time
+        doctorId     *uuid.UUID
+        status       string
+        activeStatus int
+    )
+    err = tx.QueryRow(`SELECT appointment_datetime, doctor_id, status, active_status
+                        FROM "Appointments" WHERE id=$1`, appointmentId).Scan(
+        &slotDatetime, &doctorId, &status, &activeStatus,
+    )
+    if err != nil {
+        return err
+    }
+    if status != "Cancelled" && activeStatus == 1 {
+        return nil
+    }
+    // a past slot can't be booked anymore
+    if !slotDatetime.After(time.Now()) {
+        return nil
+    }
+
+    // 2. Skip if the slot was already rebooked or already offered
+    var taken bool
+    err = tx.QueryRow(`SELECT EXISTS (
+                            SELECT 1 FROM "Appointments"
+                            WHERE appointment_datetime=$1 AND doctor_id IS NOT DISTINCT FROM $2
+                            AND status <> 'Cancelled' AND active_status=1
+                        ) OR EXISTS (
+                            SELECT 1 FROM "Waitlist"
+                            WHERE offered_datetime=$1 AND offered_doctor_id IS NOT DISTINCT FROM $2
+                            AND status='Offered' AND active_status=1
+                        )`, slotDatetime, doctorId).Scan(&taken)
+    if err != nil {
+        return err
+    }
+    if taken {
+        return nil
+    }
+
+    // 3. Best candidate: highest priority, exact doctor match first, then first come first served.
+    // An entry whose offer of this slot expired doesn't get it again.
+    var (
+        waitlistId uuid.UUID
+        petName    string
+        ownerPhone string
+        ownerEmail string
+    )
+    candidateQuery := `SELECT w.id, p.name, COALESCE(NULLIF(o.phone, ''), p.owner_phone, ''), COALESCE(o.email, '')
+                    FROM "Waitlist" w
+                    JOIN "Pets" p ON p.id = w.pet_id
+                    LEFT JOIN "Owners" o ON o.id = p.owner_id AND o.active_status=1
+                    WHERE w.status='Waiting' AND w.active_status=1
+                    AND $1::date BETWEEN w.date_from AND w.date_to
+                    AND (w.preferred_doctor_id IS NULL OR w.preferred_doctor_id = $2)
+                    AND w.offer_expired_datetime IS DISTINCT FROM $3
+                    ORDER BY w.priority DESC, (w.preferred_doctor_id IS NOT NULL) DESC, w.created_at ASC
+                    LIMIT 1
+                    FOR UPDATE OF w SKIP LOCKED`
+    slotDate := slotDatetime.In(utils.ClinicLocation()).Format("2006-01-02")
+    err = tx.QueryRow(candidateQuery, slotDate, doctorId, slotDatetime).Scan(
+        &waitlistId, &petName, &ownerPhone, &ownerEmail,
+    )
+    if err == sql.ErrNoRows {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    now := time.Now()
+    _, err = tx.Exec(`UPDATE "Waitlist"
+                    SET status='Offered', offered_datetime=$1, offered_doctor_id=$2, offered_at=$3, offered_appointment_id=$4,
+                        modified_at=$3, modified_by='system'
+                    WHERE id=$5`, slotDatetime, doctorId, now, appointmentId, waitlistId)
+    if err != nil {
+        return err
+    }
+
+    // 4. Offer goes through the outbox in the same transaction, by SMS or else by email.
+    // Without either the offer stays visible to the front desk until it expires.
+    channel, recipient := "SMS", ownerPhone
+    if recipient == "" {
+        channel, recipient = "Email", ownerEmail
+    }
+    if recipient == "" {
+        log.Println("Waitlist entry has no owner phone or email, offer not sent:", waitlistId)
+        return tx.Commit()
+    }
+    message := fmt.Sprintf("Good news! A slot opened for %s on %s. Please contact the clinic to confirm the booking.",
+        petName, slotDatetime.In(utils.ClinicLocation()).Format("02 Jan 2006 15:04"))
+    _, err = tx.Exec(`INSERT INTO "Notifications"
+                    (id, appointment_id, waitlist_id, kind, channel, recipient, subject, message, status,
+                    active_status, created_at, created_by, modified_at, modified_by)
+                    VALUES ($1,$2,$3,'WaitlistOffer',$4,$5,$6,$7,'Pending',1,$8,'system',$8,'system')`,
+        uuid.New(), appointmentId, waitlistId, channel, recipient, "Appointment slot available", message, now,
+    )
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
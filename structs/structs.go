@@ -1,6 +1,7 @@
 package structs
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,11 +32,13 @@ type Pet struct {
     BirthDate   	string 		`json:"birth_date"`
     OwnerName   	string    	`json:"owner_name"`
     OwnerPhone  	string    	`json:"owner_phone"`
+    OwnerId     	*uuid.UUID 	`json:"owner_id"`
     ActiveStatus 	int      	`json:"active_status"`
     CreatedAt   	time.Time 	`json:"created_at"`
     CreatedBy   	string    	`json:"created_by"`
     ModifiedAt  	time.Time 	`json:"modified_at"`
     ModifiedBy  	string    	`json:"modified_by"`
+    OwnerNoShowCount *int   	`json:"owner_no_show_count,omitempty"` // profile read only
 }
 
 // APPOINTMENTS
@@ -43,7 +46,8 @@ type Appointment struct {
     Id                  uuid.UUID `json:"id"`
     PetId               uuid.UUID `json:"pet_id"`
     DoctorId            uuid.UUID `json:"doctor_id"`
-    Status              string    `json:"status"` // pending, cancelled, completed
+    AppointmentTypeId   *uuid.UUID `json:"appointment_type_id"`
+    Status              string    `json:"status"` // pending, inprogress, cancelled, completed, noshow
     AppointmentDatetime time.Time `json:"appointment_datetime"`
     Notes               string    `json:"notes"`
     ActiveStatus        int       `json:"active_status"`
@@ -51,20 +55,146 @@ type Appointment struct {
     CreatedBy           string    `json:"created_by"`
     ModifiedAt          time.Time `json:"modified_at"`
     ModifiedBy          string    `json:"modified_by"`
+    Cancellation        *AppointmentCancellation `json:"cancellation,omitempty"` // single appointment reads only
+}
+
+// APPOINTMENT CANCELLATION (columns of Appointments)
+type AppointmentCancellation struct {
+    ReasonId         *uuid.UUID `json:"cancellation_reason_id"`
+    Reason           string     `json:"reason"`
+    Note             string     `json:"note"`
+    CancelledByParty string     `json:"cancelled_by_party"` // Owner, Clinic
+    CancelledAt      *time.Time `json:"cancelled_at"`
+    LateCancellation bool       `json:"late_cancellation"`
+}
+
+// CANCELLATION REASONS
+type CancellationReason struct {
+    Id           uuid.UUID `json:"id"`
+    Name         string    `json:"name"`
+    Party        string    `json:"party"` // Owner, Clinic, Any
+    RequiresNote bool      `json:"requires_note"`
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
 }
 
 // MEDICAL RECORDS
 type MedicalRecord struct {
-    Id            uuid.UUID `json:"id"`
-    AppointmentId uuid.UUID `json:"appointment_id"`
-    PetId         uuid.UUID `json:"pet_id"`
-    Diagnosis     string    `json:"diagnosis"`
-    Notes         string    `json:"notes"`
-    ActiveStatus  int       `json:"active_status"`
-    CreatedAt     time.Time `json:"created_at"`
-    CreatedBy     string    `json:"created_by"`
-    ModifiedAt    time.Time `json:"modified_at"`
-    ModifiedBy    string    `json:"modified_by"`
+    Id            uuid.UUID               `json:"id"`
+    AppointmentId uuid.UUID               `json:"appointment_id"`
+    PetId         uuid.UUID               `json:"pet_id"`
+    Diagnosis     string                  `json:"diagnosis"` // primary diagnosis for SOAP records
+    Notes         string                  `json:"notes"`
+    SOAP          *SOAPNote               `json:"soap"`
+    SignedAt      *time.Time              `json:"signed_at"` // signed records are immutable
+    SignedBy      string                  `json:"signed_by"`
+    Addenda       []MedicalRecordAddendum `json:"addenda"`
+    ActiveStatus  int                     `json:"active_status"`
+    CreatedAt     time.Time               `json:"created_at"`
+    CreatedBy     string                  `json:"created_by"`
+    ModifiedAt    time.Time               `json:"modified_at"`
+    ModifiedBy    string                  `json:"modified_by"`
+}
+
+// SOAP NOTE (sections of a medical record)
+type SOAPNote struct {
+    Structured bool           `json:"structured"` // false for records written before SOAP, read from diagnosis/notes
+    Subjective string         `json:"subjective"`
+    Objective  SOAPObjective  `json:"objective"`
+    Assessment SOAPAssessment `json:"assessment"`
+    Plan       string         `json:"plan"`
+}
+
+type SOAPObjective struct {
+    Notes  string   `json:"notes"`
+    Vitals []Vitals `json:"vitals"`
+}
+
+type SOAPAssessment struct {
+    Notes     string                   `json:"notes"`
+    Diagnoses []MedicalRecordDiagnosis `json:"diagnoses"`
+}
+
+// VITALS
+type Vitals struct {
+    Id                     uuid.UUID `json:"id"`
+    MedicalRecordId        uuid.UUID `json:"medicalrecord_id"`
+    WeightKg               *float64  `json:"weight_kg"`
+    TemperatureC           *float64  `json:"temperature_c"`
+    HeartRate              *int      `json:"heart_rate"`
+    RespiratoryRate        *int      `json:"respiratory_rate"`
+    CapillaryRefillSeconds *float64  `json:"capillary_refill_seconds"`
+    MucousMembranes        string    `json:"mucous_membranes"`
+    BodyConditionScore     *int      `json:"body_condition_score"` // 1-9
+    PainScore              *int      `json:"pain_score"`           // 0-10
+    RecordedAt             time.Time `json:"recorded_at"`
+    ActiveStatus           int       `json:"active_status"`
+    CreatedAt              time.Time `json:"created_at"`
+    CreatedBy              string    `json:"created_by"`
+    ModifiedAt             time.Time `json:"modified_at"`
+    ModifiedBy             string    `json:"modified_by"`
+}
+
+// MEDICAL RECORD ADDENDA
+type MedicalRecordAddendum struct {
+    Id              uuid.UUID `json:"id"`
+    MedicalRecordId uuid.UUID `json:"medicalrecord_id"`
+    Content         string    `json:"content"`
+    SignedAt        time.Time `json:"signed_at"`
+    SignedBy        string    `json:"signed_by"`
+    ActiveStatus    int       `json:"active_status"`
+    CreatedAt       time.Time `json:"created_at"`
+    CreatedBy       string    `json:"created_by"`
+    ModifiedAt      time.Time `json:"modified_at"`
+    ModifiedBy      string    `json:"modified_by"`
+}
+
+// MEDICAL RECORD CORRECTIONS (Admin audit)
+type MedicalRecordCorrection struct {
+    Id              uuid.UUID       `json:"id"`
+    MedicalRecordId uuid.UUID       `json:"medicalrecord_id"`
+    Action          string          `json:"action"` // Deactivate
+    Reason          string          `json:"reason"`
+    Snapshot        json.RawMessage `json:"snapshot"`
+    ActiveStatus    int             `json:"active_status"`
+    CreatedAt       time.Time       `json:"created_at"`
+    CreatedBy       string          `json:"created_by"`
+    ModifiedAt      time.Time       `json:"modified_at"`
+    ModifiedBy      string          `json:"modified_by"`
+}
+
+// MEDICAL RECORD DIAGNOSES
+type MedicalRecordDiagnosis struct {
+    Id              uuid.UUID `json:"id"`
+    MedicalRecordId uuid.UUID `json:"medicalrecord_id"`
+    Description     string     `json:"description"`
+    Code            string     `json:"code"` // optional DiagnosisCatalog code, the term is used when Description is empty
+    CatalogId       *uuid.UUID `json:"diagnosis_catalog_id"`
+    Term            string     `json:"term"` // catalog term (read only)
+    Certainty       string     `json:"certainty"` // Tentative, Confirmed, RuledOut
+    IsPrimary       bool       `json:"is_primary"`
+    ActiveStatus    int        `json:"active_status"`
+    CreatedAt       time.Time  `json:"created_at"`
+    CreatedBy       string     `json:"created_by"`
+    ModifiedAt      time.Time  `json:"modified_at"`
+    ModifiedBy      string     `json:"modified_by"`
+}
+
+// DIAGNOSIS CATALOG (coded terminology)
+type DiagnosisCode struct {
+    Id           uuid.UUID `json:"id"`
+    Code         string    `json:"code"`
+    Term         string    `json:"term"`
+    Synonyms     []string  `json:"synonyms"`
+    Species      []string  `json:"species"` // empty = every species
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
 }
 
 // TREATMENTS
@@ -74,9 +204,582 @@ type Treatment struct {
     DoctorId       	uuid.UUID `json:"doctor_id"`
     Description    	string    `json:"description"`
     Cost           	int       `json:"cost"`
+    BillingCategory	string    `json:"billing_category"` // tax rate category of its invoice line
     ActiveStatus   	int       `json:"active_status"`
     CreatedAt      	time.Time `json:"created_at"`
     CreatedBy      	string    `json:"created_by"`
     ModifiedAt     	time.Time `json:"modified_at"`
     ModifiedBy     	string    `json:"modified_by"`
-}
\ No newline at end of file
+}
+
+// PRESCRIPTIONS
+type Prescription struct {
+    Id               uuid.UUID              `json:"id"`
+    MedicalRecordId  uuid.UUID              `json:"medicalrecord_id"`
+    PetId            uuid.UUID              `json:"pet_id"`
+    DoctorId         uuid.UUID              `json:"doctor_id"` // prescribing doctor
+    DrugName         string                 `json:"drug_name"`
+    Strength         string                 `json:"strength"`
+    Dose             string                 `json:"dose"`
+    Route            string                 `json:"route"` // Oral, Topical, Otic, Ophthalmic, SC, IM, IV, Other
+    Frequency        string                 `json:"frequency"`
+    DurationDays     *int                   `json:"duration_days"`
+    Quantity         float64                `json:"quantity"` // per fill
+    Unit             string                 `json:"unit"`
+    Instructions     string                 `json:"instructions"`
+    RefillsAllowed   int                    `json:"refills_allowed"`
+    RefillsUsed      int                    `json:"refills_used"`
+    Status           string                 `json:"status"` // Active, Completed, Cancelled
+    PrescribedAt     time.Time              `json:"prescribed_at"`
+    Dispenses        []PrescriptionDispense `json:"dispenses,omitempty"`
+    ActiveStatus     int                    `json:"active_status"`
+    CreatedAt        time.Time              `json:"created_at"`
+    CreatedBy        string                 `json:"created_by"`
+    ModifiedAt       time.Time              `json:"modified_at"`
+    ModifiedBy       string                 `json:"modified_by"`
+}
+
+// PRESCRIPTION DISPENSES (first fill and refills)
+type PrescriptionDispense struct {
+    Id             uuid.UUID `json:"id"`
+    PrescriptionId uuid.UUID `json:"prescription_id"`
+    Quantity       float64   `json:"quantity"`
+    IsRefill       bool      `json:"is_refill"`
+    Notes          string    `json:"notes"`
+    DispensedAt    time.Time `json:"dispensed_at"`
+    ActiveStatus   int       `json:"active_status"`
+    CreatedAt      time.Time `json:"created_at"`
+    CreatedBy      string    `json:"created_by"`
+    ModifiedAt     time.Time `json:"modified_at"`
+    ModifiedBy     string    `json:"modified_by"`
+}
+
+// LAB ORDERS
+type LabOrder struct {
+    Id              uuid.UUID   `json:"id"`
+    AccessionNumber string      `json:"accession_number"` // printed on the sample, matched on import
+    AppointmentId   uuid.UUID   `json:"appointment_id"`
+    MedicalRecordId *uuid.UUID  `json:"medicalrecord_id"`
+    PetId           uuid.UUID   `json:"pet_id"`
+    DoctorId        uuid.UUID   `json:"doctor_id"` // ordering doctor
+    Panel           string      `json:"panel"`
+    SampleType      string      `json:"sample_type"`
+    Status          string      `json:"status"` // Ordered, Collected, InProgress, Resulted, Cancelled
+    Notes           string      `json:"notes"`
+    OrderedAt       time.Time   `json:"ordered_at"`
+    CollectedAt     *time.Time  `json:"collected_at"`
+    ResultedAt      *time.Time  `json:"resulted_at"`
+    Results         []LabResult `json:"results,omitempty"`
+    ActiveStatus    int         `json:"active_status"`
+    CreatedAt       time.Time   `json:"created_at"`
+    CreatedBy       string      `json:"created_by"`
+    ModifiedAt      time.Time   `json:"modified_at"`
+    ModifiedBy      string      `json:"modified_by"`
+}
+
+// LAB RESULTS (one per analyte)
+type LabResult struct {
+    Id            uuid.UUID  `json:"id"`
+    LabOrderId    uuid.UUID  `json:"lab_order_id"`
+    AnalyteCode   string     `json:"analyte_code"`
+    Analyte       string     `json:"analyte"`
+    Value         string     `json:"value"`
+    NumericValue  *float64   `json:"numeric_value"`
+    Unit          string     `json:"unit"`
+    ReferenceLow  *float64   `json:"reference_low"`
+    ReferenceHigh *float64   `json:"reference_high"`
+    Flag          string     `json:"flag"` // Normal, Low, High, Critical (empty when no reference range applies)
+    Source        string     `json:"source"` // Manual, CSV, HL7
+    ObservedAt    *time.Time `json:"observed_at"` // defaults to the time of entry
+    ActiveStatus  int        `json:"active_status"`
+    CreatedAt     time.Time  `json:"created_at"`
+    CreatedBy     string     `json:"created_by"`
+    ModifiedAt    time.Time  `json:"modified_at"`
+    ModifiedBy    string     `json:"modified_by"`
+}
+
+// LAB REFERENCE RANGES (by analyte, species and age band)
+type LabReferenceRange struct {
+    Id           uuid.UUID `json:"id"`
+    AnalyteCode  string    `json:"analyte_code"`
+    Species      string    `json:"species"`
+    AgeMinMonths int       `json:"age_min_months"`
+    AgeMaxMonths *int      `json:"age_max_months"` // exclusive, null = no upper bound
+    Unit         string    `json:"unit"` // empty = any unit
+    Low          *float64  `json:"low"`
+    High         *float64  `json:"high"`
+    CriticalLow  *float64  `json:"critical_low"`
+    CriticalHigh *float64  `json:"critical_high"`
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
+}
+
+// CONSENT TEMPLATES (body with {{placeholders}})
+type ConsentTemplate struct {
+    Id                uuid.UUID  `json:"id"`
+    Name              string     `json:"name"`
+    AppointmentTypeId *uuid.UUID `json:"appointment_type_id"` // default template for this type
+    Body              string     `json:"body"`
+    ActiveStatus      int        `json:"active_status"`
+    CreatedAt         time.Time  `json:"created_at"`
+    CreatedBy         string     `json:"created_by"`
+    ModifiedAt        time.Time  `json:"modified_at"`
+    ModifiedBy        string     `json:"modified_by"`
+}
+
+// CONSENTS (rendered per appointment, signed by the owner)
+type Consent struct {
+    Id                uuid.UUID  `json:"id"`
+    AppointmentId     uuid.UUID  `json:"appointment_id"`
+    ConsentTemplateId uuid.UUID  `json:"consent_template_id"`
+    Procedure         string     `json:"procedure"`
+    Estimate          int        `json:"estimate"`
+    Body              string     `json:"body"`
+    BodySha256        string     `json:"body_sha256"`
+    Status            string     `json:"status"` // Pending, Signed, Revoked
+    SignerName        string     `json:"signer_name"`
+    HasSignature      bool       `json:"has_signature"` // image is served by GET /consents/:id/signature
+    SignedAt          *time.Time `json:"signed_at"`
+    SignerIp          string     `json:"signer_ip"`
+    RevokedAt         *time.Time `json:"revoked_at"`
+    RevokedReason     string     `json:"revoked_reason"`
+    ActiveStatus      int        `json:"active_status"`
+    CreatedAt         time.Time  `json:"created_at"`
+    CreatedBy         string     `json:"created_by"`
+    ModifiedAt        time.Time  `json:"modified_at"`
+    ModifiedBy        string     `json:"modified_by"`
+}
+
+// CLINIC PROFILE (single row, branding of printed documents)
+type ClinicProfile struct {
+    Id                 uuid.UUID `json:"id"`
+    Name               string    `json:"name"`
+    Address            string    `json:"address"`
+    Phone              string    `json:"phone"`
+    Email              string    `json:"email"`
+    Website            string    `json:"website"`
+    FooterText         string    `json:"footer_text"`
+    AccentColor        string    `json:"accent_color"` // #RRGGBB
+    HasLogo            bool      `json:"has_logo"` // logo is served by GET /clinic-profile/logo
+    InvoicePrefix      string    `json:"invoice_prefix"` // invoice numbers are the prefix and a 6 digit sequence
+    ReceiptPrefix      string    `json:"receipt_prefix"` // receipt numbers, same format
+    RoundingUnit       int       `json:"rounding_unit"` // invoice grand totals are rounded half up to a multiple of it
+    StaffDiscountLimit *float64  `json:"staff_discount_limit"` // percent Staff may give, larger discounts need Admin
+    ActiveStatus       int       `json:"active_status"`
+    CreatedAt          time.Time `json:"created_at"`
+    CreatedBy          string    `json:"created_by"`
+    ModifiedAt         time.Time `json:"modified_at"`
+    ModifiedBy         string    `json:"modified_by"`
+}
+
+// WAITLIST
+type WaitlistEntry struct {
+    Id                uuid.UUID  `json:"id"`
+    PetId             uuid.UUID  `json:"pet_id"`
+    PreferredDoctorId *uuid.UUID `json:"preferred_doctor_id"`
+    DateFrom          string     `json:"date_from"` // YYYY-MM-DD
+    DateTo            string     `json:"date_to"`   // YYYY-MM-DD
+    Priority          int        `json:"priority"`  // higher = more urgent
+    Notes             string     `json:"notes"`
+    Status            string     `json:"status"` // Waiting, Offered, Booked, Cancelled
+    OfferedDatetime   *time.Time `json:"offered_datetime"`
+    OfferedDoctorId   *uuid.UUID `json:"offered_doctor_id"`
+    OfferedAt         *time.Time `json:"offered_at"`
+    AppointmentId     *uuid.UUID `json:"appointment_id"`
+    ActiveStatus      int        `json:"active_status"`
+    CreatedAt         time.Time  `json:"created_at"`
+    CreatedBy         string     `json:"created_by"`
+    ModifiedAt        time.Time  `json:"modified_at"`
+    ModifiedBy        string     `json:"modified_by"`
+}
+
+// NOTIFICATIONS (outbox)
+type Notification struct {
+    Id            uuid.UUID  `json:"id"`
+    AppointmentId *uuid.UUID `json:"appointment_id"`
+    WaitlistId    *uuid.UUID `json:"waitlist_id"`
+    Kind          string     `json:"kind"`    // WaitlistOffer, Reminder48h, Reminder2h
+    Channel       string     `json:"channel"` // SMS, Email
+    Recipient     string     `json:"recipient"`
+    Subject       string     `json:"subject"`
+    Message       string     `json:"message"`
+    Status        string     `json:"status"` // Pending, Sent, Failed
+    Attempts      int        `json:"attempts"`
+    NextAttemptAt *time.Time `json:"next_attempt_at"`
+    LastError     string     `json:"last_error"`
+    SentAt        *time.Time `json:"sent_at"`
+    ActiveStatus  int        `json:"active_status"`
+    CreatedAt     time.Time  `json:"created_at"`
+    CreatedBy     string     `json:"created_by"`
+    ModifiedAt    time.Time  `json:"modified_at"`
+    ModifiedBy    string     `json:"modified_by"`
+}
+
+// CALENDAR TOKENS
+type CalendarToken struct {
+    Id           uuid.UUID  `json:"id"`
+    DoctorId     uuid.UUID  `json:"doctor_id"`
+    Token        string     `json:"token,omitempty"` // only returned when created, stored as a hash
+    RevokedAt    *time.Time `json:"revoked_at"`
+    RevokedBy    *string    `json:"revoked_by"`
+    ActiveStatus int        `json:"active_status"`
+    CreatedAt    time.Time  `json:"created_at"`
+    CreatedBy    string     `json:"created_by"`
+    ModifiedAt   time.Time  `json:"modified_at"`
+    ModifiedBy   string     `json:"modified_by"`
+}
+
+
+// APPOINTMENT TYPES (service catalog)
+type AppointmentType struct {
+    Id                     uuid.UUID `json:"id"`
+    Name                   string    `json:"name"`
+    DefaultDurationMinutes int       `json:"default_duration_minutes"`
+    DefaultPrice           int       `json:"default_price"`
+    RequiredRole           string    `json:"required_role"` // Doctor, Staff
+    Color                  string    `json:"color"`         // #RRGGBB
+    RequiresConsent        *bool     `json:"requires_consent"` // a signed consent is needed before the appointment starts
+    BillingCategory        string    `json:"billing_category"` // tax rate category of its invoice line
+    ActiveStatus           int       `json:"active_status"`
+    CreatedAt              time.Time `json:"created_at"`
+    CreatedBy              string    `json:"created_by"`
+    ModifiedAt             time.Time `json:"modified_at"`
+    ModifiedBy             string    `json:"modified_by"`
+}
+
+// OWNERS
+type Owner struct {
+    Id                    uuid.UUID `json:"id"`
+    Name                  string    `json:"name"`
+    Email                 string    `json:"email"`
+    Phone                 string    `json:"phone"`
+    Password              string    `json:"password,omitempty"` // input only
+    PasswordHash          string    `json:"-"`
+    NoShowCount           *int      `json:"no_show_count,omitempty"` // profile reads only
+    LateCancellationCount *int      `json:"late_cancellation_count,omitempty"`
+    ActiveStatus          int       `json:"active_status"`
+    CreatedAt             time.Time `json:"created_at"`
+    CreatedBy             string    `json:"created_by"`
+    ModifiedAt            time.Time `json:"modified_at"`
+    ModifiedBy            string    `json:"modified_by"`
+}
+
+// AVAILABLE SLOTS (computed, not stored)
+type AvailableSlot struct {
+    DoctorId   uuid.UUID `json:"doctor_id"`
+    DoctorName string    `json:"doctor_name"`
+    Start      time.Time `json:"start"`
+    End        time.Time `json:"end"`
+}
+
+// RESOURCES (rooms, equipment)
+type Resource struct {
+    Id           uuid.UUID `json:"id"`
+    Name         string    `json:"name"`
+    Kind         string    `json:"kind"`     // Room, Equipment
+    Category     string    `json:"category"` // ExamRoom, OperatingTheatre, AnesthesiaMachine, ...
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
+}
+
+// APPOINTMENT TYPE RESOURCES (requirements)
+type AppointmentTypeResource struct {
+    Id                uuid.UUID `json:"id"`
+    AppointmentTypeId uuid.UUID `json:"appointment_type_id"`
+    Category          string    `json:"category"`
+    Quantity          int       `json:"quantity"`
+    ActiveStatus      int       `json:"active_status"`
+    CreatedAt         time.Time `json:"created_at"`
+    CreatedBy         string    `json:"created_by"`
+    ModifiedAt        time.Time `json:"modified_at"`
+    ModifiedBy        string    `json:"modified_by"`
+}
+
+// APPOINTMENT RESOURCES (allocations)
+type AppointmentResource struct {
+    Id            uuid.UUID `json:"id"`
+    AppointmentId uuid.UUID `json:"appointment_id"`
+    ResourceId    uuid.UUID `json:"resource_id"`
+    ResourceName  string    `json:"resource_name"`
+    Category      string    `json:"category"`
+    StartsAt      time.Time `json:"starts_at"`
+    EndsAt        time.Time `json:"ends_at"`
+    ActiveStatus  int       `json:"active_status"`
+    CreatedAt     time.Time `json:"created_at"`
+    CreatedBy     string    `json:"created_by"`
+}
+
+// QUEUE ENTRIES (waiting room)
+type QueueEntry struct {
+    Id             uuid.UUID  `json:"id"`
+    AppointmentId  *uuid.UUID `json:"appointment_id"`
+    PetId          *uuid.UUID `json:"pet_id"`
+    PetName        string     `json:"pet_name"` // pet name, or visitor name of an unregistered walk-in
+    VisitorName    string     `json:"visitor_name"`
+    Kind           string     `json:"kind"` // Appointment, WalkIn
+    TriagePriority int        `json:"triage_priority"`
+    TriageNotes    string     `json:"triage_notes"`
+    Status         string     `json:"status"` // Waiting, InRoom, Done, Left
+    DoctorId       *uuid.UUID `json:"doctor_id"`
+    DoctorName     string     `json:"doctor_name"`
+    ResourceId     *uuid.UUID `json:"resource_id"`
+    RoomName       string     `json:"room_name"`
+    CheckedInAt    time.Time  `json:"checked_in_at"`
+    CalledAt       *time.Time `json:"called_at"`
+    CompletedAt    *time.Time `json:"completed_at"`
+    ActiveStatus   int        `json:"active_status"`
+    CreatedAt      time.Time  `json:"created_at"`
+    CreatedBy      string     `json:"created_by"`
+    ModifiedAt     time.Time  `json:"modified_at"`
+    ModifiedBy     string     `json:"modified_by"`
+}
+
+// VERSIONS (MedicalRecordVersions, TreatmentVersions)
+type Version struct {
+    Id         uuid.UUID       `json:"id"`
+    EntityId   uuid.UUID       `json:"entity_id"` // medical record or treatment id
+    Version    int             `json:"version"`
+    Snapshot   json.RawMessage `json:"snapshot"`
+    AuthorName string          `json:"author_name"`
+    CreatedAt  time.Time       `json:"created_at"`
+    CreatedBy  string          `json:"created_by"`
+}
+
+// VERSION DIFF (computed, not stored)
+type VersionChange struct {
+    Field string      `json:"field"`
+    From  interface{} `json:"from"`
+    To    interface{} `json:"to"`
+}
+
+// PET TIMELINE EVENT (computed, not stored)
+type TimelineEvent struct {
+    Type            string          `json:"type"` // appointment, medical_record, vitals, treatment, prescription, lab_order, addendum, attachment
+    OccurredAt      time.Time       `json:"occurred_at"`
+    ReferenceId     uuid.UUID       `json:"reference_id"`   // id of the row the event comes from
+    AppointmentId   *uuid.UUID      `json:"appointment_id"` // null for attachments
+    MedicalRecordId *uuid.UUID      `json:"medicalrecord_id"`
+    Details         json.RawMessage `json:"details"`
+}
+
+// CLINICAL SEARCH HIT (computed, not stored)
+type ClinicalSearchHit struct {
+    Source              string    `json:"source"` // diagnosis, note, addendum, treatment
+    ReferenceId         uuid.UUID `json:"reference_id"` // id of the row that matched
+    MedicalRecordId     uuid.UUID `json:"medicalrecord_id"`
+    AppointmentId       uuid.UUID `json:"appointment_id"`
+    PetId               uuid.UUID `json:"pet_id"`
+    PetName             string    `json:"pet_name"`
+    Species             string    `json:"species"`
+    DoctorId            uuid.UUID `json:"doctor_id"`
+    DoctorName          string    `json:"doctor_name"`
+    AppointmentDatetime time.Time `json:"appointment_datetime"`
+    Rank                float64   `json:"rank"`
+    Headline            string    `json:"headline"` // HTML escaped, matches wrapped in <mark></mark>
+}
+
+// REFERRALS (to a specialist clinic, or received from another clinic)
+type Referral struct {
+    Id                  uuid.UUID   `json:"id"`
+    Direction           string      `json:"direction"` // Outgoing, Incoming
+    PetId               uuid.UUID   `json:"pet_id"`
+    ReferringDoctorId   *uuid.UUID  `json:"referring_doctor_id"` // outgoing referrals only
+    ReferringDoctorName string      `json:"referring_doctor_name"`
+    SourceClinic        string      `json:"source_clinic"`
+    DestinationClinic   string      `json:"destination_clinic"`
+    DestinationContact  string      `json:"destination_contact"`
+    Reason              string      `json:"reason"`
+    Urgency             string      `json:"urgency"` // Routine, Urgent, Emergency
+    Status              string      `json:"status"` // Draft, Sent, Received, Accepted, Declined, Completed, Cancelled
+    MedicalRecordIds    []uuid.UUID `json:"medical_record_ids"`
+    Notes               string      `json:"notes"`
+    PackageSha256       string      `json:"package_sha256"`
+    SourceReferralId    *uuid.UUID  `json:"source_referral_id"` // id at the sending clinic, incoming referrals only
+    ExportedAt          *time.Time  `json:"exported_at"`
+    ImportedAt          *time.Time  `json:"imported_at"`
+    ActiveStatus        int         `json:"active_status"`
+    CreatedAt           time.Time   `json:"created_at"`
+    CreatedBy           string      `json:"created_by"`
+    ModifiedAt          time.Time   `json:"modified_at"`
+    ModifiedBy          string      `json:"modified_by"`
+}
+
+// REFERRAL DOCUMENTS (files of an imported package)
+type ReferralDocument struct {
+    Id           uuid.UUID `json:"id"`
+    ReferralId   uuid.UUID `json:"referral_id"`
+    Name         string    `json:"name"`
+    Mime         string    `json:"mime"`
+    Size         int       `json:"size"` // content is served by GET /referrals/:id/documents/:document_id
+    Sha256       string    `json:"sha256"`
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
+}
+
+// REFERRAL PACKAGE MANIFEST (manifest.json of an exported zip, signed in manifest.sig)
+type ReferralManifest struct {
+    Format       string                 `json:"format"` // vetclinic-referral
+    Version      int                    `json:"version"`
+    ReferralId   uuid.UUID              `json:"referral_id"`
+    SourceClinic string                 `json:"source_clinic"`
+    CreatedAt    time.Time              `json:"created_at"`
+    Files        []ReferralManifestFile `json:"files"`
+}
+
+type ReferralManifestFile struct {
+    Name   string `json:"name"`
+    Sha256 string `json:"sha256"`
+    Size   int    `json:"size"`
+}
+
+// REFERRAL PACKAGE (referral.json of an exported zip)
+type ReferralPackage struct {
+    Referral Referral                `json:"referral"`
+    Pet      Pet                     `json:"pet"`
+    Records  []ReferralPackageRecord `json:"records"`
+}
+
+type ReferralPackageRecord struct {
+    AppointmentDatetime time.Time     `json:"appointment_datetime"`
+    AppointmentType     string        `json:"appointment_type"`
+    DoctorName          string        `json:"doctor_name"`
+    MedicalRecord       MedicalRecord `json:"medical_record"` // SOAP with vitals, diagnoses and addenda
+    Treatments          []Treatment   `json:"treatments"`
+    SummaryFile         string        `json:"summary_file"` // visit summary PDF in the package
+}
+
+// INVOICES (billing of an appointment)
+type Invoice struct {
+    Id                 uuid.UUID     `json:"id"`
+    ClinicProfileId    uuid.UUID     `json:"clinic_profile_id"`
+    InvoiceNumber      string        `json:"invoice_number"` // empty while Draft
+    AppointmentId      uuid.UUID     `json:"appointment_id"`
+    PetId              uuid.UUID     `json:"pet_id"`
+    OwnerId            *uuid.UUID    `json:"owner_id"`
+    BillToName         string        `json:"bill_to_name"`
+    BillToPhone        string        `json:"bill_to_phone"`
+    Status             string        `json:"status"` // Draft, Issued, Void
+    DiscountType       string        `json:"discount_type"` // Percent, Fixed, empty without invoice discount
+    DiscountValue      float64       `json:"discount_value"`
+    DiscountReason     string        `json:"discount_reason"`
+    DiscountApprovedBy string        `json:"discount_approved_by"`
+    DiscountAmount     int           `json:"discount_amount"` // invoice discount only
+    Subtotal           int           `json:"subtotal"` // lines before discounts and tax
+    DiscountTotal      int           `json:"discount_total"` // line and invoice discounts
+    TaxTotal           int           `json:"tax_total"`
+    RoundingAdjustment int           `json:"rounding_adjustment"`
+    Total              int           `json:"total"` // grand total: subtotal - discount_total + tax_total + rounding_adjustment
+    Paid               int           `json:"paid"` // computed from recorded payments
+    Balance            int           `json:"balance"` // total - paid for issued invoices, 0 otherwise
+    PaymentStatus      string        `json:"payment_status"` // Unpaid, PartiallyPaid, Paid (issued invoices only)
+    Notes              string        `json:"notes"`
+    IssuedAt           *time.Time    `json:"issued_at"`
+    IssuedBy           string        `json:"issued_by"`
+    VoidedAt           *time.Time    `json:"voided_at"`
+    VoidedBy           string        `json:"voided_by"`
+    VoidReason         string        `json:"void_reason"`
+    Lines              []InvoiceLine `json:"lines,omitempty"` // single invoice reads only
+    ActiveStatus       int           `json:"active_status"`
+    CreatedAt          time.Time     `json:"created_at"`
+    CreatedBy          string        `json:"created_by"`
+    ModifiedAt         time.Time     `json:"modified_at"`
+    ModifiedBy         string        `json:"modified_by"`
+}
+
+// INVOICE LINES
+type InvoiceLine struct {
+    Id                   uuid.UUID  `json:"id"`
+    InvoiceId            uuid.UUID  `json:"invoice_id"`
+    Source               string     `json:"source"` // AppointmentType, Treatment, Manual
+    SourceId             *uuid.UUID `json:"source_id"`
+    Description          string     `json:"description"`
+    Category             string     `json:"category"` // tax rate category
+    Quantity             int        `json:"quantity"`
+    UnitPrice            int        `json:"unit_price"`
+    Amount               int        `json:"amount"` // quantity * unit_price
+    DiscountType         string     `json:"discount_type"` // Percent, Fixed, empty without line discount
+    DiscountValue        float64    `json:"discount_value"`
+    DiscountReason       string     `json:"discount_reason"`
+    DiscountApprovedBy   string     `json:"discount_approved_by"`
+    DiscountAmount       int        `json:"discount_amount"`
+    InvoiceDiscountShare int        `json:"invoice_discount_share"` // part of the invoice discount taken off this line
+    TaxRate              float64    `json:"tax_rate"` // percent, frozen once the invoice is issued
+    TaxAmount            int        `json:"tax_amount"`
+    Total                int        `json:"total"` // amount - discounts + tax
+    SortOrder            int        `json:"sort_order"`
+    ActiveStatus         int        `json:"active_status"`
+    CreatedAt            time.Time  `json:"created_at"`
+    CreatedBy            string     `json:"created_by"`
+    ModifiedAt           time.Time  `json:"modified_at"`
+    ModifiedBy           string     `json:"modified_by"`
+}
+
+// TAX RATES (per billing category)
+type TaxRate struct {
+    Id           uuid.UUID `json:"id"`
+    Category     string    `json:"category"`
+    Name         string    `json:"name"`
+    Rate         *float64  `json:"rate"` // percent
+    ActiveStatus int       `json:"active_status"`
+    CreatedAt    time.Time `json:"created_at"`
+    CreatedBy    string    `json:"created_by"`
+    ModifiedAt   time.Time `json:"modified_at"`
+    ModifiedBy   string    `json:"modified_by"`
+}
+
+// PAYMENTS (money received, split over one or more invoices)
+type Payment struct {
+    Id             uuid.UUID           `json:"id"`
+    ReceiptNumber  string              `json:"receipt_number"`
+    OwnerId        *uuid.UUID          `json:"owner_id"`
+    PayerName      string              `json:"payer_name"`
+    Method         string              `json:"method"` // Cash, Card, Transfer
+    Amount         int                 `json:"amount"`
+    Reference      string              `json:"reference"`
+    ReceivedBy     uuid.UUID           `json:"received_by"`
+    ReceivedByName string              `json:"received_by_name"`
+    ReceivedAt     time.Time           `json:"received_at"`
+    Notes          string              `json:"notes"`
+    Status         string              `json:"status"` // Recorded, Void
+    VoidedAt       *time.Time          `json:"voided_at"`
+    VoidedBy       string              `json:"voided_by"`
+    VoidReason     string              `json:"void_reason"`
+    Allocations    []PaymentAllocation `json:"allocations"`
+    ActiveStatus   int                 `json:"active_status"`
+    CreatedAt      time.Time           `json:"created_at"`
+    CreatedBy      string              `json:"created_by"`
+    ModifiedAt     time.Time           `json:"modified_at"`
+    ModifiedBy     string              `json:"modified_by"`
+}
+
+// PAYMENT ALLOCATIONS
+type PaymentAllocation struct {
+    Id            uuid.UUID `json:"id"`
+    PaymentId     uuid.UUID `json:"payment_id"`
+    InvoiceId     uuid.UUID `json:"invoice_id"`
+    InvoiceNumber string    `json:"invoice_number"` // read only
+    Amount        int       `json:"amount"`
+    ActiveStatus  int       `json:"active_status"`
+    CreatedAt     time.Time `json:"created_at"`
+    CreatedBy     string    `json:"created_by"`
+    ModifiedAt    time.Time `json:"modified_at"`
+    ModifiedBy    string    `json:"modified_by"`
+}
+
+// OWNER BALANCE (computed, not stored)
+type OwnerBalance struct {
+    OwnerId     uuid.UUID `json:"owner_id"`
+    Invoiced    int       `json:"invoiced"` // issued invoices
+    Paid        int       `json:"paid"`
+    Outstanding int       `json:"outstanding"`
+    Invoices    []Invoice `json:"invoices"` // issued invoices with a balance, oldest first
+}
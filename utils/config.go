@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+    clinicLocation     *time.Location
+    clinicLocationOnce sync.Once
+)
+
+// ClinicTimezone is the IANA timezone of the clinic, e.g. "Asia/Jakarta" (default UTC)
+func ClinicTimezone() string {
+    return ClinicLocation().String()
+}
+
+// ClinicLocation loads CLINIC_TIMEZONE once; dates without a time (YYYY-MM-DD) are interpreted in this location
+func ClinicLocation() *time.Location {
+    clinicLocationOnce.Do(func() {
+        loc, err := time.LoadLocation(GetEnv("CLINIC_TIMEZONE", "UTC"))
+        if err != nil {
+            log.Println("Invalid CLINIC_TIMEZONE, falling back to UTC:", err)
+            loc = time.UTC
+        }
+        clinicLocation = loc
+    })
+    return clinicLocation
+}
+
+// ParseClinicDate parses YYYY-MM-DD as midnight in the clinic timezone
+func ParseClinicDate(value string) (time.Time, error) {
+    return time.ParseInLocation("2006-01-02", value, ClinicLocation())
+}
+
+// GetEnv reads an env variable, falling back when unset
+func GetEnv(key, fallback string) string {
+    if value := os.Getenv(key); value != "" {
+        return value
+    }
+    return fallback
+}
+
+// GetEnvInt reads an integer env variable, falling back when unset or invalid
+func GetEnvInt(key string, fallback int) int {
+    value, err := strconv.Atoi(os.Getenv(key))
+    if err != nil {
+        return fallback
+    }
+    return value
+}
+
+// GetEnvPositiveInt is GetEnvInt for intervals and durations: zero or negative values fall back too
+func GetEnvPositiveInt(key string, fallback int) int {
+    value := GetEnvInt(key, fallback)
+    if value <= 0 {
+        log.Println("Ignoring " + key + ", it must be greater than 0")
+        return fallback
+    }
+    return value
+}
+
+// GetEnvIntList reads a comma separated list of integers, e.g. "48,2"
+func GetEnvIntList(key string, fallback []int) []int {
+    raw := os.Getenv(key)
+    if raw == "" {
+        return fallback
+    }
+    var values []int
+    for _, part := range strings.Split(raw, ",") {
+        value, err := strconv.Atoi(strings.TrimSpace(part))
+        if err != nil {
+            return fallback
+        }
+        values = append(values, value)
+    }
+    return values
+}
+
+// GetEnvList reads a comma separated list of strings, empty entries are skipped (nil when unset)
+func GetEnvList(key string) []string {
+    var values []string
+    for _, part := range strings.Split(os.Getenv(key), ",") {
+        if value := strings.TrimSpace(part); value != "" {
+            values = append(values, value)
+        }
+    }
+    return values
+}
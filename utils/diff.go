@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"vetclinic-rest-api/structs"
+)
+
+// DiffSnapshots compares two JSON object snapshots field by field (top-level keys, sorted)
+func DiffSnapshots(from, to []byte) ([]structs.VersionChange, error) {
+    var a, b map[string]interface{}
+    if err := json.Unmarshal(from, &a); err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(to, &b); err != nil {
+        return nil, err
+    }
+
+    keys := map[string]bool{}
+    for k := range a {
+        keys[k] = true
+    }
+    for k := range b {
+        keys[k] = true
+    }
+    fields := make([]string, 0, len(keys))
+    for k := range keys {
+        fields = append(fields, k)
+    }
+    sort.Strings(fields)
+
+    changes := []structs.VersionChange{}
+    for _, field := range fields {
+        if !reflect.DeepEqual(a[field], b[field]) {
+            changes = append(changes, structs.VersionChange{Field: field, From: a[field], To: b[field]})
+        }
+    }
+    return changes, nil
+}
@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// HL7Observation is one OBX segment of an ORU message
+type HL7Observation struct {
+    Code           string // OBX-3 identifier
+    Name           string // OBX-3 text
+    Value          string // OBX-5
+    Unit           string // OBX-6
+    ReferenceRange string // OBX-7
+    AbnormalFlag   string // OBX-8
+    ObservedAt     *time.Time // OBX-14
+}
+
+// HL7Order is one OBR segment with the OBX segments that follow it
+type HL7Order struct {
+    PatientId    string // PID-3
+    PlacerNumber string // OBR-2, our accession number
+    FillerNumber string // OBR-3, the analyzer's sample id
+    Panel        string // OBR-4 text (identifier when there is no text)
+    ObservedAt   *time.Time // OBR-7
+    Observations []HL7Observation
+}
+
+// ParseHL7ORU reads the orders of a simple HL7 v2 ORU^R01 message (or several, one after another).
+// Only MSH, PID, OBR and OBX are used; repetitions, escapes and sub-components beyond the first are ignored.
+func ParseHL7ORU(message string) ([]HL7Order, error) {
+    message = strings.ReplaceAll(message, "\r\n", "\r")
+    message = strings.ReplaceAll(message, "\n", "\r")
+
+    fieldSep, componentSep := "|", "^"
+    var orders []HL7Order
+    var current *HL7Order
+    patientId := ""
+    sawHeader := false
+
+    for _, segment := range strings.Split(message, "\r") {
+        segment = strings.TrimSpace(segment)
+        if len(segment) < 3 {
+            continue
+        }
+        kind := segment[:3]
+        if kind == "MSH" {
+            if len(segment) < 8 {
+                return nil, errors.New("MSH segment is too short")
+            }
+            // MSH-1 is the field separator itself, MSH-2 starts with the component separator
+            fieldSep = segment[3:4]
+            componentSep = segment[4:5]
+            sawHeader = true
+            patientId = ""
+            continue
+        }
+        if !sawHeader {
+            return nil, errors.New("message must start with an MSH segment")
+        }
+
+        fields := strings.Split(segment, fieldSep)
+        field := func(i int) string {
+            if i < len(fields) {
+                return strings.TrimSpace(fields[i])
+            }
+            return ""
+        }
+        component := func(i, c int) string {
+            parts := strings.Split(field(i), componentSep)
+            if c < len(parts) {
+                return strings.TrimSpace(parts[c])
+            }
+            return ""
+        }
+
+        switch kind {
+        case "PID":
+            patientId = component(3, 0)
+        case "OBR":
+            orders = append(orders, HL7Order{
+                PatientId:    patientId,
+                PlacerNumber: component(2, 0),
+                FillerNumber: component(3, 0),
+                Panel:        firstNonEmpty(component(4, 1), component(4, 0)),
+                ObservedAt:   parseHL7Time(field(7)),
+            })
+            current = &orders[len(orders)-1]
+        case "OBX":
+            if current == nil {
+                return nil, errors.New("OBX segment without a preceding OBR")
+            }
+            current.Observations = append(current.Observations, HL7Observation{
+                Code:           component(3, 0),
+                Name:           firstNonEmpty(component(3, 1), component(3, 0)),
+                Value:          component(5, 0),
+                Unit:           component(6, 0),
+                ReferenceRange: field(7),
+                AbnormalFlag:   field(8),
+                ObservedAt:     parseHL7Time(field(14)),
+            })
+        }
+    }
+
+    if !sawHeader {
+        return nil, errors.New("message must start with an MSH segment")
+    }
+    if len(orders) == 0 {
+        return nil, errors.New("message has no OBR segment")
+    }
+    return orders, nil
+}
+
+// parseHL7Time reads HL7 TS values (YYYYMMDD[HHMM[SS]][+/-ZZZZ]), without offset they are clinic time
+func parseHL7Time(value string) *time.Time {
+    if value == "" {
+        return nil
+    }
+    if i := strings.IndexByte(value, '.'); i >= 0 && i+1 < len(value) {
+        // drop fractional seconds but keep an offset
+        end := i + 1
+        for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+            end++
+        }
+        value = value[:i] + value[end:]
+    }
+    for _, layout := range []string{"20060102150405-0700", "200601021504-0700", "20060102150405", "200601021504", "20060102"} {
+        var t time.Time
+        var err error
+        if strings.HasSuffix(layout, "-0700") {
+            t, err = time.Parse(layout, value)
+        } else {
+            t, err = time.ParseInLocation(layout, value, ClinicLocation())
+        }
+        if err == nil {
+            return &t
+        }
+    }
+    return nil
+}
+
+func firstNonEmpty(values ...string) string {
+    for _, v := range values {
+        if v != "" {
+            return v
+        }
+    }
+    return ""
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarEvent is one VEVENT of an iCalendar (RFC 5545) feed
+type CalendarEvent struct {
+    UID          string
+    Start        time.Time
+    End          time.Time
+    Summary      string
+    Description  string
+    Status       string // CONFIRMED, CANCELLED
+    Sequence     int
+    LastModified time.Time
+}
+
+const icalUTCFormat = "20060102T150405Z"
+
+// BuildICalendar renders a VCALENDAR with the given events, all times in UTC
+func BuildICalendar(calendarName string, events []CalendarEvent) string {
+    var b strings.Builder
+    now := time.Now().UTC()
+
+    writeICalLine(&b, "BEGIN:VCALENDAR")
+    writeICalLine(&b, "VERSION:2.0")
+    writeICalLine(&b, "PRODID:-//VetClinic REST API//Doctor Schedule//EN")
+    writeICalLine(&b, "CALSCALE:GREGORIAN")
+    writeICalLine(&b, "METHOD:PUBLISH")
+    writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(calendarName))
+
+    for _, e := range events {
+        writeICalLine(&b, "BEGIN:VEVENT")
+        writeICalLine(&b, "UID:"+e.UID)
+        writeICalLine(&b, "DTSTAMP:"+now.Format(icalUTCFormat))
+        writeICalLine(&b, "DTSTART:"+e.Start.UTC().Format(icalUTCFormat))
+        writeICalLine(&b, "DTEND:"+e.End.UTC().Format(icalUTCFormat))
+        writeICalLine(&b, "SUMMARY:"+escapeICalText(e.Summary))
+        if e.Description != "" {
+            writeICalLine(&b, "DESCRIPTION:"+escapeICalText(e.Description))
+        }
+        writeICalLine(&b, "STATUS:"+e.Status)
+        writeICalLine(&b, "SEQUENCE:"+strconv.Itoa(e.Sequence))
+        if !e.LastModified.IsZero() {
+            writeICalLine(&b, "LAST-MODIFIED:"+e.LastModified.UTC().Format(icalUTCFormat))
+        }
+        writeICalLine(&b, "END:VEVENT")
+    }
+
+    writeICalLine(&b, "END:VCALENDAR")
+    return b.String()
+}
+
+// escapeICalText escapes TEXT values (RFC 5545 section 3.3.11)
+func escapeICalText(s string) string {
+    s = strings.ReplaceAll(s, `\`, `\\`)
+    s = strings.ReplaceAll(s, ";", `\;`)
+    s = strings.ReplaceAll(s, ",", `\,`)
+    s = strings.ReplaceAll(s, "\r\n", `\n`)
+    s = strings.ReplaceAll(s, "\n", `\n`)
+    return s
+}
+
+// writeICalLine folds content lines longer than 75 octets without splitting UTF-8 characters
+func writeICalLine(b *strings.Builder, line string) {
+    limit := 75
+    for len(line) > limit {
+        cut := limit
+        for cut > 0 && line[cut]&0xC0 == 0x80 {
+            cut--
+        }
+        b.WriteString(line[:cut])
+        b.WriteString("\r\n ")
+        line = line[cut:]
+        limit = 74 // continuation lines start with a space
+    }
+    b.WriteString(line)
+    b.WriteString("\r\n")
+}
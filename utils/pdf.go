@@ -0,0 +1,462 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"time"
+)
+
+// A4 portrait in points, all positions are measured from the top-left corner
+const (
+    PDFPageWidth  = 595.28
+    PDFPageHeight = 841.89
+    PDFMargin     = 50.0
+    pdfFooterSize = 8.0
+)
+
+// PDFColumn is a column of PDF.Table
+type PDFColumn struct {
+    Title string
+    Width float64
+    Right bool // right-aligned, for amounts
+}
+
+type pdfImage struct {
+    key           [32]byte // sha256 of the source, a logo repeated on every page is embedded once
+    width, height int
+    data          []byte // zlib compressed RGB
+}
+
+// PDF writes simple A4 documents in pure Go: the built-in Helvetica fonts (WinAnsi), lines, boxes and
+// PNG/JPEG images. Layout helpers (Heading, Paragraph, Field, Table) flow down the page from the
+// cursor Y and start a new page when the content doesn't fit.
+type PDF struct {
+    Y        float64 // cursor, distance from the top edge
+    title    string
+    pages    []*bytes.Buffer
+    images   []pdfImage
+    header   func(p *PDF)
+    footer   string
+    inHeader bool
+}
+
+func NewPDF(title string) *PDF {
+    return &PDF{title: title}
+}
+
+// SetHeader is called at the top of every new page, it should leave Y below what it drew
+func (p *PDF) SetHeader(header func(p *PDF)) {
+    p.header = header
+}
+
+// SetFooter is printed at the bottom of every page next to the page number
+func (p *PDF) SetFooter(footer string) {
+    p.footer = footer
+}
+
+func (p *PDF) AddPage() {
+    p.pages = append(p.pages, &bytes.Buffer{})
+    p.Y = PDFMargin
+    if p.header != nil && !p.inHeader {
+        p.inHeader = true
+        p.header(p)
+        p.inHeader = false
+    }
+}
+
+func (p *PDF) page() *bytes.Buffer {
+    if len(p.pages) == 0 {
+        p.AddPage()
+    }
+    return p.pages[len(p.pages)-1]
+}
+
+// ContentWidth is the usable width between the margins
+func (p *PDF) ContentWidth() float64 {
+    return PDFPageWidth - 2*PDFMargin
+}
+
+// ensure starts a new page when height doesn't fit above the footer
+func (p *PDF) ensure(height float64) {
+    if len(p.pages) == 0 || p.Y+height > PDFPageHeight-PDFMargin {
+        p.AddPage()
+    }
+}
+
+// SetColor sets the fill color for the following text and boxes
+func (p *PDF) SetColor(r, g, b uint8) {
+    fmt.Fprintf(p.page(), "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// SetStrokeColor sets the color of the following lines
+func (p *PDF) SetStrokeColor(r, g, b uint8) {
+    fmt.Fprintf(p.page(), "%.3f %.3f %.3f RG\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// Text draws a single line with its top at y
+func (p *PDF) Text(x, y, size float64, bold bool, text string) {
+    font := "F1"
+    if bold {
+        font = "F2"
+    }
+    baseline := PDFPageHeight - y - size*0.8
+    fmt.Fprintf(p.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, baseline, pdfEscape(text))
+}
+
+func (p *PDF) Line(x1, y1, x2, y2, width float64) {
+    fmt.Fprintf(p.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+func (p *PDF) FillRect(x, y, w, h float64) {
+    fmt.Fprintf(p.page(), "%.2f %.2f %.2f %.2f re f\n", x, PDFPageHeight-y-h, w, h)
+}
+
+// Image draws a PNG or JPEG scaled to fit in maxW x maxH, keeping its aspect ratio, and returns the drawn size
+func (p *PDF) Image(data []byte, x, y, maxW, maxH float64) (float64, float64, error) {
+    key := sha256.Sum256(data)
+    index := 0
+    for i, img := range p.images {
+        if img.key == key {
+            index = i + 1
+            break
+        }
+    }
+    if index == 0 {
+        img, err := decodePDFImage(data)
+        if err != nil {
+            return 0, 0, err
+        }
+        img.key = key
+        p.images = append(p.images, img)
+        index = len(p.images)
+    }
+    img := p.images[index-1]
+
+    scale := maxW / float64(img.width)
+    if s := maxH / float64(img.height); s < scale {
+        scale = s
+    }
+    w, h := float64(img.width)*scale, float64(img.height)*scale
+    fmt.Fprintf(p.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, PDFPageHeight-y-h, index)
+    return w, h, nil
+}
+
+func decodePDFImage(data []byte) (pdfImage, error) {
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return pdfImage{}, err
+    }
+    bounds := img.Bounds()
+    if bounds.Dx() == 0 || bounds.Dy() == 0 {
+        return pdfImage{}, fmt.Errorf("image is empty")
+    }
+
+    // Flatten onto white, PDF images without a mask have no transparency
+    rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+    for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+        for px := bounds.Min.X; px < bounds.Max.X; px++ {
+            c := color.NRGBAModel.Convert(img.At(px, py)).(color.NRGBA)
+            a := int(c.A)
+            rgb = append(rgb,
+                byte((int(c.R)*a+255*(255-a))/255),
+                byte((int(c.G)*a+255*(255-a))/255),
+                byte((int(c.B)*a+255*(255-a))/255),
+            )
+        }
+    }
+    return pdfImage{width: bounds.Dx(), height: bounds.Dy(), data: deflate(rgb)}, nil
+}
+
+// Space moves the cursor down
+func (p *PDF) Space(height float64) {
+    p.Y += height
+}
+
+// Rule draws a thin line across the page at the cursor
+func (p *PDF) Rule() {
+    p.ensure(6)
+    p.Line(PDFMargin, p.Y+3, PDFPageWidth-PDFMargin, p.Y+3, 0.5)
+    p.Y += 6
+}
+
+// Heading writes a bold section title, kept on the same page as the line that follows it
+func (p *PDF) Heading(text string, size float64) {
+    p.ensure(size*1.4 + 4 + 14)
+    p.Y += 4
+    p.Text(PDFMargin, p.Y, size, true, text)
+    p.Y += size * 1.4
+}
+
+// Paragraph writes wrapped text, newlines in text start new lines
+func (p *PDF) Paragraph(text string, size float64) {
+    p.wrapped(PDFMargin, p.ContentWidth(), size, false, text)
+}
+
+// Field writes "label  value" with the value wrapped next to the label column
+func (p *PDF) Field(label, value string) {
+    const size, labelWidth = 10.0, 120.0
+    if strings.TrimSpace(value) == "" {
+        value = "-"
+    }
+    p.ensure(size * 1.35)
+    p.Text(PDFMargin, p.Y, size, true, label)
+    p.wrapped(PDFMargin+labelWidth, p.ContentWidth()-labelWidth, size, false, value)
+}
+
+func (p *PDF) wrapped(x, width, size float64, bold bool, text string) {
+    for _, line := range WrapPDFText(text, width, size, bold) {
+        p.ensure(size * 1.35)
+        p.Text(x, p.Y, size, bold, line)
+        p.Y += size * 1.35
+    }
+}
+
+// Table writes a header row and wrapped rows, the header is repeated when the table continues on a new page
+func (p *PDF) Table(columns []PDFColumn, rows [][]string) {
+    const size, pad = 9.0, 3.0
+    lineHeight := size * 1.3
+
+    drawHeader := func() {
+        p.ensure(lineHeight + 2*pad)
+        x := PDFMargin
+        p.SetColor(235, 235, 235)
+        p.FillRect(PDFMargin, p.Y, p.ContentWidth(), lineHeight+2*pad)
+        p.SetColor(0, 0, 0)
+        for _, col := range columns {
+            p.cell(x, p.Y+pad, col, size, true, col.Title)
+            x += col.Width
+        }
+        p.Y += lineHeight + 2*pad
+    }
+    drawHeader()
+
+    for _, row := range rows {
+        cells := make([][]string, len(columns))
+        lines := 1
+        for i, col := range columns {
+            value := ""
+            if i < len(row) {
+                value = row[i]
+            }
+            cells[i] = WrapPDFText(value, col.Width-2*pad, size, false)
+            if len(cells[i]) > lines {
+                lines = len(cells[i])
+            }
+        }
+        height := float64(lines)*lineHeight + 2*pad
+        if p.Y+height > PDFPageHeight-PDFMargin {
+            p.AddPage()
+            drawHeader()
+        }
+        x := PDFMargin
+        for i, col := range columns {
+            for j, line := range cells[i] {
+                p.cell(x, p.Y+pad+float64(j)*lineHeight, col, size, false, line)
+            }
+            x += col.Width
+        }
+        p.Y += height
+        p.Line(PDFMargin, p.Y, PDFPageWidth-PDFMargin, p.Y, 0.25)
+    }
+}
+
+func (p *PDF) cell(x, y float64, col PDFColumn, size float64, bold bool, text string) {
+    const pad = 3.0
+    if col.Right {
+        x += col.Width - pad - PDFTextWidth(text, size, bold)
+    } else {
+        x += pad
+    }
+    p.Text(x, y, size, bold, text)
+}
+
+// Bytes finishes the document: page footers, fonts, images and the cross-reference table
+func (p *PDF) Bytes() []byte {
+    if len(p.pages) == 0 {
+        p.AddPage()
+    }
+
+    var out bytes.Buffer
+    var offsets []int
+    object := func(body string) {
+        offsets = append(offsets, out.Len())
+        fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+    }
+    stream := func(dict string, data []byte) {
+        offsets = append(offsets, out.Len())
+        fmt.Fprintf(&out, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+        out.Write(data)
+        out.WriteString("\nendstream\nendobj\n")
+    }
+
+    // 1 catalog, 2 pages, 3-4 fonts, 5 info, 6 resources, images, then a page and its content per page
+    firstImage := 7
+    firstPage := firstImage + len(p.images)
+    out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+    object("<< /Type /Catalog /Pages 2 0 R >>")
+    kids := make([]string, len(p.pages))
+    for i := range p.pages {
+        kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+    }
+    object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+    object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+    object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+    object(fmt.Sprintf("<< /Title (%s) /Producer (vetclinic-rest-api) /CreationDate (D:%s) >>",
+        pdfEscape(p.title), time.Now().UTC().Format("20060102150405Z")))
+
+    xobjects := ""
+    for i := range p.images {
+        xobjects += fmt.Sprintf(" /Im%d %d 0 R", i+1, firstImage+i)
+    }
+    object(fmt.Sprintf("<< /Font << /F1 3 0 R /F2 4 0 R >> /XObject <<%s >> >>", xobjects))
+    for _, img := range p.images {
+        stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode",
+            img.width, img.height), img.data)
+    }
+
+    for i, content := range p.pages {
+        // footer with "Page x of n" is only known now
+        pageLabel := fmt.Sprintf("Page %d of %d", i+1, len(p.pages))
+        footerY := PDFPageHeight - PDFMargin + 14
+        fmt.Fprintf(content, "0.4 0.4 0.4 rg\n")
+        fmt.Fprintf(content, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", pdfFooterSize, PDFMargin,
+            PDFPageHeight-footerY-pdfFooterSize*0.8, pdfEscape(p.footer))
+        fmt.Fprintf(content, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", pdfFooterSize,
+            PDFPageWidth-PDFMargin-PDFTextWidth(pageLabel, pdfFooterSize, false),
+            PDFPageHeight-footerY-pdfFooterSize*0.8, pageLabel)
+
+        object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources 6 0 R /Contents %d 0 R >>",
+            PDFPageWidth, PDFPageHeight, len(offsets)+2))
+        stream("/Filter /FlateDecode", deflate(content.Bytes()))
+    }
+
+    xref := out.Len()
+    fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+    for _, offset := range offsets {
+        fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+    }
+    fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+    return out.Bytes()
+}
+
+func deflate(data []byte) []byte {
+    var buf bytes.Buffer
+    w := zlib.NewWriter(&buf)
+    w.Write(data)
+    w.Close()
+    return buf.Bytes()
+}
+
+// WrapPDFText splits text into lines that fit width, breaking words that are longer than a line
+func WrapPDFText(text string, width, size float64, bold bool) []string {
+    var lines []string
+    for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+        line := ""
+        for _, word := range strings.Fields(paragraph) {
+            candidate := word
+            if line != "" {
+                candidate = line + " " + word
+            }
+            if PDFTextWidth(candidate, size, bold) <= width {
+                line = candidate
+                continue
+            }
+            if line != "" {
+                lines = append(lines, line)
+            }
+            // a single word wider than the line is cut where it overflows
+            line = ""
+            for _, r := range word {
+                if line != "" && PDFTextWidth(line+string(r), size, bold) > width {
+                    lines = append(lines, line)
+                    line = ""
+                }
+                line += string(r)
+            }
+        }
+        lines = append(lines, line)
+    }
+    return lines
+}
+
+// PDFTextWidth measures text in points with the Helvetica metrics
+func PDFTextWidth(text string, size float64, bold bool) float64 {
+    widths := &helveticaWidths
+    if bold {
+        widths = &helveticaBoldWidths
+    }
+    total := 0
+    for _, b := range toWinAnsi(text) {
+        if b >= 32 && b <= 126 {
+            total += widths[b-32]
+        } else {
+            total += 556
+        }
+    }
+    return float64(total) * size / 1000
+}
+
+// winAnsiSpecial maps the characters of the 0x80-0x9F range of WinAnsiEncoding
+var winAnsiSpecial = map[rune]byte{
+    '€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '•': 0x95, '–': 0x96, '—': 0x97,
+    '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '™': 0x99,
+}
+
+// toWinAnsi converts UTF-8 to the single-byte encoding of the standard fonts, unknown characters become '?'
+func toWinAnsi(text string) []byte {
+    out := make([]byte, 0, len(text))
+    for _, r := range text {
+        switch {
+        case r == '\t':
+            out = append(out, ' ')
+        case r >= 32 && r < 127, r >= 0xA0 && r <= 0xFF:
+            out = append(out, byte(r))
+        case winAnsiSpecial[r] != 0:
+            out = append(out, winAnsiSpecial[r])
+        default:
+            out = append(out, '?')
+        }
+    }
+    return out
+}
+
+func pdfEscape(text string) string {
+    var b strings.Builder
+    for _, c := range toWinAnsi(text) {
+        switch c {
+        case '\\', '(', ')':
+            b.WriteByte('\\')
+            b.WriteByte(c)
+        default:
+            b.WriteByte(c)
+        }
+    }
+    return b.String()
+}
+
+// Advance widths of characters 32-126 (Adobe AFM, per 1000 units)
+var helveticaWidths = [95]int{
+    278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+    556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+    1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+    667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+    333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+    556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+    278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+    556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+    975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+    667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+    333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+    611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrNoSigningKey is returned when REFERRAL_SIGNING_KEY isn't configured
+var ErrNoSigningKey = errors.New("REFERRAL_SIGNING_KEY is not set")
+
+// referralSigningKey is shared by the clinics that exchange referral packages
+func referralSigningKey() ([]byte, error) {
+    key := GetEnv("REFERRAL_SIGNING_KEY", "")
+    if key == "" {
+        return nil, ErrNoSigningKey
+    }
+    return []byte(key), nil
+}
+
+// SignReferralPackage returns the hex HMAC-SHA256 of data with the referral signing key
+func SignReferralPackage(data []byte) (string, error) {
+    key, err := referralSigningKey()
+    if err != nil {
+        return "", err
+    }
+    mac := hmac.New(sha256.New, key)
+    mac.Write(data)
+    return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyReferralPackage checks a signature made by SignReferralPackage in constant time
+func VerifyReferralPackage(data []byte, signature string) (bool, error) {
+    expected, err := SignReferralPackage(data)
+    if err != nil {
+        return false, err
+    }
+    return hmac.Equal([]byte(expected), []byte(signature)), nil
+}
+
+// Sha256Hex returns the hex SHA-256 of data
+func Sha256Hex(data []byte) string {
+    sum := sha256.Sum256(data)
+    return hex.EncodeToString(sum[:])
+}